@@ -0,0 +1,22 @@
+#!/bin/sh
+# Copyright (c) 2018 The ZJU-SEL Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Query the DNS server of the testing case, or the nameserver of the pod if not set.
+SERVER=${DNS_SERVER:-$(awk '/^nameserver/ { print $2; exit }' /etc/resolv.conf)}
+
+# The name of the pod is its IP with dashes, e.g. 10-244-1-5.<namespace>.pod.<domain>.
+echo "${QUERIES}" | sed "s/POD_IP/$(echo "${POD_IP}" | tr . -)/g" > /tmp/queries.txt
+
+dnsperf -s "${SERVER}" -d /tmp/queries.txt $* && echo "Capstan Testing Done"
@@ -19,27 +19,28 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/ZJU-SEL/capstan/pkg/capstan"
 	"github.com/ZJU-SEL/capstan/pkg/util"
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
-	"github.com/spf13/pflag"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 )
 
 var (
-	kubeconfig    = pflag.String("kubeconfig", "/etc/kubernetes/admin.conf", "path to kubernetes admin config file")
-	capstanConfig = pflag.String("config", "/etc/capstan/config", "path to capstan config file")
-	version       = pflag.Bool("version", false, "Display version")
 	// VERSION is the version of capstan.
 	VERSION = "1.0"
 )
 
-func initK8sClient() (*kubernetes.Clientset, error) {
+const (
+	defaultKubeconfig    = "/etc/kubernetes/admin.conf"
+	defaultCapstanConfig = "/etc/capstan/config"
+	defaultResultsDir    = "/tmp/capstan"
+)
+
+func initK8sClient(kubeconfig string) (*kubernetes.Clientset, error) {
 	// Create kubernetes client config. Use kubeconfig if given, otherwise assume in-cluster.
-	config, err := util.NewClusterConfig(*kubeconfig)
+	config, err := util.NewClusterConfig(kubeconfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to build kubeconfig")
 	}
@@ -51,25 +52,67 @@ func initK8sClient() (*kubernetes.Clientset, error) {
 	return kubeClient, nil
 }
 
-func main() {
-	util.InitFlags()
-	util.InitLogs()
-	defer util.FlushLogs()
+// newRootCommand creates the capstan command with all of its subcommands.
+func newRootCommand() *command {
+	cmd := newCommand("capstan <command> [flags]", "capstan is a benchmarker of Kubernetes clusters")
+	version := cmd.Flags.Bool("version", false, "Display version")
+	cmd.Run = func(args []string) error {
+		if *version {
+			fmt.Println(VERSION)
+			return nil
+		}
+		if len(args) > 0 {
+			cmd.PrintUsage(os.Stderr)
+			return &exitError{code: 2, msg: fmt.Sprintf("unknown command %q for %q", args[0], cmd.FullName())}
+		}
+		cmd.PrintUsage(os.Stdout)
+		return nil
+	}
 
-	// Print capstan version
-	if *version {
+	cmd.AddCommand(
+		newRunCommand(),
+		newValidateCommand(),
+		newListCommand(),
+		newReportCommand(),
+		newCompareCommand(),
+		newCleanupCommand(),
+		newVersionCommand(),
+	)
+	return cmd
+}
+
+func newVersionCommand() *command {
+	cmd := newCommand("version", "Display version")
+	cmd.Run = func(args []string) error {
 		fmt.Println(VERSION)
-		os.Exit(0)
+		return nil
 	}
+	return cmd
+}
 
-	// Initilize kubernetes client
-	kubeClient, err := initK8sClient()
-	if err != nil {
-		glog.Fatal(err)
+// defaultArgs makes "capstan [flags]" an alias of "capstan run [flags]", as before subcommands existed.
+func defaultArgs(args []string) []string {
+	if len(args) == 0 {
+		return []string{"run"}
+	}
+	switch args[0] {
+	case "-h", "--help", "-help", "--version":
+		return args
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return append([]string{"run"}, args...)
 	}
+	return args
+}
 
-	// Run capstan
-	if err := capstan.Run(kubeClient, *capstanConfig); err != nil {
-		glog.Fatal(err)
+func main() {
+	err := newRootCommand().Execute(defaultArgs(os.Args[1:]))
+	util.FlushLogs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if e, ok := err.(*exitError); ok {
+			os.Exit(e.code)
+		}
+		os.Exit(1)
 	}
 }
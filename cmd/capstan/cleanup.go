@@ -0,0 +1,41 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/ZJU-SEL/capstan/pkg/capstan"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func newCleanupCommand() *command {
+	cmd := newCommand("cleanup [flags]", "Remove the leftover resources of capstan runs")
+	kubeconfig := cmd.Flags.String("kubeconfig", defaultKubeconfig, "path to kubernetes admin config file")
+	namespace := cmd.Flags.String("namespace", workload.DefaultNamespace, "namespace used by capstan runs")
+	keepNamespace := cmd.Flags.Bool("keep-namespace", false, "only delete the capstan pods and keep the namespace")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+
+		kubeClient, err := initK8sClient(*kubeconfig)
+		if err != nil {
+			return err
+		}
+		return capstan.Cleanup(kubeClient, *namespace, !*keepNamespace)
+	}
+	return cmd
+}
@@ -0,0 +1,181 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	goflag "flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ZJU-SEL/capstan/pkg/util"
+	"github.com/spf13/pflag"
+)
+
+// command is a capstan command, a command either runs or dispatches to its subcommands.
+type command struct {
+	// Use is the one-line usage message, the first word is the name of the command.
+	Use string
+	// Short is the short description shown in the help of the parent command.
+	Short string
+	// Long is the long description shown in the help of the command.
+	Long string
+	// Flags is the flags of the command.
+	Flags *pflag.FlagSet
+	// Run runs the command with the non-flag arguments.
+	Run func(args []string) error
+	// Commands is the subcommands of the command.
+	Commands []*command
+
+	parent      *command
+	localUsages string
+}
+
+// exitError is an error which makes capstan exit with the given code.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+// newCommand creates a command with an empty flag set.
+func newCommand(use, short string) *command {
+	cmd := &command{
+		Use:   use,
+		Short: short,
+	}
+	cmd.Flags = pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	cmd.Flags.SetOutput(ioutil.Discard)
+	return cmd
+}
+
+// Name returns the name of the command.
+func (c *command) Name() string {
+	return strings.Fields(c.Use)[0]
+}
+
+// FullName returns the name of the command prefixed with the names of its parents.
+func (c *command) FullName() string {
+	if c.parent == nil {
+		return c.Name()
+	}
+	return c.parent.FullName() + " " + c.Name()
+}
+
+// AddCommand adds subcommands to the command.
+func (c *command) AddCommand(cmds ...*command) {
+	for _, cmd := range cmds {
+		cmd.parent = c
+		c.Commands = append(c.Commands, cmd)
+	}
+}
+
+// Find finds the subcommand with the given name.
+func (c *command) Find(name string) *command {
+	for _, cmd := range c.Commands {
+		if cmd.Name() == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Execute parses the flags and runs the command, or dispatches to a subcommand
+// if the first argument is the name of a subcommand.
+func (c *command) Execute(args []string) error {
+	if len(args) > 0 {
+		if cmd := c.Find(args[0]); cmd != nil {
+			return cmd.Execute(args[1:])
+		}
+	}
+
+	// Keep the usages of the flags of the command before the global flags are added.
+	c.localUsages = c.Flags.FlagUsages()
+	if err := util.InitFlagSet(c.Flags, args); err != nil {
+		if err == pflag.ErrHelp {
+			c.PrintUsage(os.Stdout)
+			return nil
+		}
+		c.PrintUsage(os.Stderr)
+		return &exitError{code: 2, msg: err.Error()}
+	}
+	util.InitLogs()
+
+	if c.Run == nil {
+		if c.Flags.NArg() > 0 {
+			c.PrintUsage(os.Stderr)
+			return &exitError{code: 2, msg: fmt.Sprintf("unknown command %q for %q", c.Flags.Arg(0), c.FullName())}
+		}
+		c.PrintUsage(os.Stdout)
+		return nil
+	}
+	return c.Run(c.Flags.Args())
+}
+
+// PrintUsage prints the help message of the command.
+func (c *command) PrintUsage(w io.Writer) {
+	if c.Long != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(c.Long))
+	} else if c.Short != "" {
+		fmt.Fprintf(w, "%s\n\n", c.Short)
+	}
+
+	use := c.Use
+	if c.parent != nil {
+		use = c.parent.FullName() + " " + c.Use
+	}
+	fmt.Fprintf(w, "Usage:\n  %s\n", use)
+
+	if len(c.Commands) > 0 {
+		fmt.Fprintf(w, "\nAvailable Commands:\n")
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, cmd := range c.Commands {
+			fmt.Fprintf(tw, "  %s\t%s\n", cmd.Name(), cmd.Short)
+		}
+		tw.Flush()
+	}
+
+	if c.localUsages != "" {
+		fmt.Fprintf(w, "\nFlags:\n%s", c.localUsages)
+	}
+
+	global := pflag.NewFlagSet("global", pflag.ContinueOnError)
+	global.SetNormalizeFunc(util.WordSepNormalizeFunc)
+	global.AddGoFlagSet(goflag.CommandLine)
+	global.AddFlagSet(pflag.CommandLine)
+	fmt.Fprintf(w, "\nGlobal Flags:\n%s", global.FlagUsages())
+
+	if len(c.Commands) > 0 {
+		fmt.Fprintf(w, "\nUse \"%s <command> --help\" for more information about a command.\n", c.FullName())
+	}
+}
+
+// requireArgs returns an error if the number of arguments is not in [min, max],
+// a negative max means no upper limit.
+func requireArgs(cmd *command, args []string, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		cmd.PrintUsage(os.Stderr)
+		return &exitError{code: 2, msg: fmt.Sprintf("wrong number of arguments for %q", cmd.FullName())}
+	}
+	return nil
+}
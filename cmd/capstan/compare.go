@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/compare"
+	"github.com/pkg/errors"
+)
+
+const compareLong = `
+Compare the testing results of runs with the baseline run. A run can be given as
+its results directory, its summary.json or results.json file, or its UUID under
+--results-dir. Exits with status 1 if any metric regresses more than --threshold
+and the change is significant at --alpha (set --alpha=1 to only check the threshold),
+or if a metric of the baseline is missing in a run unless --allow-missing is set.
+Metrics with too few repeats for any change to be significant at --alpha, e.g. 3
+against 3 repeats at the default --alpha, are only checked against --threshold.
+`
+
+func newCompareCommand() *command {
+	cmd := newCommand("compare [flags] <baseline> <run>...", "Compare runs and flag regressions")
+	cmd.Long = compareLong
+	resultsDir := cmd.Flags.String("results-dir", defaultResultsDir, "directory of testing results used to find runs by UUID")
+	threshold := cmd.Flags.Float64("threshold", 5, "max percentage a metric may get worse than the baseline")
+	alpha := cmd.Flags.Float64("alpha", 0.05, "significance level of the Mann-Whitney U test")
+	allowMissing := cmd.Flags.Bool("allow-missing", false, "do not fail if a metric of the baseline is missing in a run")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 2, -1); err != nil {
+			return err
+		}
+
+		var runs []compare.Run
+		for _, source := range args {
+			run, err := compare.LoadRun(source, *resultsDir)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to load run %s", source)
+			}
+			runs = append(runs, run)
+		}
+
+		rows := compare.Compare(runs[0], runs[1:], compare.Options{
+			Threshold: *threshold,
+			Alpha:     *alpha,
+		})
+		if err := compare.PrintTable(os.Stdout, rows); err != nil {
+			return err
+		}
+
+		if n := compare.CountThresholdOnly(rows); n > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %d metrics have too few repeats to be significant at alpha %g, they are only checked against the threshold\n", n, *alpha)
+		}
+
+		var problems []string
+		if compare.HasRegression(rows) {
+			problems = append(problems, fmt.Sprintf("found regressions against baseline %s", runs[0].Label))
+		}
+		if compare.HasMissing(rows) && !*allowMissing {
+			problems = append(problems, fmt.Sprintf("found metrics of baseline %s missing in runs", runs[0].Label))
+		}
+		if len(problems) > 0 {
+			return &exitError{code: 1, msg: strings.Join(problems, ", ")}
+		}
+		return nil
+	}
+	return cmd
+}
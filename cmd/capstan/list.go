@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan/loader"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func newListCommand() *command {
+	cmd := newCommand("list <command>", "List the defined workloads, testing tools or testing cases")
+	cmd.AddCommand(
+		newListWorkloadsCommand(),
+		newListToolsCommand(),
+		newListCasesCommand(),
+	)
+	return cmd
+}
+
+func newListWorkloadsCommand() *command {
+	cmd := newCommand("workloads", "List the defined workloads")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+		for _, name := range workload.Workloads() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newListToolsCommand() *command {
+	cmd := newCommand("tools", "List the defined testing tools")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+		for _, name := range workload.Tools() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newListCasesCommand() *command {
+	cmd := newCommand("cases [workload]", "List the defined testing cases of all workloads or of a workload")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 1); err != nil {
+			return err
+		}
+
+		workloads := workload.Workloads()
+		if len(args) == 1 {
+			workloads = args
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "WORKLOAD\tTESTING TOOL\tTESTING CASE")
+		for _, name := range workloads {
+			toolNames, err := loader.DefToolNames(name)
+			if err != nil {
+				return err
+			}
+			if len(toolNames) == 0 {
+				// any testing tool and any testing case is allowed.
+				continue
+			}
+			for _, toolName := range toolNames {
+				testingCaseSet, err := loader.DefTestingCaseSet(name, toolName)
+				if err != nil {
+					return err
+				}
+				for _, testingCase := range testingCaseSet {
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", name, toolName, testingCase)
+				}
+			}
+		}
+		return tw.Flush()
+	}
+	return cmd
+}
@@ -0,0 +1,48 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/ZJU-SEL/capstan/pkg/report"
+)
+
+func newReportCommand() *command {
+	cmd := newCommand("report [flags] <UUID|results directory>", "Render the HTML report of a run from its results on disk")
+	resultsDir := cmd.Flags.String("results-dir", defaultResultsDir, "directory of testing results used to find runs by UUID")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 1, 1); err != nil {
+			return err
+		}
+
+		runDir := args[0]
+		if _, err := os.Stat(runDir); os.IsNotExist(err) {
+			runDir = path.Join(*resultsDir, args[0])
+		}
+
+		out, err := report.Generate(runDir)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+	return cmd
+}
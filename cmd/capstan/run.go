@@ -0,0 +1,46 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/ZJU-SEL/capstan/pkg/capstan"
+)
+
+func newRunCommand() *command {
+	cmd := newCommand("run [flags]", "Run all the testing workloads of a capstan config")
+	kubeconfig := cmd.Flags.String("kubeconfig", defaultKubeconfig, "path to kubernetes admin config file")
+	capstanConfig := cmd.Flags.String("config", defaultCapstanConfig, "path to capstan config file")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+
+		// Initilize kubernetes client
+		kubeClient, err := initK8sClient(*kubeconfig)
+		if err != nil {
+			return err
+		}
+
+		// Run capstan, exit with 3 if some testing cases have failed and been skipped.
+		err = capstan.Run(kubeClient, *capstanConfig)
+		if e, ok := err.(*capstan.PartialFailureError); ok {
+			return &exitError{code: 3, msg: e.Error()}
+		}
+		return err
+	}
+	return cmd
+}
@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan"
+)
+
+func newValidateCommand() *command {
+	cmd := newCommand("validate [flags]", "Parse and check a capstan config without a cluster")
+	capstanConfig := cmd.Flags.String("config", defaultCapstanConfig, "path to capstan config file")
+	cmd.Run = func(args []string) error {
+		if err := requireArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+
+		if err := capstan.Validate(*capstanConfig); err != nil {
+			return err
+		}
+		fmt.Printf("%s is valid\n", *capstanConfig)
+		return nil
+	}
+	return cmd
+}
@@ -17,15 +17,21 @@ limitations under the License.
 package capstan
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"syscall"
 	"time"
 
 	"github.com/ZJU-SEL/capstan/pkg/capstan/loader"
 	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/cluster"
 	"github.com/ZJU-SEL/capstan/pkg/dashboard"
+	"github.com/ZJU-SEL/capstan/pkg/report"
+	"github.com/ZJU-SEL/capstan/pkg/results"
 	"github.com/ZJU-SEL/capstan/pkg/workload"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
@@ -38,53 +44,88 @@ import (
 //
 // 1. Read capstan config
 // 2. Load all workloads
-// 3. Start runs all testing workloads sequentially
-// 4. Launch the HTTP server
+// 3. Take a fingerprint of the cluster
+// 4. Start runs all testing workloads
+// 5. Launch the HTTP server
+//
+// On SIGTERM the running testing cases are aborted and cleaned up, and Run returns
+// once the namespace of capstan has been deleted.
 func Run(kubeClient kubernetes.Interface, capstanConfig string) error {
 	// 1. Read capstan config.
-	cfg, err := types.ReadConfig(capstanConfig)
+	// 2. Load all workloads
+	cfg, workloads, err := loadConfig(capstanConfig)
 	if err != nil {
-		return errors.Wrap(err, "Failed read capstan config")
+		return err
 	}
 	glog.V(1).Infof("Initializing capstan with config %v", cfg)
 
-	if len(cfg.Workloads) == 0 {
-		return errors.New("Testing workload not set, exit")
-	}
+	// Catch the signals before the namespace is created, so a SIGTERM at any point after
+	// it is handled below and the namespace is deleted by the deferred cleanup.
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(term)
 
-	// 2. Load all workloads
 	err = workload.CreateNamespace(kubeClient, types.Namespace)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
-		err := workload.DeleteNamespace(kubeClient, types.Namespace)
+		err := workload.DeleteNamespace(context.Background(), kubeClient, types.Namespace)
 		if err != nil {
 			glog.Warningf("Failed delete namespace %v: %v", types.Namespace, err)
 		}
 	}()
 
-	workloads, err := loader.LoadAllWorkloads(cfg.Workloads)
+	// Initialize the run-state store, all testing cases are pending before running.
+	results.DefaultStore.Init(types.UUID, types.Provider)
+	for _, wl := range cfg.Workloads {
+		var testingCases []string
+		for _, testingCase := range wl.TestingTool.TestingCaseSet {
+			testingCases = append(testingCases, testingCase.Name)
+		}
+		results.DefaultStore.AddWorkload(wl.Name, wl.Image, wl.TestingTool.Name, wl.Frequency, testingCases)
+	}
+
+	// Keep the config and the final run-state next to the testing results.
+	runDir := path.Join(types.ResultsDir, types.UUID)
+	if err := results.WriteJSON(path.Join(runDir, results.ConfigFile), cfg); err != nil {
+		return errors.Wrap(err, "Failed save capstan config")
+	}
+
+	// 3. Take a fingerprint of the cluster, its key fields are attached to the pushed metrics.
+	fp, err := cluster.GetFingerprint(kubeClient)
 	if err != nil {
-		return errors.Wrap(err, "Failed load workloads")
+		glog.Warningf("Failed take the fingerprint of the cluster: %v", err)
+	} else {
+		types.ClusterLabels = fp.Labels()
+		if err := results.WriteJSON(path.Join(runDir, cluster.FingerprintFile), fp); err != nil {
+			return errors.Wrap(err, "Failed save the fingerprint of the cluster")
+		}
 	}
+	defer func() {
+		if err := results.DefaultStore.Save(path.Join(runDir, results.ResultsFile)); err != nil {
+			glog.Warningf("Failed save the results of run %v: %v", types.UUID, err)
+		}
+		summaries := results.Summarize(results.DefaultStore.Snapshot())
+		if err := results.WriteJSON(path.Join(runDir, results.SummaryFile), summaries); err != nil {
+			glog.Warningf("Failed save the summary of run %v: %v", types.UUID, err)
+		}
+		if _, err := report.Generate(runDir); err != nil {
+			glog.Warningf("Failed generate the report of run %v: %v", types.UUID, err)
+		}
+	}()
 
-	// 3. Start runs all testing workloads sequentially
-	testingDone := make(chan bool)
-	testingErr := make(chan error)
+	// 4. Start runs all testing workloads, sequentially unless a parallelism is set
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// testingDone is buffered, so the testing goroutine never blocks on it.
+	testingDone := make(chan error, 1)
 	go func() {
-		for _, wk := range workloads {
-			err := wk.Run(kubeClient)
-			if err != nil {
-				testingErr <- err
-			}
-			time.Sleep(time.Duration(cfg.Steps) * time.Second)
-		}
-		testingDone <- true
+		testingDone <- runWorkloads(ctx, kubeClient, cfg, workloads)
 	}()
 
-	// 4. Launch the HTTP server
+	// 5. Launch the HTTP server
 	srv := &http.Server{
 		Addr:    cfg.Address,
 		Handler: dashboard.NewHandler(),
@@ -94,18 +135,138 @@ func Run(kubeClient kubernetes.Interface, capstanConfig string) error {
 		doneServ <- srv.ListenAndServe()
 	}()
 
-	term := make(chan os.Signal, 1)
-	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
-
 	select {
-	case <-testingDone:
+	case err := <-testingDone:
+		if err != nil {
+			return err
+		}
 		glog.V(4).Info("Finished all tests")
-	case <-term:
-		glog.V(4).Info("Received SIGTERM, exiting gracefully...")
-	case err := <-testingErr:
-		return err
+	case sig := <-term:
+		glog.V(4).Infof("Received %v, aborting the running testing cases...", sig)
+		cancel()
+		// wait for the running testing cases to be cleaned up.
+		<-testingDone
+		// an aborted run must not look like a completed run to the caller.
+		return errors.Errorf("run aborted by signal %v", sig)
 	case err := <-doneServ:
+		cancel()
+		<-testingDone
 		return err
 	}
 	return nil
 }
+
+// runWorkloads runs all testing workloads and records the final state of the run,
+// it returns once all testing cases have finished or been aborted by the ctx.
+func runWorkloads(ctx context.Context, kubeClient kubernetes.Interface, cfg types.Config, workloads []workload.Interface) error {
+	results.DefaultStore.StartRun()
+
+	var err error
+	if cfg.Parallelism > 1 {
+		err = runParallel(ctx, kubeClient, cfg, workloads)
+	} else {
+		err = runSequential(ctx, kubeClient, cfg, workloads)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		glog.Warningf("Run %v aborted: %v", types.UUID, err)
+		results.DefaultStore.FinishRun(results.StateAborted)
+		return nil
+	case err != nil:
+		results.DefaultStore.FinishRun(results.StateFailed)
+		return err
+	}
+	// The failed testing cases have been skipped by the failure policy.
+	if failed, total := results.DefaultStore.FailedCases(); failed != 0 {
+		results.DefaultStore.FinishRun(results.StatePartiallyFailed)
+		return &PartialFailureError{Failed: failed, Total: total}
+	}
+	results.DefaultStore.FinishRun(results.StateSucceeded)
+	return nil
+}
+
+// PartialFailureError is returned by Run when the run has finished, but some of its
+// testing cases have failed and been skipped by the failure policy.
+type PartialFailureError struct {
+	Failed int
+	Total  int
+}
+
+// Error implements the error interface.
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d testing cases failed, see the failures in %s", e.Failed, e.Total, path.Join(types.ResultsDir, types.UUID))
+}
+
+// runOptions returns the options of running the testing cases of the config.
+func runOptions(cfg types.Config) workload.RunOptions {
+	// The failure policy has been validated with the config.
+	policy, err := workload.ParseFailurePolicy(cfg.FailurePolicy)
+	if err != nil {
+		glog.Warningf("Invalid failure policy %q, using %s: %v", cfg.FailurePolicy, policy, err)
+	}
+	return workload.RunOptions{
+		Namespace:     types.Namespace,
+		FailurePolicy: policy,
+		ResultsDir:    path.Join(types.ResultsDir, types.UUID),
+		Publish:       pushMetrics,
+	}
+}
+
+// runSequential runs the workloads one after another.
+func runSequential(ctx context.Context, kubeClient kubernetes.Interface, cfg types.Config, workloads []workload.Interface) error {
+	for _, wk := range workloads {
+		if err := wk.Run(ctx, kubeClient); err != nil {
+			return err
+		}
+		// aggregate the repeats of each testing case into statistics.
+		if err := pushSummaries(wk.GetName()); err != nil {
+			return err
+		}
+		if err := workload.Sleep(ctx, time.Duration(cfg.Steps)*time.Second); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate reads a capstan config and checks that all of its workloads and
+// testing tools can be loaded, without touching the cluster.
+func Validate(capstanConfig string) error {
+	_, _, err := loadConfig(capstanConfig)
+	return err
+}
+
+// loadConfig reads a capstan config and loads all of its workloads.
+// All problems of the config are reported at once, before anything is created in the cluster.
+func loadConfig(capstanConfig string) (types.Config, []workload.Interface, error) {
+	cfg, err := types.ReadConfig(capstanConfig)
+	configErr, invalid := err.(*types.ConfigError)
+	if err != nil && !invalid {
+		return cfg, nil, errors.Wrap(err, "Failed read capstan config")
+	}
+	if !invalid {
+		configErr = &types.ConfigError{}
+	}
+
+	configErr.Errors = append(configErr.Errors, loader.ValidateWorkloads(cfg.Workloads)...)
+	if len(configErr.Errors) != 0 {
+		return cfg, nil, configErr
+	}
+	// the settings of the run are only changed by a valid config, the run options below use them.
+	types.ApplyConfig(cfg)
+
+	workloads, err := loader.LoadAllWorkloads(cfg.Workloads, runOptions(cfg))
+	if err != nil {
+		return cfg, nil, errors.Wrap(err, "Failed load workloads")
+	}
+
+	// Make sure the testing tool and testing cases of every workload are defined.
+	for _, wk := range workloads {
+		if _, err := wk.TestingTool(); err != nil {
+			return cfg, nil, errors.Wrapf(err, "Failed load the testing tool of %s", wk.GetName())
+		}
+	}
+
+	return cfg, workloads, nil
+}
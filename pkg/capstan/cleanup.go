@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capstan
+
+import (
+	"context"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// protectedNamespaces are never deleted by Cleanup.
+var protectedNamespaces = []string{"default", "kube-system", "kube-public"}
+
+// Cleanup removes the leftover resources of capstan runs in the namespace: all pods and
+// persistent volume claims created by capstan, and the namespace itself if deleteNamespace is true.
+func Cleanup(kubeClient kubernetes.Interface, namespace string, deleteNamespace bool) error {
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(apismetav1.ListOptions{LabelSelector: workload.CapstanSelector})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list capstan pods in namespace %v", namespace)
+	}
+	for _, pod := range pods.Items {
+		glog.V(1).Infof("Deleting capstan pod %s/%s", namespace, pod.Name)
+		err := kubeClient.CoreV1().Pods(namespace).Delete(pod.Name, apismetav1.NewDeleteOptions(0))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete pod %v", pod.Name)
+		}
+	}
+
+	claims, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).List(apismetav1.ListOptions{LabelSelector: workload.CapstanSelector})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list capstan persistent volume claims in namespace %v", namespace)
+	}
+	for _, claim := range claims.Items {
+		glog.V(1).Infof("Deleting capstan persistent volume claim %s/%s", namespace, claim.Name)
+		err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(claim.Name, &apismetav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete persistent volume claim %v", claim.Name)
+		}
+	}
+
+	if !deleteNamespace {
+		return nil
+	}
+	for _, ns := range protectedNamespaces {
+		if namespace == ns {
+			glog.Warningf("Namespace %v is not created by capstan, skip deleting it", namespace)
+			return nil
+		}
+	}
+	if _, err := kubeClient.CoreV1().Namespaces().Get(namespace, apismetav1.GetOptions{}); apierrors.IsNotFound(err) {
+		return nil
+	}
+	glog.V(1).Infof("Deleting capstan namespace %s", namespace)
+	return workload.DeleteNamespace(context.Background(), kubeClient, namespace)
+}
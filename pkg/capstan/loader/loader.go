@@ -18,45 +18,102 @@ package loader
 
 import (
 	"github.com/ZJU-SEL/capstan/pkg/workload"
-	"github.com/ZJU-SEL/capstan/pkg/workload/iperf3"
-	"github.com/ZJU-SEL/capstan/pkg/workload/mysql"
-	"github.com/ZJU-SEL/capstan/pkg/workload/nginx"
+	"github.com/ZJU-SEL/capstan/pkg/workload/generic"
 	"github.com/golang/glog"
-	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	// The built-in workloads register themselves when imported.
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/dns"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/fio"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/iperf3"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/mysql"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/nginx"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/postgres"
+	_ "github.com/ZJU-SEL/capstan/pkg/workload/redis"
 )
 
 // LoadAllWorkloads loads all workloads by parsing workloads section config,
-// return all of workloads which are supported in the capstan.
-func LoadAllWorkloads(workloads []workload.Workload) (ret []workload.Interface, err error) {
+// return all of workloads which are registered in the capstan and run with the options.
+func LoadAllWorkloads(workloads []workload.Workload, opts workload.RunOptions) (ret []workload.Interface, err error) {
 	for _, wl := range workloads {
-		find := false
-		for _, wlDef := range workload.DefWorkloads {
-			if wl.Name == wlDef {
-				w, err := loadWorkload(wl)
-				if err != nil {
-					return ret, errors.Wrap(err, "Failed load the testing workload")
+		factory, err := workload.Lookup(wl.GetType())
+		if err != nil {
+			return ret, err
+		}
+		glog.V(1).Infof("Load a testing workload with config:%v", wl)
+		ret = append(ret, factory.New(wl, opts))
+	}
+	return ret, nil
+}
+
+// DefTestingCaseSet returns the defined testing case set of a testing tool of a workload.
+func DefTestingCaseSet(name, toolName string) ([]string, error) {
+	factory, err := workload.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory.ToolTestingCaseSet(toolName)
+}
+
+// DefToolNames returns the names of the testing tools of a workload, it is empty if the
+// workload allows any testing tool.
+func DefToolNames(name string) ([]string, error) {
+	factory, err := workload.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory.ToolNames(), nil
+}
+
+// ValidateWorkloads checks that the workloads, their testing tools and testing cases
+// are defined in capstan, all problems are returned at once.
+func ValidateWorkloads(workloads []workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, wl := range workloads {
+		fldPath := field.NewPath("Workloads").Index(i)
+		if wl.Name == "" {
+			continue
+		}
+		factory, err := workload.Lookup(wl.GetType())
+		if err != nil {
+			typePath := fldPath.Child("name")
+			if wl.Type != "" {
+				typePath = fldPath.Child("type")
+			}
+			allErrs = append(allErrs, field.NotSupported(typePath, wl.GetType(), workload.Workloads()))
+			continue
+		}
+		// The generic section would be ignored by the other workloads.
+		if wl.Generic != nil && wl.GetType() != generic.WorkloadName {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("generic"), "only a workload of the generic type has a generic section"))
+		}
+
+		toolPath := fldPath.Child("testingTool")
+		testingCaseSet, err := factory.ToolTestingCaseSet(wl.TestingTool.Name)
+		if err != nil && wl.TestingTool.Name != "" {
+			allErrs = append(allErrs, field.NotSupported(toolPath.Child("name"), wl.TestingTool.Name, factory.ToolNames()))
+		}
+
+		if err == nil && len(testingCaseSet) != 0 {
+			for j, testingCase := range wl.TestingTool.TestingCaseSet {
+				if testingCase.Name != "" && !contains(testingCaseSet, testingCase.Name) {
+					allErrs = append(allErrs, field.NotSupported(toolPath.Child("testingCaseSet").Index(j).Child("name"), testingCase.Name, testingCaseSet))
 				}
-				ret = append(ret, w)
-				find = true
 			}
 		}
-		if !find {
-			return ret, errors.Errorf("The testing workload %q has not defined in capstan", wl.Name)
+
+		if factory.Validate != nil {
+			allErrs = append(allErrs, factory.Validate(fldPath, wl)...)
 		}
 	}
-	return ret, nil
+	return allErrs
 }
 
-func loadWorkload(wl workload.Workload) (workload.Interface, error) {
-	glog.V(1).Infof("Load a testing workload with config:%v", wl)
-	switch wl.Name {
-	case "nginx":
-		return nginx.NewWorkload(wl), nil
-	case "iperf3":
-		return iperf3.NewWorkload(wl), nil
-	case "mysql":
-		return mysql.NewWorkload(wl), nil
-	default:
-		return nil, errors.Errorf("unknown workload %v", wl.Name)
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
+	return false
 }
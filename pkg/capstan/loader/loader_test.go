@@ -0,0 +1,117 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	v1 "k8s.io/api/core/v1"
+)
+
+// TestPodNamesDiffer checks that the workload pods and the testing pods of every testing case
+// of the registered workloads have different names, even if the testing tool has the same name
+// as the workload, e.g. iperf3, or two workloads of the same type and testing tool run in parallel.
+func TestPodNamesDiffer(t *testing.T) {
+	for _, name := range workload.Workloads() {
+		factory, err := workload.Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tools := factory.ToolNames()
+		if len(tools) == 0 {
+			tools = []string{name}
+		}
+		for _, tool := range tools {
+			testingCases, err := factory.ToolTestingCaseSet(tool)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(testingCases) == 0 {
+				testingCases = []string{"benchmark"}
+			}
+			for _, testingCase := range testingCases {
+				names := map[string]bool{}
+				// two workloads of the type with the testing tool.
+				for _, workloadName := range []string{name, name + "-2"} {
+					for _, pod := range []string{
+						workload.BuildWorkloadPodName(workloadName, testingCase),
+						workload.BuildTestingPodName(workloadName, tool, testingCase),
+					} {
+						if names[pod] {
+							t.Errorf("workload %s: two pods of testing case %s are named %q", name, testingCase, pod)
+						}
+						names[pod] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestValidateWorkloadsTestingTool(t *testing.T) {
+	tests := []struct {
+		tool        string
+		testingCase string
+		errs        int
+	}{
+		{"redis-benchmark", "benchmarkSameNode", 0},
+		{"memtier", "memtierDiffNode", 0},
+		// the testing cases of the other testing tool.
+		{"memtier", "benchmarkSameNode", 1},
+		{"redis-benchmark", "memtierDiffNode", 1},
+		{"memtier_benchmark", "memtierDiffNode", 1},
+	}
+	for _, test := range tests {
+		wl := workload.Workload{Name: "redis", TestingTool: workload.TestingTool{
+			Name:           test.tool,
+			TestingCaseSet: []workload.TestingCase{{Name: test.testingCase}},
+		}}
+		if errs := ValidateWorkloads([]workload.Workload{wl}); len(errs) != test.errs {
+			t.Errorf("%s %s: errors %v, want %d errors", test.tool, test.testingCase, errs, test.errs)
+		}
+	}
+}
+
+func TestValidateWorkloadsGenericSection(t *testing.T) {
+	spec := &workload.GenericWorkload{
+		Server:  v1.PodSpec{Containers: []v1.Container{{Name: "server"}}},
+		Client:  v1.PodSpec{Containers: []v1.Container{{Name: "client"}}},
+		Metrics: []workload.MetricRegex{{Name: "qps", Regex: `qps: (\d+)`}},
+	}
+	tests := []struct {
+		name string
+		wl   workload.Workload
+		errs []string
+	}{
+		{"generic", workload.Workload{Name: "echo", Type: "generic", Generic: spec}, nil},
+		{"generic without section", workload.Workload{Name: "echo", Type: "generic"}, []string{"Workloads[0].generic"}},
+		{"nginx", workload.Workload{Name: "nginx"}, nil},
+		{"nginx with section", workload.Workload{Name: "nginx", Generic: spec}, []string{"Workloads[0].generic"}},
+		{"typed nginx with section", workload.Workload{Name: "web", Type: "nginx", Generic: spec}, []string{"Workloads[0].generic"}},
+	}
+	for _, test := range tests {
+		var errs []string
+		for _, err := range ValidateWorkloads([]workload.Workload{test.wl}) {
+			errs = append(errs, err.Field)
+		}
+		if !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("%s: errors on %q, want %q", test.name, errs, test.errs)
+		}
+	}
+}
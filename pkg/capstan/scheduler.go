@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capstan
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodesPerJob is the number of nodes reserved for a job, which is enough for
+// the workload pod and the testing pod to run on different nodes.
+const nodesPerJob = 2
+
+// job is a unit of parallel testing: the testing cases of a workload which run
+// sequentially on the same reserved nodes.
+type job struct {
+	workload     workload.Interface
+	frequency    int
+	testingCases []string
+}
+
+// nodePool reserves nodes for parallel jobs, so that parallel testing cases don't share nodes.
+type nodePool struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	free []string
+	size int
+}
+
+// newNodePool creates a node pool of all schedulable nodes of the cluster.
+func newNodePool(kubeClient kubernetes.Interface) (*nodePool, error) {
+	nodes, err := kubeClient.CoreV1().Nodes().List(apismetav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list nodes")
+	}
+
+	p := &nodePool{}
+	p.cond = sync.NewCond(&p.mu)
+	for _, node := range nodes.Items {
+		if !isSchedulable(node) {
+			continue
+		}
+		hostname := node.Labels[workload.LabelHostname]
+		if hostname == "" {
+			hostname = node.Name
+		}
+		p.free = append(p.free, hostname)
+	}
+	p.size = len(p.free)
+	if p.size == 0 {
+		return nil, errors.New("No schedulable nodes to run testing cases")
+	}
+	return p, nil
+}
+
+// Acquire reserves n nodes, it blocks until enough nodes are released or the ctx is cancelled.
+// It fails if the pool has less than n nodes, since the testing cases on different nodes
+// would silently run on the same node.
+func (p *nodePool) Acquire(ctx context.Context, n int) ([]string, error) {
+	if n > p.size {
+		return nil, errors.Errorf("Only %d schedulable nodes, %d distinct nodes are required", p.size, n)
+	}
+
+	// Wake up the waiting below when the ctx is cancelled.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.mu.Lock()
+			p.cond.Broadcast()
+			p.mu.Unlock()
+		case <-stop:
+		}
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.free) < n {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		p.cond.Wait()
+	}
+	nodes := append([]string{}, p.free[:n]...)
+	p.free = p.free[n:]
+	return nodes, nil
+}
+
+// Release returns the reserved nodes to the pool.
+func (p *nodePool) Release(nodes []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, nodes...)
+	p.cond.Broadcast()
+}
+
+// isSchedulable returns true if testing pods can be scheduled to the node.
+func isSchedulable(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == v1.NodeReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// runParallel runs the workloads with at most cfg.Parallelism jobs at the same time.
+// A job is a workload, or a testing case of a workload if cfg.ParallelCases is set,
+// and every job runs on its own reserved nodes. The statistics of a workload are pushed
+// once all of its jobs have finished. No new job is started after a job fails, and the
+// first error is returned once the running jobs have finished.
+func runParallel(ctx context.Context, kubeClient kubernetes.Interface, cfg types.Config, workloads []workload.Interface) error {
+	pool, err := newNodePool(kubeClient)
+	if err != nil {
+		return err
+	}
+	if pool.size < nodesPerJob {
+		return errors.Errorf("Only %d schedulable nodes, parallel testing reserves %d nodes per job, set Parallelism to 1 to run the testing cases sequentially", pool.size, nodesPerJob)
+	}
+	if pool.size < cfg.Parallelism*nodesPerJob {
+		glog.Warningf("Only %d schedulable nodes for parallelism %d, jobs will wait for free nodes", pool.size, cfg.Parallelism)
+	}
+
+	var jobs []job
+	remaining := map[string]int{}
+	for i, wk := range workloads {
+		var testingCases []string
+		for _, testingCase := range cfg.Workloads[i].TestingTool.TestingCaseSet {
+			testingCases = append(testingCases, testingCase.Name)
+		}
+		if !cfg.ParallelCases {
+			jobs = append(jobs, job{workload: wk, frequency: cfg.Workloads[i].Frequency, testingCases: testingCases})
+			remaining[wk.GetName()]++
+			continue
+		}
+		for _, testingCase := range testingCases {
+			jobs = append(jobs, job{workload: wk, frequency: cfg.Workloads[i].Frequency, testingCases: []string{testingCase}})
+			remaining[wk.GetName()]++
+		}
+	}
+
+	opts := runOptions(cfg)
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, cfg.Parallelism)
+	for _, j := range jobs {
+		sem <- struct{}{}
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed || ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			nodes, err := pool.Acquire(ctx, nodesPerJob)
+			if err == nil {
+				glog.V(1).Infof("Reserved nodes %v for the testing cases %v of %s", nodes, j.testingCases, j.workload.GetName())
+				err = runJob(ctx, kubeClient, j, nodes, opts)
+				pool.Release(nodes)
+			}
+
+			name := j.workload.GetName()
+			mu.Lock()
+			remaining[name]--
+			done := remaining[name] == 0
+			mu.Unlock()
+			if err == nil && done {
+				// aggregate the repeats of each testing case into statistics.
+				err = pushSummaries(name)
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(j)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// runJob runs all repeats of the testing cases of a job on the nodes with the options.
+func runJob(ctx context.Context, kubeClient kubernetes.Interface, j job, nodes []string, opts workload.RunOptions) error {
+	// every job has its own testing tool, which keeps the state of its current testing case.
+	testingTool, err := j.workload.TestingTool()
+	if err != nil {
+		return err
+	}
+	testingTool.SetNodes(nodes)
+
+	return workload.RunTestingCases(ctx, kubeClient, j.workload, testingTool, j.frequency, j.testingCases, opts)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capstan
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestNodePool(nodes ...string) *nodePool {
+	p := &nodePool{free: nodes, size: len(nodes)}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// acquireAsync acquires n nodes of the pool in the background.
+func acquireAsync(ctx context.Context, p *nodePool, n int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Acquire(ctx, n)
+		done <- err
+	}()
+	return done
+}
+
+func TestNodePoolAcquire(t *testing.T) {
+	p := newTestNodePool("node-1", "node-2", "node-3")
+	nodes, err := p.Acquire(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nodes, []string{"node-1", "node-2"}) {
+		t.Errorf("nodes %v, want node-1 and node-2", nodes)
+	}
+
+	// only node-3 is free, so the next job waits until the nodes are released.
+	done := acquireAsync(context.Background(), p, 2)
+	select {
+	case err := <-done:
+		t.Fatalf("acquired 2 nodes of 1 free node: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.Release(nodes)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("acquire after release: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire is still waiting after the nodes are released")
+	}
+	if len(p.free) != 1 {
+		t.Errorf("free nodes %v, want 1 node", p.free)
+	}
+}
+
+func TestNodePoolAcquireCancel(t *testing.T) {
+	p := newTestNodePool("node-1", "node-2")
+	nodes, err := p.Acquire(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := acquireAsync(ctx, p, 2)
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("error %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire is still waiting after the ctx is cancelled")
+	}
+
+	// the cancelled acquire has not taken any node.
+	p.Release(nodes)
+	if len(p.free) != 2 {
+		t.Errorf("free nodes %v, want 2 nodes", p.free)
+	}
+}
+
+func TestNodePoolAcquireTooMany(t *testing.T) {
+	p := newTestNodePool("node-1")
+	done := acquireAsync(context.Background(), p, 2)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("acquired 2 nodes of a pool of 1 node")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire of more nodes than the pool has is waiting forever")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capstan
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// pushSummaries pushes the statistics of every metric of every testing case of a workload to
+// Pushgateway, each statistic is pushed as a separate metric, e.g. capstan_wrk_qps_mean. The
+// statistics of a testing case are pushed at once, since a push replaces the metrics of its group.
+func pushSummaries(workloadName string) error {
+	for _, summaries := range groupSummaries(results.Summarize(results.DefaultStore.Snapshot()), workloadName) {
+		var collectors []prometheus.Collector
+		for _, s := range summaries {
+			collectors = append(collectors, summaryCollectors(s)...)
+		}
+
+		s := summaries[0]
+		if err := pushMetrics(
+			s.TestingTool,
+			map[string]string{
+				"workloadName": s.Workload,
+				"testingName":  s.TestingTool,
+				"testingCase":  s.TestingCase,
+			},
+			collectors...,
+		); err != nil {
+			return errors.Wrapf(err, "Could not push the statistics of testing case %s to Pushgateway", s.TestingCase)
+		}
+	}
+	return nil
+}
+
+// groupSummaries returns the summaries of the workload grouped by testing case, in the order of the summaries.
+func groupSummaries(summaries []results.Summary, workloadName string) [][]results.Summary {
+	var groups [][]results.Summary
+	index := map[string]int{}
+	for _, s := range summaries {
+		if s.Workload != workloadName {
+			continue
+		}
+		key := s.TestingTool + "/" + s.TestingCase
+		i, found := index[key]
+		if !found {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+	return groups
+}
+
+// summaryCollectors returns a gauge of every statistic of a metric of a testing case.
+func summaryCollectors(s results.Summary) []prometheus.Collector {
+	stats := []struct {
+		name  string
+		value float64
+	}{
+		{"count", float64(s.Count)},
+		{"mean", s.Mean},
+		{"median", s.Median},
+		{"stddev", s.Stddev},
+		{"min", s.Min},
+		{"max", s.Max},
+		{"p90", s.P90},
+		{"p95", s.P95},
+		{"cv", s.CV},
+	}
+
+	var collectors []prometheus.Collector
+	for _, stat := range stats {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: invalidMetricChars.ReplaceAllString(fmt.Sprintf("capstan_%s_%s_%s", s.TestingTool, s.Metric, stat.name), "_"),
+			Help: fmt.Sprintf("The %s of %s over all repeats of %s testing case", stat.name, s.Metric, s.TestingTool),
+		})
+		gauge.Set(stat.value)
+		collectors = append(collectors, gauge)
+	}
+	return collectors
+}
+
+// pushMetrics pushes the collectors to Pushgateway with the labels of the run and of the
+// cluster added to the labels (to adhere to workload.Publisher).
+func pushMetrics(job string, labels map[string]string, collectors ...prometheus.Collector) error {
+	grouping := map[string]string{
+		"uid":      types.UUID,
+		"provider": types.Provider,
+	}
+	for k, v := range labels {
+		grouping[k] = v
+	}
+	return push.Collectors(job, types.WithClusterLabels(grouping), types.PushgatewayEndpoint, collectors...)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capstan
+
+import (
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGroupSummaries(t *testing.T) {
+	summaries := []results.Summary{
+		{Workload: "nginx", TestingTool: "wrk", TestingCase: "benchmarkSameNode", Metric: "qps"},
+		{Workload: "nginx", TestingTool: "wrk", TestingCase: "benchmarkSameNode", Metric: "latency_p99"},
+		{Workload: "nginx", TestingTool: "wrk", TestingCase: "benchmarkDiffNode", Metric: "qps"},
+		{Workload: "redis", TestingTool: "redis-benchmark", TestingCase: "benchmarkSameNode", Metric: "rps"},
+		{Workload: "nginx", TestingTool: "wrk", TestingCase: "benchmarkSameNode", Metric: "transfer"},
+	}
+	groups := groupSummaries(summaries, "nginx")
+	if len(groups) != 2 || len(groups[0]) != 3 || len(groups[1]) != 1 {
+		t.Fatalf("groups %+v, want the 3 metrics of benchmarkSameNode and the metric of benchmarkDiffNode", groups)
+	}
+
+	// all the statistics of all the metrics of a testing case are pushed at once, so their
+	// names must not collide.
+	registry := prometheus.NewRegistry()
+	for _, s := range groups[0] {
+		for _, c := range summaryCollectors(s) {
+			if err := registry.Register(c); err != nil {
+				t.Fatalf("statistics of %s: %v", s.Metric, err)
+			}
+		}
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 3*9 {
+		t.Errorf("%d metrics pushed, want 9 statistics of 3 metrics", len(families))
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// IncludeKey is the key of the list of configs a config is based on.
+const IncludeKey = "include"
+
+// envVar matches ${ENV_VAR} and ${ENV_VAR:-default}.
+var envVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// loadDocument reads a JSON or YAML config, substitutes the environment variables in its
+// string values, and merges the configs listed in its include section into it. The configs are returned
+// as a JSON-compatible document.
+func loadDocument(filename string, loading map[string]bool) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if loading[abs] {
+		return nil, errors.Errorf("config %s includes itself", filename)
+	}
+	loading[abs] = true
+	defer delete(loading, abs)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !isJSON(filename, data) {
+		data, err = yaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse YAML config %s", filename)
+		}
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse capstan config %s", filename)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	// The variables are substituted after parsing, so their values can't break the syntax
+	// of the config. The strings of numbers and booleans are converted by convertScalars.
+	var missing []string
+	substituteEnv(doc, &missing)
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return nil, errors.Errorf("environment variables used in %s are not set: %s", filename, strings.Join(missing, ", "))
+	}
+
+	includes, err := includeList(doc[IncludeKey])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid %s section in %s", IncludeKey, filename)
+	}
+	delete(doc, IncludeKey)
+
+	// The included configs are merged in order, the including config overrides all of them.
+	merged := map[string]interface{}{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		base, err := loadDocument(include, loading)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to include %s", include)
+		}
+		merged = mergeObject(merged, base)
+	}
+	return mergeObject(merged, doc), nil
+}
+
+// isJSON detects the format of a config by its extension, or by its content
+// if the extension is unknown.
+func isJSON(filename string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return true
+	case ".yaml", ".yml":
+		return false
+	}
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// substituteEnv replaces ${ENV_VAR} in the string values of a document with the value of
+// the environment variable, and ${ENV_VAR:-default} with the default if the variable is
+// unset or empty. Keys are kept as they are. The unset variables without a default are
+// added to missing.
+func substituteEnv(doc interface{}, missing *[]string) interface{} {
+	switch value := doc.(type) {
+	case string:
+		return envVar.ReplaceAllStringFunc(value, func(match string) string {
+			groups := envVar.FindStringSubmatch(match)
+			name := groups[1]
+			if env := os.Getenv(name); env != "" {
+				return env
+			}
+			if strings.HasPrefix(groups[2], ":-") {
+				return groups[3]
+			}
+			if _, found := os.LookupEnv(name); !found && !containsString(*missing, name) {
+				*missing = append(*missing, name)
+			}
+			return ""
+		})
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = substituteEnv(item, missing)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = substituteEnv(item, missing)
+		}
+	}
+	return doc
+}
+
+// convertScalars converts the strings of a document to numbers or booleans where the field
+// of type t is a number or a boolean, since the environment variables are substituted into
+// strings, e.g. frequency: ${CAPSTAN_FREQUENCY:-3}. Other strings are kept, so they can't
+// be turned into numbers by the value of a variable.
+func convertScalars(doc interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return doc
+	}
+
+	switch value := doc.(type) {
+	case string:
+		switch t.Kind() {
+		case reflect.Bool:
+			if value == "true" || value == "false" {
+				return value == "true"
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return f
+			}
+		}
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for key, item := range value {
+				if f, found := findField(fields, key); found {
+					value[key] = convertScalars(item, f.Type)
+				}
+			}
+		case reflect.Map:
+			for key, item := range value {
+				value[key] = convertScalars(item, t.Elem())
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range value {
+				value[i] = convertScalars(item, t.Elem())
+			}
+		}
+	}
+	return doc
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// includeList returns the include section of a config, which is a list of paths.
+func includeList(section interface{}) ([]string, error) {
+	if section == nil {
+		return nil, nil
+	}
+	items, ok := section.([]interface{})
+	if !ok {
+		return nil, errors.New("must be a list of paths")
+	}
+	var includes []string
+	for _, item := range items {
+		include, ok := item.(string)
+		if !ok || include == "" {
+			return nil, errors.New("must be a list of paths")
+		}
+		includes = append(includes, include)
+	}
+	return includes, nil
+}
+
+// mergeObject merges the override into the base. Objects are merged recursively with
+// case-insensitive keys, lists of named objects (e.g. workloads and testing cases) are
+// merged by name, and any other value of the override replaces the one of the base.
+func mergeObject(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		baseKey := findKey(base, key)
+		if baseKey == "" {
+			base[key] = value
+			continue
+		}
+		merged := mergeValue(base[baseKey], value)
+		delete(base, baseKey)
+		base[key] = merged
+	}
+	return base
+}
+
+func mergeValue(base, override interface{}) interface{} {
+	switch o := override.(type) {
+	case map[string]interface{}:
+		if b, ok := base.(map[string]interface{}); ok {
+			return mergeObject(b, o)
+		}
+	case []interface{}:
+		if b, ok := base.([]interface{}); ok && isNamedList(b) && isNamedList(o) {
+			return mergeNamedList(b, o)
+		}
+	}
+	return override
+}
+
+// mergeNamedList merges the objects of the override into the objects of the base with
+// the same name, the objects with new names are appended.
+func mergeNamedList(base, override []interface{}) []interface{} {
+	merged := append([]interface{}{}, base...)
+	for _, item := range override {
+		obj := item.(map[string]interface{})
+		found := false
+		for i, baseItem := range merged {
+			baseObj := baseItem.(map[string]interface{})
+			if objectName(baseObj) == objectName(obj) {
+				merged[i] = mergeObject(baseObj, obj)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, obj)
+		}
+	}
+	return merged
+}
+
+// isNamedList returns true if every item of the list is an object with a name.
+func isNamedList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		obj, ok := item.(map[string]interface{})
+		if !ok || objectName(obj) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func objectName(obj map[string]interface{}) string {
+	name, _ := obj[findKey(obj, "name")].(string)
+	return name
+}
+
+// findKey finds the key of an object case-insensitively, the same as encoding/json does.
+func findKey(obj map[string]interface{}, key string) string {
+	if _, found := obj[key]; found {
+		return key
+	}
+	for k := range obj {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return ""
+}
@@ -0,0 +1,323 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+// writeConfigs writes the configs by their paths relative to dir.
+func writeConfigs(t *testing.T, dir string, configs map[string]string) {
+	for name, config := range configs {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadDocumentSubstitutesEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capstan-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := `# ${NOT_SET} in a comment is ignored.
+Provider: ${CAPSTAN_TEST_PROVIDER}
+Steps: ${CAPSTAN_TEST_STEPS:-10}
+Namespace: capstan-${CAPSTAN_TEST_NAMESPACE:-}
+Workloads:
+- name: nginx
+  frequency: ${CAPSTAN_TEST_FREQUENCY}
+  ${CAPSTAN_TEST_KEY}: kept
+`
+	filename := filepath.Join(dir, "capstan.yaml")
+	writeConfigs(t, dir, map[string]string{"capstan.yaml": config})
+	// The values are not parsed as YAML, so they may contain any characters.
+	os.Setenv("CAPSTAN_TEST_PROVIDER", `aliyun: "#1"`)
+	os.Setenv("CAPSTAN_TEST_FREQUENCY", "3")
+	defer os.Unsetenv("CAPSTAN_TEST_PROVIDER")
+	defer os.Unsetenv("CAPSTAN_TEST_FREQUENCY")
+
+	doc, err := loadDocument(filename, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"Provider":  `aliyun: "#1"`,
+		"Steps":     "10",
+		"Namespace": "capstan-",
+	} {
+		if doc[key] != want {
+			t.Errorf("%s = %#v, want %q", key, doc[key], want)
+		}
+	}
+	wl := doc["Workloads"].([]interface{})[0].(map[string]interface{})
+	if wl["frequency"] != "3" {
+		t.Errorf("frequency = %#v, want \"3\"", wl["frequency"])
+	}
+	if wl["${CAPSTAN_TEST_KEY}"] != "kept" {
+		t.Errorf("keys are substituted: %v", wl)
+	}
+
+	os.Unsetenv("CAPSTAN_TEST_FREQUENCY")
+	if _, err := loadDocument(filename, map[string]bool{}); err == nil || !strings.Contains(err.Error(), "CAPSTAN_TEST_FREQUENCY") {
+		t.Errorf("loading a config with an unset environment variable: %v, want an error", err)
+	}
+}
+
+func TestConvertScalars(t *testing.T) {
+	doc := map[string]interface{}{
+		"Steps":         "10",
+		"ParallelCases": "true",
+		"Provider":      "3",
+		"Workloads": []interface{}{
+			map[string]interface{}{
+				"name":      "1",
+				"Frequency": " 3 ",
+				"testingTool": map[string]interface{}{
+					"testingCaseSet": []interface{}{
+						map[string]interface{}{
+							"timeout": "60",
+							"options": map[string]interface{}{"iodepth": "16"},
+						},
+					},
+				},
+			},
+		},
+		"unknown": "1",
+	}
+	convertScalars(doc, reflect.TypeOf(Config{}))
+
+	want := map[string]interface{}{
+		"Steps":         float64(10),
+		"ParallelCases": true,
+		// Strings stay strings, even if they look like numbers.
+		"Provider": "3",
+		"Workloads": []interface{}{
+			map[string]interface{}{
+				"name":      "1",
+				"Frequency": float64(3),
+				"testingTool": map[string]interface{}{
+					"testingCaseSet": []interface{}{
+						map[string]interface{}{
+							"timeout": float64(60),
+							"options": map[string]interface{}{"iodepth": "16"},
+						},
+					},
+				},
+			},
+		},
+		"unknown": "1",
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("converted document %v, want %v", doc, want)
+	}
+}
+
+func TestReadConfigIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capstan-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfigs(t, dir, map[string]string{
+		"base/base.yaml": `Steps: 1
+Namespace: capstan
+Prometheus:
+  PushgatewayEndpoint: http://127.0.0.1:9091
+Workloads:
+- name: nginx
+  image: nginx
+  frequency: 1
+  testingTool:
+    name: wrk
+    image: wrk
+    testingCaseSet:
+    - name: benchmarkSameNode
+      timeout: 60
+    - name: benchmarkDiffNode
+`,
+		// The paths of the included configs are relative to the including config.
+		"base/tuning.json": `{"Parallelism": 2, "Steps": 3}`,
+		"aliyun/capstan.yaml": `include:
+- ../base/base.yaml
+- ../base/tuning.json
+Provider: aliyun
+steps: ${CAPSTAN_TEST_STEPS:-5}
+Workloads:
+- name: nginx
+  Frequency: 2
+  testingTool:
+    testingCaseSet:
+    - name: benchmarkDiffNode
+      timeout: 30
+    - name: benchmarkPod2Pod
+- name: redis
+  image: redis
+  frequency: 1
+  testingTool:
+    name: redis-benchmark
+    image: redis
+    testingCaseSet:
+    - name: benchmarkSameNode
+`,
+	})
+
+	config, err := ReadConfig(filepath.Join(dir, "aliyun", "capstan.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The including config overrides the included ones, which are merged in order,
+	// and the keys are merged case-insensitively.
+	if config.Provider != "aliyun" || config.Namespace != "capstan" || config.Steps != 5 || config.Parallelism != 2 {
+		t.Errorf("Provider %q, Namespace %q, Steps %d, Parallelism %d, want aliyun, capstan, 5 and 2",
+			config.Provider, config.Namespace, config.Steps, config.Parallelism)
+	}
+	// The workloads and the testing cases are merged by name.
+	want := []workload.Workload{
+		{
+			Name:      "nginx",
+			Image:     "nginx",
+			Frequency: 2,
+			TestingTool: workload.TestingTool{
+				Name:  "wrk",
+				Image: "wrk",
+				TestingCaseSet: []workload.TestingCase{
+					{Name: "benchmarkSameNode", Timeout: 60},
+					{Name: "benchmarkDiffNode", Timeout: 30},
+					{Name: "benchmarkPod2Pod"},
+				},
+			},
+		},
+		{
+			Name:      "redis",
+			Image:     "redis",
+			Frequency: 1,
+			TestingTool: workload.TestingTool{
+				Name:           "redis-benchmark",
+				Image:          "redis",
+				TestingCaseSet: []workload.TestingCase{{Name: "benchmarkSameNode"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(config.Workloads, want) {
+		t.Errorf("workloads %+v, want %+v", config.Workloads, want)
+	}
+}
+
+func TestLoadDocumentIncludeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capstan-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfigs(t, dir, map[string]string{
+		"self.yaml":     "include: [self.yaml]\n",
+		"a.yaml":        "include: [sub/b.yaml]\n",
+		"sub/b.yaml":    "include: [../a.yaml]\n",
+		"missing.yaml":  "include: [none.yaml]\n",
+		"invalid.yaml":  "include: base.yaml\n",
+		"diamond.yaml":  "include: [a2.yaml, b2.yaml]\n",
+		"a2.yaml":       "include: [common.yaml]\nSteps: 1\n",
+		"b2.yaml":       "include: [common.yaml]\nParallelism: 1\n",
+		"common.yaml":   "Namespace: capstan\n",
+		"unset.yaml":    "include: [unset2.yaml]\n",
+		"unset2.yaml":   "Provider: ${CAPSTAN_TEST_UNSET}\n",
+		"malformed.yml": "Steps: [1\n",
+	})
+
+	for _, test := range []struct {
+		config string
+		err    string
+	}{
+		{"self.yaml", "includes itself"},
+		{"a.yaml", "includes itself"},
+		{"missing.yaml", "none.yaml"},
+		{"invalid.yaml", "must be a list of paths"},
+		{"unset.yaml", "CAPSTAN_TEST_UNSET"},
+		{"malformed.yml", "Failed to parse YAML config"},
+		// A config included twice, but not by itself, is not a cycle.
+		{"diamond.yaml", ""},
+	} {
+		_, err := loadDocument(filepath.Join(dir, test.config), map[string]bool{})
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.config, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want an error containing %q", test.config, err, test.err)
+		}
+	}
+}
+
+func TestMergeObject(t *testing.T) {
+	base := map[string]interface{}{
+		"Steps": float64(1),
+		"Prometheus": map[string]interface{}{
+			"PushgatewayEndpoint": "http://127.0.0.1:9091",
+		},
+		"Workloads": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "nginx", "frequency": float64(1)},
+			map[string]interface{}{"name": "redis", "image": "redis"},
+		},
+		"args": []interface{}{"-c", "10"},
+	}
+	override := map[string]interface{}{
+		"steps":      float64(2),
+		"Prometheus": map[string]interface{}{"Extra": "x"},
+		"Workloads": []interface{}{
+			map[string]interface{}{"Name": "redis", "image": "redis:5"},
+			map[string]interface{}{"name": "mysql"},
+		},
+		// Lists of values which are not named objects are replaced.
+		"args": []interface{}{"-t", "2"},
+	}
+
+	want := map[string]interface{}{
+		"steps": float64(2),
+		"Prometheus": map[string]interface{}{
+			"PushgatewayEndpoint": "http://127.0.0.1:9091",
+			"Extra":               "x",
+		},
+		"Workloads": []interface{}{
+			map[string]interface{}{"name": "nginx", "image": "nginx", "frequency": float64(1)},
+			// The keys of the override replace the ones of the base.
+			map[string]interface{}{"Name": "redis", "image": "redis:5"},
+			map[string]interface{}{"name": "mysql"},
+		},
+		"args": []interface{}{"-t", "2"},
+	}
+	if got := mergeObject(base, override); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v, want %v", got, want)
+	}
+}
@@ -18,7 +18,7 @@ package types
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"reflect"
 
 	"github.com/ZJU-SEL/capstan/pkg/prometheus"
 	"github.com/ZJU-SEL/capstan/pkg/workload"
@@ -34,11 +34,25 @@ var (
 	// PushgatewayEndpoint is the endpoint of pushGateway.
 	PushgatewayEndpoint string
 	// Namespace is the namespace of capstan.
-	Namespace = "capstan"
+	Namespace = workload.DefaultNamespace
 	// UUID is used to mark a run of capstan.
 	UUID string
+	// ClusterLabels are the key fields of the fingerprint of the cluster, which are
+	// attached to the metrics pushed to Pushgateway.
+	ClusterLabels map[string]string
 )
 
+// WithClusterLabels adds the ClusterLabels to the labels of a pushed metric and returns them,
+// the labels of the metric take precedence.
+func WithClusterLabels(labels map[string]string) map[string]string {
+	for name, value := range ClusterLabels {
+		if _, found := labels[name]; !found {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
 // Config is the internal representation of capstan configuration.
 type Config struct {
 	UUID       string `json:"UUID"`
@@ -47,23 +61,51 @@ type Config struct {
 	Address    string `json:"Address"`
 	Steps      int    `json:"Steps"`
 	Namespace  string `json:"Namespace"`
-	Prometheus prometheus.Config
-	Workloads  []workload.Workload
+	// Parallelism is the max number of workloads (or testing cases if ParallelCases
+	// is set) running at the same time, they run sequentially if it is 0 or 1.
+	Parallelism   int  `json:"Parallelism"`
+	ParallelCases bool `json:"ParallelCases"`
+	// FailurePolicy is what to do when a testing case fails: abort (the default),
+	// skip-case or retry-N.
+	FailurePolicy string `json:"FailurePolicy"`
+	Prometheus    prometheus.Config
+	Workloads     []workload.Workload
 }
 
 // ReadConfig reads from a file with the given name and returns
 // a config or an error if the file was unable to be parsed.
+// The config can be JSON or YAML, with ${ENV_VAR} and ${ENV_VAR:-default} substituted
+// from the environment in its string values, and the configs in its include section merged in.
+// Unknown fields and invalid values are reported together in a *ConfigError,
+// along with the parsed config.
+// It does not change the settings of the run, see ApplyConfig.
 func ReadConfig(filepath string) (Config, error) {
-	data, err := ioutil.ReadFile(filepath)
+	doc, err := loadDocument(filepath, map[string]bool{})
+	if err != nil {
+		return Config{}, err
+	}
+	convertScalars(doc, reflect.TypeOf(Config{}))
+	data, err := json.Marshal(doc)
 	if err != nil {
 		return Config{}, errors.WithStack(err)
 	}
 	config := Config{}
 	err = json.Unmarshal(data, &config)
 	if err != nil {
-		return Config{}, errors.WithStack(err)
+		return Config{}, errors.Wrapf(err, "Failed to parse capstan config %s", filepath)
 	}
 
+	allErrs := validateFields(nil, doc, reflect.TypeOf(config))
+	allErrs = append(allErrs, ValidateConfig(config)...)
+	if len(allErrs) != 0 {
+		return config, &ConfigError{Errors: allErrs}
+	}
+	return config, nil
+}
+
+// ApplyConfig sets the UUID, results directory, provider, namespace and Pushgateway
+// endpoint of the run from a config, it must only be called once the config is valid.
+func ApplyConfig(config Config) {
 	if config.UUID != "" {
 		UUID = config.UUID
 	} else {
@@ -81,6 +123,4 @@ func ReadConfig(filepath string) (Config, error) {
 	}
 
 	PushgatewayEndpoint = config.Prometheus.PushgatewayEndpoint
-
-	return config, nil
 }
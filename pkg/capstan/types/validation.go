@@ -0,0 +1,304 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validUUID matches the uuid of a run, which is used as a directory name.
+var validUUID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// ConfigError is the error of an invalid capstan config, it contains all the problems found.
+type ConfigError struct {
+	Errors field.ErrorList
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	msgs := []string{"invalid capstan config:"}
+	for _, err := range e.Errors {
+		msgs = append(msgs, "  "+err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ValidateConfig checks the fields of a capstan config which don't depend on a specific workload.
+func ValidateConfig(config Config) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if config.UUID != "" && (!validUUID.MatchString(config.UUID) || strings.Contains(config.UUID, "..")) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("UUID"), config.UUID, "must consist of alphanumeric characters, '.', '_' or '-'"))
+	}
+
+	if config.Address != "" {
+		allErrs = append(allErrs, validateAddress(field.NewPath("Address"), config.Address)...)
+	}
+
+	if config.Steps < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("Steps"), config.Steps, "must be greater than or equal to 0"))
+	}
+
+	if config.Parallelism < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("Parallelism"), config.Parallelism, "must be greater than or equal to 0"))
+	}
+
+	if _, err := workload.ParseFailurePolicy(config.FailurePolicy); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("FailurePolicy"), config.FailurePolicy, "must be abort, skip-case or retry-N with N greater than 0"))
+	}
+
+	if config.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(config.Namespace) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("Namespace"), config.Namespace, msg))
+		}
+	}
+
+	endpointPath := field.NewPath("Prometheus", "PushgatewayEndpoint")
+	if config.Prometheus.PushgatewayEndpoint == "" {
+		allErrs = append(allErrs, field.Required(endpointPath, "the metrics of testing cases are pushed to Pushgateway"))
+	} else if u, err := url.Parse(config.Prometheus.PushgatewayEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(endpointPath, config.Prometheus.PushgatewayEndpoint, "must be an http or https URL, e.g. http://127.0.0.1:9091"))
+	}
+
+	workloadsPath := field.NewPath("Workloads")
+	if len(config.Workloads) == 0 {
+		allErrs = append(allErrs, field.Required(workloadsPath, "at least one testing workload is required"))
+	}
+	names := map[string]bool{}
+	for i, wl := range config.Workloads {
+		if wl.Name != "" && names[wl.Name] {
+			allErrs = append(allErrs, field.Duplicate(workloadsPath.Index(i).Child("name"), wl.Name))
+		}
+		names[wl.Name] = true
+		allErrs = append(allErrs, validateWorkload(workloadsPath.Index(i), wl)...)
+	}
+
+	return allErrs
+}
+
+// workloadImageRequired returns whether the testing cases of the workload run a workload
+// pod from its image, which is assumed if the workload is not registered.
+func workloadImageRequired(wl workload.Workload) bool {
+	factory, err := workload.Lookup(wl.GetType())
+	return err != nil || !factory.NoWorkloadPod
+}
+
+// validateWorkload checks the required fields of a testing workload.
+func validateWorkload(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if wl.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+	if wl.Image == "" && workloadImageRequired(wl) {
+		allErrs = append(allErrs, field.Required(fldPath.Child("image"), ""))
+	}
+	if wl.Frequency <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("frequency"), wl.Frequency, "must be greater than 0"))
+	}
+
+	toolPath := fldPath.Child("testingTool")
+	if wl.TestingTool.Name == "" {
+		allErrs = append(allErrs, field.Required(toolPath.Child("name"), ""))
+	}
+	if wl.TestingTool.Image == "" {
+		allErrs = append(allErrs, field.Required(toolPath.Child("image"), ""))
+	}
+	if wl.TestingTool.Steps < 0 {
+		allErrs = append(allErrs, field.Invalid(toolPath.Child("steps"), wl.TestingTool.Steps, "must be greater than or equal to 0"))
+	}
+
+	casesPath := toolPath.Child("testingCaseSet")
+	if len(wl.TestingTool.TestingCaseSet) == 0 {
+		allErrs = append(allErrs, field.Required(casesPath, "at least one testing case is required"))
+	}
+	names := map[string]bool{}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		namePath := casesPath.Index(i).Child("name")
+		if testingCase.Name == "" {
+			allErrs = append(allErrs, field.Required(namePath, ""))
+			continue
+		}
+		if names[testingCase.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, testingCase.Name))
+		}
+		names[testingCase.Name] = true
+		if wl.Name != "" && wl.TestingTool.Name != "" {
+			allErrs = append(allErrs, validatePodNames(namePath, wl, testingCase.Name)...)
+		}
+	}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		if testingCase.StartTimeout < 0 {
+			allErrs = append(allErrs, field.Invalid(casesPath.Index(i).Child("startTimeout"), testingCase.StartTimeout, "must be greater than or equal to 0"))
+		}
+		if testingCase.Timeout < 0 {
+			allErrs = append(allErrs, field.Invalid(casesPath.Index(i).Child("timeout"), testingCase.Timeout, "must be greater than or equal to 0"))
+		}
+		switch testingCase.Placement {
+		case "", workload.PlacementSameNode, workload.PlacementDifferentNode:
+		default:
+			allErrs = append(allErrs, field.NotSupported(casesPath.Index(i).Child("placement"), testingCase.Placement, []string{workload.PlacementSameNode, workload.PlacementDifferentNode}))
+		}
+	}
+
+	return allErrs
+}
+
+// validatePodNames checks that the names of the workload pod and the testing pod of a testing
+// case are valid pod names, and valid label values since the pods are labeled with their names.
+func validatePodNames(fldPath *field.Path, wl workload.Workload, testingCase string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for _, name := range []string{
+		workload.BuildWorkloadPodName(wl.Name, testingCase),
+		workload.BuildTestingPodName(wl.Name, wl.TestingTool.Name, testingCase),
+	} {
+		msgs := append(validation.IsDNS1123Subdomain(name), validation.IsValidLabelValue(name)...)
+		for _, msg := range msgs {
+			allErrs = append(allErrs, field.Invalid(fldPath, testingCase, fmt.Sprintf("the pod name %q %s", name, msg)))
+		}
+	}
+	return allErrs
+}
+
+// validateAddress checks the address is a valid host:port to listen on.
+func validateAddress(fldPath *field.Path, address string) field.ErrorList {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, address, "must be in the form host:port")}
+	}
+	if host != "" && net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(host)) != 0 {
+		return field.ErrorList{field.Invalid(fldPath, address, "host must be an IP address or a hostname")}
+	}
+	if p, err := strconv.Atoi(port); err != nil || len(validation.IsValidPortNum(p)) != 0 {
+		return field.ErrorList{field.Invalid(fldPath, address, "port must be a number between 1 and 65535")}
+	}
+	return nil
+}
+
+// validateFields checks that every key of the decoded JSON document is a field of type t.
+// Keys are matched case-insensitively, the same as encoding/json does.
+func validateFields(fldPath *field.Path, doc interface{}, t reflect.Type) field.ErrorList {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Types with custom decoding (e.g. time.Time) are opaque.
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			f, found := findField(fields, key)
+			if !found {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Child(key), "unknown field"))
+				continue
+			}
+			allErrs = append(allErrs, validateFields(fldPath.Child(key), obj[key], f.Type)...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := doc.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			allErrs = append(allErrs, validateFields(fldPath.Index(i), item, t.Elem())...)
+		}
+	case reflect.Map:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key, value := range obj {
+			allErrs = append(allErrs, validateFields(fldPath.Key(key), value, t.Elem())...)
+		}
+	}
+	return allErrs
+}
+
+// jsonFields returns the JSON names and the fields of a struct type, including promoted fields.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, pf := range jsonFields(ft) {
+					if _, found := fields[n]; !found {
+						fields[n] = pf
+					}
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// findField finds the field of a JSON key, an exact match is preferred to a case-insensitive one.
+func findField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, found := fields[key]; found {
+		return f, true
+	}
+	for name, f := range fields {
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const validConfig = `Prometheus:
+  PushgatewayEndpoint: http://127.0.0.1:9091
+Workloads:
+- name: web
+  image: nginx
+  frequency: 1
+  testingTool:
+    name: ab
+    image: httpd
+    testingCaseSet:
+    - name: sameNode
+      timeout: 60
+    - name: diffNode
+`
+
+func TestReadConfigValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit changes the valid config, which is decoded from YAML.
+		edit func(doc map[string]interface{})
+		// errs are the type and the field of the expected errors.
+		errs []string
+	}{
+		{
+			name: "valid",
+			edit: func(doc map[string]interface{}) {},
+		},
+		{
+			name: "unknown fields",
+			edit: func(doc map[string]interface{}) {
+				doc["Step"] = 1
+				wl := workloadOf(doc)
+				wl["frequncy"] = 1
+				tool(wl)["args"] = ""
+				testingCase(wl, 0)["timout"] = 1
+			},
+			errs: []string{
+				"FieldValueForbidden Step",
+				"FieldValueForbidden Workloads[0].frequncy",
+				"FieldValueForbidden Workloads[0].testingTool.args",
+				"FieldValueForbidden Workloads[0].testingTool.testingCaseSet[0].timout",
+			},
+		},
+		{
+			name: "keys matched case-insensitively",
+			edit: func(doc map[string]interface{}) {
+				doc["steps"] = 2
+				wl := workloadOf(doc)
+				wl["Frequency"] = wl["frequency"]
+				delete(wl, "frequency")
+			},
+		},
+		{
+			name: "duplicate workloads",
+			edit: func(doc map[string]interface{}) {
+				doc["Workloads"] = append(doc["Workloads"].([]interface{}), workloadOf(doc))
+			},
+			errs: []string{"FieldValueDuplicate Workloads[1].name"},
+		},
+		{
+			name: "duplicate testing cases",
+			edit: func(doc map[string]interface{}) {
+				testingCase(workloadOf(doc), 1)["name"] = "sameNode"
+			},
+			errs: []string{"FieldValueDuplicate Workloads[0].testingTool.testingCaseSet[1].name"},
+		},
+		{
+			name: "negative timeouts",
+			edit: func(doc map[string]interface{}) {
+				testingCase(workloadOf(doc), 0)["timeout"] = -1
+				testingCase(workloadOf(doc), 1)["startTimeout"] = -1
+			},
+			errs: []string{
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[0].timeout",
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[1].startTimeout",
+			},
+		},
+		{
+			name: "pod name too long",
+			edit: func(doc map[string]interface{}) {
+				testingCase(workloadOf(doc), 0)["name"] = strings.Repeat("a", 60)
+			},
+			// Both pod names are longer than a label value.
+			errs: []string{
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[0].name",
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[0].name",
+			},
+		},
+		{
+			name: "invalid pod name",
+			edit: func(doc map[string]interface{}) {
+				tool(workloadOf(doc))["name"] = "a_b"
+			},
+			// Only the testing pod names contain the testing tool name, an underscore is
+			// valid in a label value but not in a pod name.
+			errs: []string{
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[0].name",
+				"FieldValueInvalid Workloads[0].testingTool.testingCaseSet[1].name",
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "capstan-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range tests {
+		doc := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(validConfig), &doc); err != nil {
+			t.Fatal(err)
+		}
+		test.edit(doc)
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(dir, "capstan.yaml")
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var errs []string
+		_, err = ReadConfig(filename)
+		if err != nil {
+			configErr, ok := err.(*ConfigError)
+			if !ok {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+				continue
+			}
+			for _, e := range configErr.Errors {
+				errs = append(errs, string(e.Type)+" "+e.Field)
+			}
+		}
+		sort.Strings(errs)
+		if !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("%s: errors = %q, want %q (%v)", test.name, errs, test.errs, err)
+		}
+	}
+}
+
+func workloadOf(doc map[string]interface{}) map[string]interface{} {
+	return doc["Workloads"].([]interface{})[0].(map[string]interface{})
+}
+
+func tool(wl map[string]interface{}) map[string]interface{} {
+	return wl["testingTool"].(map[string]interface{})
+}
+
+func testingCase(wl map[string]interface{}, i int) map[string]interface{} {
+	return tool(wl)["testingCaseSet"].([]interface{})[i].(map[string]interface{})
+}
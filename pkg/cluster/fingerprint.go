@@ -0,0 +1,269 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// FingerprintFile is the file of the cluster fingerprint under the results directory of a run.
+	FingerprintFile = "cluster.json"
+
+	// ProxyModeUnknown is the kube-proxy mode when it can't be detected.
+	ProxyModeUnknown = "unknown"
+	// CNIUnknown is the CNI when it can't be detected.
+	CNIUnknown = "unknown"
+
+	systemNamespace        = "kube-system"
+	kubeProxyName          = "kube-proxy"
+	defaultClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+var (
+	// the well-known labels of nodes, the newer ones first.
+	instanceTypeLabels = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"}
+	zoneLabels         = []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"}
+	regionLabels       = []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"}
+
+	// knownCNIs are the names of the daemonsets of the CNI plugins in kube-system.
+	knownCNIs = []string{"cilium", "canal", "calico", "flannel", "weave", "kube-router", "terway", "aws-node", "antrea", "kube-ovn", "kindnet", "contiv", "romana"}
+
+	proxyModeFlag   = regexp.MustCompile(`--proxy-mode[= ]"?([a-z]*)`)
+	proxyModeConfig = regexp.MustCompile(`(?m)^\s*mode:\s*"?([a-z]*)"?\s*$`)
+)
+
+// Fingerprint is a snapshot of the cluster which a run is testing.
+type Fingerprint struct {
+	Time           time.Time      `json:"time"`
+	ServerVersion  string         `json:"serverVersion"`
+	Platform       string         `json:"platform,omitempty"`
+	Nodes          []Node         `json:"nodes"`
+	CNI            string         `json:"cni"`
+	StorageClasses []StorageClass `json:"storageClasses"`
+	KubeProxyMode  string         `json:"kubeProxyMode"`
+}
+
+// Node is the fingerprint of a node of the cluster.
+type Node struct {
+	Name                    string            `json:"name"`
+	InstanceType            string            `json:"instanceType,omitempty"`
+	Region                  string            `json:"region,omitempty"`
+	Zone                    string            `json:"zone,omitempty"`
+	KernelVersion           string            `json:"kernelVersion"`
+	OSImage                 string            `json:"osImage"`
+	ContainerRuntimeVersion string            `json:"containerRuntimeVersion"`
+	KubeletVersion          string            `json:"kubeletVersion"`
+	Allocatable             map[string]string `json:"allocatable"`
+	Unschedulable           bool              `json:"unschedulable,omitempty"`
+}
+
+// StorageClass is the fingerprint of a storage class of the cluster.
+type StorageClass struct {
+	Name        string `json:"name"`
+	Provisioner string `json:"provisioner"`
+	Default     bool   `json:"default,omitempty"`
+}
+
+// GetFingerprint takes a snapshot of the cluster. It fails only if the server version
+// or the nodes can't be got, the other parts are left empty or unknown if they can't
+// be detected.
+func GetFingerprint(kubeClient kubernetes.Interface) (*Fingerprint, error) {
+	version, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get the server version")
+	}
+	fp := &Fingerprint{
+		Time:          time.Now(),
+		ServerVersion: version.GitVersion,
+		Platform:      version.Platform,
+	}
+
+	nodes, err := kubeClient.CoreV1().Nodes().List(apismetav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list nodes")
+	}
+	for _, node := range nodes.Items {
+		fp.Nodes = append(fp.Nodes, nodeFingerprint(node))
+	}
+
+	fp.CNI = detectCNI(kubeClient)
+	fp.KubeProxyMode = detectProxyMode(kubeClient)
+
+	classes, err := kubeClient.StorageV1().StorageClasses().List(apismetav1.ListOptions{})
+	if err != nil {
+		glog.Warningf("Failed to list storage classes: %v", err)
+	} else {
+		for _, class := range classes.Items {
+			fp.StorageClasses = append(fp.StorageClasses, StorageClass{
+				Name:        class.Name,
+				Provisioner: class.Provisioner,
+				Default:     class.Annotations[defaultClassAnnotation] == "true",
+			})
+		}
+	}
+
+	return fp, nil
+}
+
+// Labels returns the key fields of the fingerprint, which are attached as labels to the
+// metrics pushed to Pushgateway. The fields which differ between nodes are "mixed".
+func (fp *Fingerprint) Labels() map[string]string {
+	var instanceTypes, runtimes, kernels []string
+	for _, node := range fp.Nodes {
+		instanceTypes = appendUnique(instanceTypes, node.InstanceType)
+		runtimes = appendUnique(runtimes, node.ContainerRuntimeVersion)
+		kernels = appendUnique(kernels, node.KernelVersion)
+	}
+
+	labels := map[string]string{
+		"kubernetesVersion": fp.ServerVersion,
+		"nodes":             strconv.Itoa(len(fp.Nodes)),
+		"cni":               fp.CNI,
+		"kubeProxyMode":     fp.KubeProxyMode,
+		"instanceType":      single(instanceTypes),
+		"containerRuntime":  single(runtimes),
+		"kernelVersion":     single(kernels),
+	}
+	for name, value := range labels {
+		if value == "" {
+			delete(labels, name)
+			continue
+		}
+		// Pushgateway doesn't allow '/' in the values of grouping labels, e.g. docker://17.3.2.
+		labels[name] = strings.Replace(value, "/", "", -1)
+	}
+	return labels
+}
+
+func nodeFingerprint(node v1.Node) Node {
+	info := node.Status.NodeInfo
+	n := Node{
+		Name:                    node.Name,
+		InstanceType:            findLabel(node.Labels, instanceTypeLabels),
+		Region:                  findLabel(node.Labels, regionLabels),
+		Zone:                    findLabel(node.Labels, zoneLabels),
+		KernelVersion:           info.KernelVersion,
+		OSImage:                 info.OSImage,
+		ContainerRuntimeVersion: info.ContainerRuntimeVersion,
+		KubeletVersion:          info.KubeletVersion,
+		Allocatable:             map[string]string{},
+		Unschedulable:           node.Spec.Unschedulable,
+	}
+	for name, quantity := range node.Status.Allocatable {
+		n.Allocatable[string(name)] = quantity.String()
+	}
+	return n
+}
+
+// detectCNI detects the CNI plugins by the names of the daemonsets in kube-system.
+func detectCNI(kubeClient kubernetes.Interface) string {
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(systemNamespace).List(apismetav1.ListOptions{})
+	if err != nil {
+		glog.Warningf("Failed to list the daemonsets of %s: %v", systemNamespace, err)
+		return CNIUnknown
+	}
+
+	var found []string
+	for _, cni := range knownCNIs {
+		for _, ds := range daemonSets.Items {
+			if strings.Contains(ds.Name, cni) {
+				found = appendUnique(found, cni)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return CNIUnknown
+	}
+	sort.Strings(found)
+	return strings.Join(found, ",")
+}
+
+// detectProxyMode detects the mode of kube-proxy from its config map created by kubeadm,
+// or from the flags of its daemonset. The default mode of kube-proxy is iptables.
+func detectProxyMode(kubeClient kubernetes.Interface) string {
+	cm, err := kubeClient.CoreV1().ConfigMaps(systemNamespace).Get(kubeProxyName, apismetav1.GetOptions{})
+	if err == nil {
+		for _, data := range cm.Data {
+			if match := proxyModeConfig.FindStringSubmatch(data); match != nil {
+				return proxyMode(match[1])
+			}
+		}
+	}
+
+	ds, err := kubeClient.AppsV1().DaemonSets(systemNamespace).Get(kubeProxyName, apismetav1.GetOptions{})
+	if err != nil {
+		glog.V(4).Infof("Failed to get the kube-proxy daemonset: %v", err)
+		return ProxyModeUnknown
+	}
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		flags := strings.Join(append(append([]string{}, container.Command...), container.Args...), " ")
+		if match := proxyModeFlag.FindStringSubmatch(flags); match != nil {
+			return proxyMode(match[1])
+		}
+	}
+	return proxyMode("")
+}
+
+func proxyMode(mode string) string {
+	if mode == "" {
+		return "iptables"
+	}
+	return mode
+}
+
+func findLabel(labels map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := labels[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// single returns the only value of the list, or "mixed" if there are more.
+func single(values []string) string {
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0]
+	}
+	return "mixed"
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
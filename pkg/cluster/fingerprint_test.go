@@ -0,0 +1,358 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	typedappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typedstoragev1 "k8s.io/client-go/kubernetes/typed/storage/v1"
+)
+
+// fakeClient is a kubernetes.Interface serving the objects of the tests which the fingerprint
+// is taken from, the objects of kube-system are served for any namespace. Calling the other
+// methods of the clients panics.
+type fakeClient struct {
+	kubernetes.Interface
+	version        version.Info
+	nodes          []v1.Node
+	configMaps     []v1.ConfigMap
+	daemonSets     []appsv1.DaemonSet
+	storageClasses []storagev1.StorageClass
+	// err is the error of listing the daemonsets and the storage classes.
+	err error
+}
+
+func (c *fakeClient) Discovery() discovery.DiscoveryInterface {
+	return &fakeDiscovery{client: c}
+}
+
+func (c *fakeClient) CoreV1() corev1.CoreV1Interface {
+	return &fakeCore{client: c}
+}
+
+func (c *fakeClient) AppsV1() typedappsv1.AppsV1Interface {
+	return &fakeApps{client: c}
+}
+
+func (c *fakeClient) StorageV1() typedstoragev1.StorageV1Interface {
+	return &fakeStorage{client: c}
+}
+
+type fakeDiscovery struct {
+	discovery.DiscoveryInterface
+	client *fakeClient
+}
+
+func (d *fakeDiscovery) ServerVersion() (*version.Info, error) {
+	info := d.client.version
+	return &info, nil
+}
+
+type fakeCore struct {
+	corev1.CoreV1Interface
+	client *fakeClient
+}
+
+func (c *fakeCore) Nodes() corev1.NodeInterface {
+	return &fakeNodes{client: c.client}
+}
+
+func (c *fakeCore) ConfigMaps(namespace string) corev1.ConfigMapInterface {
+	return &fakeConfigMaps{client: c.client}
+}
+
+type fakeNodes struct {
+	corev1.NodeInterface
+	client *fakeClient
+}
+
+func (n *fakeNodes) List(opts apismetav1.ListOptions) (*v1.NodeList, error) {
+	return &v1.NodeList{Items: n.client.nodes}, nil
+}
+
+type fakeConfigMaps struct {
+	corev1.ConfigMapInterface
+	client *fakeClient
+}
+
+func (c *fakeConfigMaps) Get(name string, options apismetav1.GetOptions) (*v1.ConfigMap, error) {
+	for i := range c.client.configMaps {
+		if c.client.configMaps[i].Name == name {
+			cm := c.client.configMaps[i]
+			return &cm, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("configmaps"), name)
+}
+
+type fakeApps struct {
+	typedappsv1.AppsV1Interface
+	client *fakeClient
+}
+
+func (a *fakeApps) DaemonSets(namespace string) typedappsv1.DaemonSetInterface {
+	return &fakeDaemonSets{client: a.client}
+}
+
+type fakeDaemonSets struct {
+	typedappsv1.DaemonSetInterface
+	client *fakeClient
+}
+
+func (d *fakeDaemonSets) Get(name string, options apismetav1.GetOptions) (*appsv1.DaemonSet, error) {
+	for i := range d.client.daemonSets {
+		if d.client.daemonSets[i].Name == name {
+			ds := d.client.daemonSets[i]
+			return &ds, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(appsv1.Resource("daemonsets"), name)
+}
+
+func (d *fakeDaemonSets) List(opts apismetav1.ListOptions) (*appsv1.DaemonSetList, error) {
+	if d.client.err != nil {
+		return nil, d.client.err
+	}
+	return &appsv1.DaemonSetList{Items: d.client.daemonSets}, nil
+}
+
+type fakeStorage struct {
+	typedstoragev1.StorageV1Interface
+	client *fakeClient
+}
+
+func (s *fakeStorage) StorageClasses() typedstoragev1.StorageClassInterface {
+	return &fakeStorageClasses{client: s.client}
+}
+
+type fakeStorageClasses struct {
+	typedstoragev1.StorageClassInterface
+	client *fakeClient
+}
+
+func (s *fakeStorageClasses) List(opts apismetav1.ListOptions) (*storagev1.StorageClassList, error) {
+	if s.client.err != nil {
+		return nil, s.client.err
+	}
+	return &storagev1.StorageClassList{Items: s.client.storageClasses}, nil
+}
+
+func daemonSet(name string, command, args []string) appsv1.DaemonSet {
+	ds := appsv1.DaemonSet{ObjectMeta: apismetav1.ObjectMeta{Name: name}}
+	ds.Spec.Template.Spec.Containers = []v1.Container{{Name: name, Command: command, Args: args}}
+	return ds
+}
+
+func configMap(name, key, data string) v1.ConfigMap {
+	return v1.ConfigMap{
+		ObjectMeta: apismetav1.ObjectMeta{Name: name},
+		Data:       map[string]string{key: data},
+	}
+}
+
+func TestDetectCNI(t *testing.T) {
+	tests := []struct {
+		name       string
+		daemonSets []string
+		err        error
+		cni        string
+	}{
+		{"calico", []string{"kube-proxy", "calico-node"}, nil, "calico"},
+		{"flannel", []string{"kube-flannel-ds-amd64", "kube-proxy"}, nil, "flannel"},
+		{"several", []string{"weave-net", "calico-node", "calico-typha"}, nil, "calico,weave"},
+		// canal runs calico and flannel in the daemonset named canal.
+		{"canal", []string{"canal"}, nil, "canal"},
+		{"none", []string{"kube-proxy"}, nil, CNIUnknown},
+		{"list error", []string{"calico-node"}, errors.New("forbidden"), CNIUnknown},
+	}
+	for _, test := range tests {
+		client := &fakeClient{err: test.err}
+		for _, name := range test.daemonSets {
+			client.daemonSets = append(client.daemonSets, daemonSet(name, nil, nil))
+		}
+		if cni := detectCNI(client); cni != test.cni {
+			t.Errorf("%s: CNI %q, want %q", test.name, cni, test.cni)
+		}
+	}
+}
+
+func TestDetectProxyMode(t *testing.T) {
+	kubeadmConfig := func(mode string) []v1.ConfigMap {
+		return []v1.ConfigMap{configMap(kubeProxyName, "config.conf", "apiVersion: kubeproxy.config.k8s.io/v1alpha1\nkind: KubeProxyConfiguration\nmode: "+mode+"\n")}
+	}
+	kubeProxy := func(command, args []string) []appsv1.DaemonSet {
+		return []appsv1.DaemonSet{daemonSet(kubeProxyName, command, args)}
+	}
+	tests := []struct {
+		name       string
+		configMaps []v1.ConfigMap
+		daemonSets []appsv1.DaemonSet
+		mode       string
+	}{
+		{"kubeadm config", kubeadmConfig("ipvs"), kubeProxy([]string{"/usr/local/bin/kube-proxy", "--config=/var/lib/kube-proxy/config.conf"}, nil), "ipvs"},
+		{"quoted config", kubeadmConfig(`"ipvs"`), nil, "ipvs"},
+		{"default config", kubeadmConfig(`""`), nil, "iptables"},
+		// The config map takes precedence over the flags.
+		{"config and flag", kubeadmConfig("iptables"), kubeProxy([]string{"kube-proxy", "--proxy-mode=ipvs"}, nil), "iptables"},
+		{"config without mode", []v1.ConfigMap{configMap(kubeProxyName, "kubeconfig.conf", "apiVersion: v1\nkind: Config\n")}, kubeProxy(nil, []string{"--proxy-mode=ipvs"}), "ipvs"},
+		{"flag", nil, kubeProxy([]string{"kube-proxy", "--proxy-mode=ipvs"}, nil), "ipvs"},
+		{"separate flag", nil, kubeProxy([]string{"kube-proxy"}, []string{"--proxy-mode", "userspace"}), "userspace"},
+		{"no flag", nil, kubeProxy([]string{"kube-proxy", "--v=2"}, nil), "iptables"},
+		{"no kube-proxy", nil, nil, ProxyModeUnknown},
+	}
+	for _, test := range tests {
+		client := &fakeClient{configMaps: test.configMaps, daemonSets: test.daemonSets}
+		if mode := detectProxyMode(client); mode != test.mode {
+			t.Errorf("%s: kube-proxy mode %q, want %q", test.name, mode, test.mode)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	node := func(instanceType, runtime, kernel string) Node {
+		return Node{InstanceType: instanceType, ContainerRuntimeVersion: runtime, KernelVersion: kernel}
+	}
+	tests := []struct {
+		name   string
+		fp     Fingerprint
+		labels map[string]string
+	}{
+		{
+			name: "uniform nodes",
+			fp: Fingerprint{
+				ServerVersion: "v1.10.2",
+				CNI:           "calico",
+				KubeProxyMode: "ipvs",
+				Nodes:         []Node{node("ecs.g5.large", "docker://17.3.2", "4.4.0"), node("ecs.g5.large", "docker://17.3.2", "4.4.0")},
+			},
+			labels: map[string]string{
+				"kubernetesVersion": "v1.10.2",
+				"nodes":             "2",
+				"cni":               "calico",
+				"kubeProxyMode":     "ipvs",
+				"instanceType":      "ecs.g5.large",
+				// '/' is not allowed in the values of grouping labels.
+				"containerRuntime": "docker:17.3.2",
+				"kernelVersion":    "4.4.0",
+			},
+		},
+		{
+			// The fields which differ between nodes are mixed, and the empty ones are dropped.
+			name: "mixed nodes",
+			fp: Fingerprint{
+				ServerVersion: "v1.10.2",
+				Nodes:         []Node{node("", "docker://17.3.2", "4.4.0"), node("", "containerd://1.1.0", "4.4.0")},
+			},
+			labels: map[string]string{
+				"kubernetesVersion": "v1.10.2",
+				"nodes":             "2",
+				"containerRuntime":  "mixed",
+				"kernelVersion":     "4.4.0",
+			},
+		},
+		{
+			name:   "no nodes",
+			fp:     Fingerprint{},
+			labels: map[string]string{"nodes": "0"},
+		},
+	}
+	for _, test := range tests {
+		if labels := test.fp.Labels(); !reflect.DeepEqual(labels, test.labels) {
+			t.Errorf("%s: labels %v, want %v", test.name, labels, test.labels)
+		}
+	}
+}
+
+func TestGetFingerprint(t *testing.T) {
+	client := &fakeClient{
+		version: version.Info{GitVersion: "v1.10.2", Platform: "linux/amd64"},
+		nodes: []v1.Node{
+			{
+				ObjectMeta: apismetav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+					"beta.kubernetes.io/instance-type":         "ecs.g5.large",
+					"failure-domain.beta.kubernetes.io/region": "cn-hangzhou",
+					"failure-domain.beta.kubernetes.io/zone":   "cn-hangzhou-b",
+					"topology.kubernetes.io/zone":              "cn-hangzhou-g",
+				}},
+				Spec: v1.NodeSpec{Unschedulable: true},
+				Status: v1.NodeStatus{
+					NodeInfo:    v1.NodeSystemInfo{KernelVersion: "4.4.0", OSImage: "Ubuntu 16.04", ContainerRuntimeVersion: "docker://17.3.2", KubeletVersion: "v1.10.2"},
+					Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), v1.ResourceMemory: resource.MustParse("8Gi")},
+				},
+			},
+		},
+		daemonSets: []appsv1.DaemonSet{daemonSet("calico-node", nil, nil), daemonSet(kubeProxyName, []string{"kube-proxy", "--proxy-mode=ipvs"}, nil)},
+		storageClasses: []storagev1.StorageClass{
+			{ObjectMeta: apismetav1.ObjectMeta{Name: "standard", Annotations: map[string]string{defaultClassAnnotation: "true"}}, Provisioner: "kubernetes.io/gce-pd"},
+			{ObjectMeta: apismetav1.ObjectMeta{Name: "ssd"}, Provisioner: "kubernetes.io/gce-pd"},
+		},
+	}
+
+	fp, err := GetFingerprint(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Fingerprint{
+		Time:          fp.Time,
+		ServerVersion: "v1.10.2",
+		Platform:      "linux/amd64",
+		Nodes: []Node{{
+			Name:                    "node-1",
+			InstanceType:            "ecs.g5.large",
+			Region:                  "cn-hangzhou",
+			Zone:                    "cn-hangzhou-g",
+			KernelVersion:           "4.4.0",
+			OSImage:                 "Ubuntu 16.04",
+			ContainerRuntimeVersion: "docker://17.3.2",
+			KubeletVersion:          "v1.10.2",
+			Allocatable:             map[string]string{"cpu": "4", "memory": "8Gi"},
+			Unschedulable:           true,
+		}},
+		CNI:           "calico",
+		KubeProxyMode: "ipvs",
+		StorageClasses: []StorageClass{
+			{Name: "standard", Provisioner: "kubernetes.io/gce-pd", Default: true},
+			{Name: "ssd", Provisioner: "kubernetes.io/gce-pd"},
+		},
+	}
+	if !reflect.DeepEqual(fp, want) {
+		t.Errorf("fingerprint %+v, want %+v", fp, want)
+	}
+
+	// The fingerprint is taken even if the daemonsets and storage classes can't be listed.
+	client.err = errors.New("forbidden")
+	fp, err = GetFingerprint(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp.CNI != CNIUnknown || fp.StorageClasses != nil || len(fp.Nodes) != 1 {
+		t.Errorf("fingerprint %+v, want an unknown CNI and no storage classes", fp)
+	}
+}
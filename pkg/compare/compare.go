@@ -0,0 +1,228 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"text/tabwriter"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/stats"
+	"github.com/pkg/errors"
+)
+
+// Run is the statistics of all testing cases of a capstan run.
+type Run struct {
+	Label     string
+	Summaries []results.Summary
+}
+
+// Options is the options of comparing runs.
+type Options struct {
+	// Threshold is the max percentage a metric may get worse than the baseline.
+	Threshold float64
+	// Alpha is the significance level of the Mann-Whitney U test.
+	Alpha float64
+}
+
+// Row is the comparison of a metric of a testing case between the baseline and another run.
+type Row struct {
+	Workload     string
+	TestingTool  string
+	TestingCase  string
+	Metric       string
+	Unit         string
+	Label        string
+	BaselineMean float64
+	Mean         float64
+	// Delta is the percentage change of the mean against the baseline.
+	Delta float64
+	// PValue is the p-value of the Mann-Whitney U test, it is NaN if there are too few repeats.
+	PValue float64
+	// ThresholdOnly is true if there are too few repeats for any change to be significant
+	// at the significance level, the threshold alone decides a regression then.
+	ThresholdOnly bool
+	// Missing is true if the run has no result of the metric of the baseline, e.g. the
+	// testing case has failed or was skipped in the run.
+	Missing    bool
+	Regression bool
+}
+
+// LoadRun loads the statistics of a run. The source can be a results directory of a run,
+// a summary file, a results file, or the UUID of a run under resultsDir.
+func LoadRun(source, resultsDir string) (Run, error) {
+	fi, err := os.Stat(source)
+	if err != nil && os.IsNotExist(err) && resultsDir != "" {
+		source = path.Join(resultsDir, source)
+		fi, err = os.Stat(source)
+	}
+	if err != nil {
+		return Run{}, errors.WithStack(err)
+	}
+
+	dir, file := source, ""
+	if fi.IsDir() {
+		file = path.Join(dir, results.SummaryFile)
+		if _, err := os.Stat(file); err != nil {
+			file = path.Join(dir, results.ResultsFile)
+		}
+	} else {
+		dir, file = path.Dir(source), source
+	}
+
+	run := Run{Label: path.Base(path.Clean(dir))}
+	if path.Base(file) == results.ResultsFile {
+		r, err := results.Load(file)
+		if err != nil {
+			return Run{}, err
+		}
+		if r.UUID != "" {
+			run.Label = r.UUID
+		}
+		run.Summaries = results.Summarize(r)
+		return run, nil
+	}
+
+	run.Summaries, err = results.LoadSummaries(file)
+	if err != nil {
+		return Run{}, err
+	}
+	return run, nil
+}
+
+// Compare aligns the runs with the baseline by workload, testing tool, testing case and metric,
+// and compares the statistics of each run with the baseline.
+func Compare(baseline Run, runs []Run, opts Options) []Row {
+	var rows []Row
+	for _, base := range baseline.Summaries {
+		for _, run := range runs {
+			row := Row{
+				Workload:     base.Workload,
+				TestingTool:  base.TestingTool,
+				TestingCase:  base.TestingCase,
+				Metric:       base.Metric,
+				Unit:         base.Unit,
+				Label:        run.Label,
+				BaselineMean: base.Mean,
+				PValue:       math.NaN(),
+			}
+
+			s, found := findSummary(run.Summaries, base)
+			if !found {
+				row.Missing = true
+				rows = append(rows, row)
+				continue
+			}
+
+			row.Mean = s.Mean
+			if base.Mean != 0 {
+				row.Delta = (s.Mean - base.Mean) / math.Abs(base.Mean) * 100
+			}
+			_, row.PValue = stats.MannWhitneyU(base.Values, s.Values)
+			// With too few repeats even runs which do not overlap at all are not significant,
+			// e.g. 3 against 3 repeats can not get below p=0.1, so only the threshold is checked.
+			minPValue := stats.MinPValue(len(base.Values), len(s.Values))
+			row.ThresholdOnly = math.IsNaN(minPValue) || minPValue >= opts.Alpha
+
+			worse := -row.Delta
+			if base.LowerIsBetter {
+				worse = row.Delta
+			}
+			// A regression must exceed the threshold, and must be significant when
+			// there are enough repeats to run the significance test.
+			significant := row.ThresholdOnly || row.PValue < opts.Alpha
+			row.Regression = worse > opts.Threshold && significant
+
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// HasRegression returns true if any row is a regression.
+func HasRegression(rows []Row) bool {
+	for _, row := range rows {
+		if row.Regression {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMissing returns true if any row is missing in its run.
+func HasMissing(rows []Row) bool {
+	for _, row := range rows {
+		if row.Missing {
+			return true
+		}
+	}
+	return false
+}
+
+// CountThresholdOnly returns the number of rows which are only checked against the threshold.
+func CountThresholdOnly(rows []Row) int {
+	count := 0
+	for _, row := range rows {
+		if !row.Missing && row.ThresholdOnly {
+			count++
+		}
+	}
+	return count
+}
+
+// PrintTable prints the rows as a table.
+func PrintTable(w io.Writer, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WORKLOAD\tTOOL\tCASE\tMETRIC\tRUN\tBASELINE\tMEAN\tDELTA\tP-VALUE\tRESULT")
+	for _, row := range rows {
+		mean, delta, pvalue, result := "-", "-", "-", "ok"
+		if row.Missing {
+			result = "missing"
+		} else {
+			mean = fmt.Sprintf("%.2f", row.Mean)
+			delta = fmt.Sprintf("%+.2f%%", row.Delta)
+			if !math.IsNaN(row.PValue) {
+				pvalue = fmt.Sprintf("%.4f", row.PValue)
+			}
+			if row.Regression {
+				result = "REGRESSION"
+			}
+			if row.ThresholdOnly {
+				result += " (threshold only)"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s (%s)\t%s\t%.2f\t%s\t%s\t%s\t%s\n",
+			row.Workload, row.TestingTool, row.TestingCase, row.Metric, row.Unit, row.Label,
+			row.BaselineMean, mean, delta, pvalue, result)
+	}
+	return tw.Flush()
+}
+
+// findSummary finds the summary of the same metric of the same testing case.
+func findSummary(summaries []results.Summary, s results.Summary) (results.Summary, bool) {
+	for _, other := range summaries {
+		if other.Workload == s.Workload && other.TestingTool == s.TestingTool &&
+			other.TestingCase == s.TestingCase && other.Metric == s.Metric {
+			return other, true
+		}
+	}
+	return results.Summary{}, false
+}
@@ -0,0 +1,96 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package compare
+
+import (
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/stats"
+)
+
+func summary(testingCase string, values ...float64) results.Summary {
+	return results.Summary{
+		Workload:    "nginx",
+		TestingTool: "wrk",
+		TestingCase: testingCase,
+		Metric:      "qps",
+		Values:      values,
+		Summary:     stats.Summarize(values),
+	}
+}
+
+func TestCompare(t *testing.T) {
+	opts := Options{Threshold: 5, Alpha: 0.05}
+	tests := []struct {
+		name          string
+		base, run     results.Summary
+		regression    bool
+		thresholdOnly bool
+	}{
+		{"5 repeats significant", summary("a", 100, 101, 99, 102, 98), summary("a", 80, 81, 79, 82, 78), true, false},
+		{"5 repeats within threshold", summary("a", 100, 101, 99, 102, 98), summary("a", 98, 99, 97, 100, 96), false, false},
+		{"5 repeats overlapping", summary("a", 100, 60, 140, 70, 130), summary("a", 90, 50, 130, 60, 120), false, false},
+		// 3 against 3 repeats can not get below p=0.1, so only the threshold decides.
+		{"3 repeats", summary("a", 100, 101, 99), summary("a", 80, 81, 79), true, true},
+		{"3 repeats within threshold", summary("a", 100, 101, 99), summary("a", 98, 99, 97), false, true},
+		{"1 repeat", summary("a", 100), summary("a", 80), true, true},
+	}
+	for _, test := range tests {
+		rows := Compare(Run{Summaries: []results.Summary{test.base}}, []Run{{Summaries: []results.Summary{test.run}}}, opts)
+		if len(rows) != 1 {
+			t.Fatalf("%s: got %d rows, want 1", test.name, len(rows))
+		}
+		if rows[0].Regression != test.regression || rows[0].ThresholdOnly != test.thresholdOnly {
+			t.Errorf("%s: regression %v threshold only %v, want %v %v (p-value %v)", test.name,
+				rows[0].Regression, rows[0].ThresholdOnly, test.regression, test.thresholdOnly, rows[0].PValue)
+		}
+	}
+}
+
+func TestCompareMissing(t *testing.T) {
+	baseline := Run{Summaries: []results.Summary{summary("a", 100, 101, 99), summary("b", 100, 101, 99)}}
+	run := Run{Summaries: []results.Summary{summary("a", 100, 101, 99)}}
+	rows := Compare(baseline, []Run{run}, Options{Threshold: 5, Alpha: 0.05})
+	if !HasMissing(rows) {
+		t.Errorf("testing case b missing in the run is not reported")
+	}
+	if HasRegression(rows) {
+		t.Errorf("unexpected regression: %+v", rows)
+	}
+}
+
+func TestCompareEveryMetric(t *testing.T) {
+	latency := func(values ...float64) results.Summary {
+		s := summary("a", values...)
+		s.Metric, s.Unit, s.LowerIsBetter = "latency_p99", "ms", true
+		return s
+	}
+	baseline := Run{Summaries: []results.Summary{summary("a", 100, 101, 99, 102, 98), latency(3, 3.1, 2.9, 3, 3.2)}}
+	run := Run{Label: "run", Summaries: []results.Summary{latency(4, 4.1, 3.9, 4, 4.2), summary("a", 100, 101, 99, 102, 98)}}
+
+	rows := Compare(baseline, []Run{run}, Options{Threshold: 5, Alpha: 0.05})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want a row of every metric", len(rows))
+	}
+	if rows[0].Metric != "qps" || rows[0].Regression {
+		t.Errorf("row %+v, want qps without regression", rows[0])
+	}
+	if rows[1].Metric != "latency_p99" || !rows[1].Regression {
+		t.Errorf("row %+v, want a regression of the latency", rows[1])
+	}
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package dashboard
 
 import (
-	"fmt"
+	"encoding/json"
 	"net/http"
 
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/golang/glog"
 	"github.com/gorilla/mux"
 )
 
@@ -38,12 +40,10 @@ func NewHandler() http.Handler {
 	return handler
 }
 
-// overviewHandler hands the overview request.
+// overviewHandler hands the overview request, it returns the state of the current run as JSON.
 func overviewHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("Not Implemented")
-}
-
-// downloadHandler hands the download request.
-func downloadHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Printf("Not Implemented")
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results.DefaultStore.Snapshot()); err != nil {
+		glog.Warningf("Failed to encode the overview of run: %v", err)
+	}
 }
@@ -0,0 +1,190 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// validUUID matches the uuid of a run, it must not contain path separators.
+var validUUID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Manifest is the internal representation of the manifest of a downloaded run.
+type Manifest struct {
+	UUID        string          `json:"uuid"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Config      json.RawMessage `json:"config,omitempty"`
+	Results     *results.Run    `json:"results,omitempty"`
+	// Summaries is the statistics of every metric of every testing case of the run.
+	Summaries []results.Summary `json:"summaries,omitempty"`
+}
+
+// downloadHandler hands the download request, it streams the results directory
+// of the run given by the uuid parameter as a tar.gz archive.
+func downloadHandler(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	runDir, err := findRunDir(uuid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if fi, err := os.Stat(runDir); err != nil || !fi.IsDir() {
+		http.Error(w, fmt.Sprintf("results of run %q not found", uuid), http.StatusNotFound)
+		return
+	}
+
+	manifest, err := buildManifest(uuid, runDir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"capstan-%s.tar.gz\"", uuid))
+	if err := writeArchive(w, runDir, uuid, manifest); err != nil {
+		// The response has been started, so only log the error here.
+		glog.Warningf("Failed to stream the results of run %v: %v", uuid, err)
+	}
+}
+
+// findRunDir returns the results directory of the run, it rejects uuids which may escape ResultsDir.
+func findRunDir(uuid string) (string, error) {
+	if uuid == "" {
+		return "", errors.New("missing uuid parameter")
+	}
+	if !validUUID.MatchString(uuid) || strings.Contains(uuid, "..") {
+		return "", errors.Errorf("invalid uuid %q", uuid)
+	}
+
+	root, err := filepath.Abs(types.ResultsDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	runDir := filepath.Join(root, uuid)
+	if filepath.Dir(runDir) != root {
+		return "", errors.Errorf("invalid uuid %q", uuid)
+	}
+	return runDir, nil
+}
+
+// buildManifest builds the manifest which lists the config used and every parsed metric of the run.
+func buildManifest(uuid, runDir string) ([]byte, error) {
+	manifest := Manifest{
+		UUID:        uuid,
+		GeneratedAt: time.Now(),
+	}
+
+	if data, err := ioutil.ReadFile(filepath.Join(runDir, results.ConfigFile)); err == nil {
+		manifest.Config = json.RawMessage(data)
+	}
+
+	if uuid == types.UUID {
+		run := results.DefaultStore.Snapshot()
+		manifest.Results = &run
+	} else if run, err := results.Load(filepath.Join(runDir, results.ResultsFile)); err == nil {
+		manifest.Results = &run
+	}
+	if manifest.Results != nil {
+		manifest.Summaries = results.Summarize(*manifest.Results)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// writeArchive writes the manifest and all regular files under runDir to w as a tar.gz archive,
+// all entries are placed under the prefix directory.
+func writeArchive(w io.Writer, runDir, prefix string, manifest []byte) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    filepath.ToSlash(filepath.Join(prefix, results.ManifestFile)),
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return errors.WithStack(err)
+	}
+
+	err := filepath.Walk(runDir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(runDir, file)
+		if err != nil {
+			return err
+		}
+		// Skip symlinks and other special files, and the manifest generated above.
+		if rel == "." || rel == results.ManifestFile || !(fi.Mode().IsRegular() || fi.IsDir()) {
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(gw.Close())
+}
@@ -0,0 +1,213 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboard
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
+)
+
+func TestFindRunDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capstan-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(resultsDir string) { types.ResultsDir = resultsDir }(types.ResultsDir)
+	types.ResultsDir = dir
+
+	tests := []struct {
+		uuid  string
+		valid bool
+	}{
+		{"0b7c3a1e-2f4d-4c8a-9e6b-1d2f3a4b5c6d", true},
+		{"run.1_a", true},
+		{"", false},
+		{"..", false},
+		{"../etc", false},
+		{"a/../../etc", false},
+		{"a..b", false},
+		{"/etc/passwd", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{".hidden", false},
+		// The query is decoded once, so an encoded separator stays encoded.
+		{"a%2Fb", false},
+		{"a%5Cb", false},
+	}
+	for _, test := range tests {
+		runDir, err := findRunDir(test.uuid)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("findRunDir(%q) = %q, want an error", test.uuid, runDir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("findRunDir(%q) failed: %v", test.uuid, err)
+		} else if runDir != filepath.Join(dir, test.uuid) {
+			t.Errorf("findRunDir(%q) = %q, want %q", test.uuid, runDir, filepath.Join(dir, test.uuid))
+		}
+	}
+}
+
+func TestDownloadHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capstan-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(resultsDir, uuid string) { types.ResultsDir, types.UUID = resultsDir, uuid }(types.ResultsDir, types.UUID)
+	types.ResultsDir = dir
+	// The run is not the current one, so its results are loaded from its directory.
+	types.UUID = "current"
+
+	const uuid = "finished"
+	runDir := filepath.Join(dir, uuid)
+	run := results.Run{UUID: uuid, State: results.StateSucceeded, Workloads: []results.Workload{{
+		Name:        "nginx",
+		TestingTool: "wrk",
+		TestingCases: []results.Case{{
+			Name: "benchmarkSameNode",
+			Repeats: []results.Repeat{
+				{Repeat: 1, State: results.StateSucceeded, Metrics: []results.Metric{{Name: "qps", Value: 100}}},
+				{Repeat: 2, State: results.StateSucceeded, Metrics: []results.Metric{{Name: "qps", Value: 200}}},
+			},
+		}},
+	}}}
+	files := map[string]string{
+		results.ConfigFile:                  `{"UUID":"finished"}`,
+		"nginx/wrk/benchmarkSameNode/1.log": "Requests/sec: 100",
+		// A manifest left in the directory is replaced by the generated one.
+		results.ManifestFile: "stale",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(runDir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(runDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := results.WriteJSON(filepath.Join(runDir, results.ResultsFile), run); err != nil {
+		t.Fatal(err)
+	}
+	// Symlinks are not followed out of the results directory.
+	if err := os.Symlink("/etc/passwd", filepath.Join(runDir, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"uuid=..%2Fetc", http.StatusBadRequest},
+		{"uuid=%2Fetc%2Fpasswd", http.StatusBadRequest},
+		{"uuid=finished%2F..%2F..", http.StatusBadRequest},
+		{"uuid=unknown", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		downloadHandler(w, httptest.NewRequest("GET", "/download?"+test.query, nil))
+		if w.Code != test.status {
+			t.Errorf("GET /download?%s: status %d, want %d", test.query, w.Code, test.status)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	downloadHandler(w, httptest.NewRequest("GET", "/download?uuid="+uuid, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /download?uuid=%s: status %d, want %d: %s", uuid, w.Code, http.StatusOK, w.Body.String())
+	}
+	archive := readArchive(t, w.Body)
+
+	var names []string
+	for name := range archive {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wantNames := []string{
+		"finished/config.json",
+		"finished/manifest.json",
+		"finished/nginx/",
+		"finished/nginx/wrk/",
+		"finished/nginx/wrk/benchmarkSameNode/",
+		"finished/nginx/wrk/benchmarkSameNode/1.log",
+		"finished/results.json",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("archive entries %q, want %q", names, wantNames)
+	}
+	if log := archive["finished/nginx/wrk/benchmarkSameNode/1.log"]; log != files["nginx/wrk/benchmarkSameNode/1.log"] {
+		t.Errorf("1.log = %q, want %q", log, files["nginx/wrk/benchmarkSameNode/1.log"])
+	}
+
+	manifest := Manifest{}
+	if err := json.Unmarshal([]byte(archive["finished/manifest.json"]), &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if manifest.UUID != uuid {
+		t.Errorf("manifest uuid %q, want %q", manifest.UUID, uuid)
+	}
+	config := map[string]interface{}{}
+	if err := json.Unmarshal(manifest.Config, &config); err != nil || config["UUID"] != uuid {
+		t.Errorf("manifest config %s, want %s", manifest.Config, files[results.ConfigFile])
+	}
+	if manifest.Results == nil || manifest.Results.State != results.StateSucceeded {
+		t.Errorf("manifest results %+v, want the results of the run", manifest.Results)
+	}
+	if len(manifest.Summaries) != 1 || !reflect.DeepEqual(manifest.Summaries[0].Values, []float64{100, 200}) {
+		t.Errorf("manifest summaries %+v, want the qps of both repeats", manifest.Summaries)
+	}
+}
+
+// readArchive returns the contents of the entries of a tar.gz archive by their names.
+func readArchive(t *testing.T, r io.Reader) map[string]string {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	entries := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("invalid tar archive: %v", err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = string(data)
+	}
+}
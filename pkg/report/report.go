@@ -0,0 +1,253 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/cluster"
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/pkg/errors"
+)
+
+// ReportFile is the file name of the HTML report of a run.
+const ReportFile = "report.html"
+
+const (
+	chartWidth    = 480
+	chartHeight   = 200
+	chartPadding  = 30
+	chartBarSpace = 0.2
+)
+
+// reportData is the data used to render the report template.
+type reportData struct {
+	Run         results.Run
+	Config      string
+	GeneratedAt time.Time
+	Nodes       []string
+	Cluster     *cluster.Fingerprint
+	Workloads   []workloadData
+}
+
+type workloadData struct {
+	results.Workload
+	Cases []caseData
+}
+
+type caseData struct {
+	results.Case
+	Summaries []results.Summary
+	Chart     *chart
+}
+
+// chart is an inline SVG bar chart of the metric of every repeat of a testing case.
+type chart struct {
+	Width  int
+	Height int
+	AxisY  int
+	AxisX2 int
+	LabelY int
+	Metric string
+	Unit   string
+	Bars   []bar
+}
+
+type bar struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	ValueY float64
+	Label  string
+	Value  string
+}
+
+// Generate renders the HTML report of the run whose results are stored in runDir,
+// and saves it as runDir/report.html.
+func Generate(runDir string) (string, error) {
+	data, err := Render(runDir)
+	if err != nil {
+		return "", err
+	}
+	out := path.Join(runDir, ReportFile)
+	if err := ioutil.WriteFile(out, data, 0644); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return out, nil
+}
+
+// Render renders the HTML report of the run whose results are stored in runDir.
+// It only reads the files written by capstan, so it can be used offline.
+func Render(runDir string) ([]byte, error) {
+	run, err := results.Load(path.Join(runDir, results.ResultsFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the results of run")
+	}
+
+	data := reportData{
+		Run:         run,
+		GeneratedAt: time.Now(),
+		Nodes:       findNodes(run),
+	}
+
+	if cfg, err := ioutil.ReadFile(path.Join(runDir, results.ConfigFile)); err == nil {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, cfg, "", "    "); err == nil {
+			data.Config = buf.String()
+		} else {
+			data.Config = string(cfg)
+		}
+	}
+
+	if body, err := ioutil.ReadFile(path.Join(runDir, cluster.FingerprintFile)); err == nil {
+		fp := &cluster.Fingerprint{}
+		if err := json.Unmarshal(body, fp); err == nil {
+			data.Cluster = fp
+		}
+	}
+
+	summaries := results.Summarize(run)
+	for _, wl := range run.Workloads {
+		wd := workloadData{Workload: wl}
+		for _, c := range wl.TestingCases {
+			cd := caseData{Case: c}
+			for _, s := range summaries {
+				if s.Workload == wl.Name && s.TestingCase == c.Name {
+					cd.Summaries = append(cd.Summaries, s)
+				}
+			}
+			cd.Chart = buildChart(c)
+			wd.Cases = append(wd.Cases, cd)
+		}
+		data.Workloads = append(data.Workloads, wd)
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"formatTime":  formatTime,
+		"formatFloat": formatFloat,
+		"duration":    duration,
+	}).Parse(reportTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "error when parsing report template")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, errors.Wrap(err, "error when executing report template")
+	}
+	return buf.Bytes(), nil
+}
+
+// buildChart builds the bar chart of the metric of every repeat of a testing case,
+// it returns nil if no repeat has a metric.
+func buildChart(c results.Case) *chart {
+	var max float64
+	var metric *results.Metric
+	for _, r := range c.Repeats {
+		if r.Metric == nil {
+			continue
+		}
+		if metric == nil {
+			metric = r.Metric
+		}
+		if r.Metric.Value > max {
+			max = r.Metric.Value
+		}
+	}
+	if metric == nil {
+		return nil
+	}
+
+	ch := &chart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		AxisY:  chartHeight - chartPadding,
+		AxisX2: chartWidth - chartPadding,
+		LabelY: chartHeight - chartPadding + 14,
+		Metric: metric.Name,
+		Unit:   metric.Unit,
+	}
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	slot := plotWidth / float64(len(c.Repeats))
+	for i, r := range c.Repeats {
+		b := bar{
+			X:     chartPadding + float64(i)*slot + slot*chartBarSpace/2,
+			Y:     chartPadding + plotHeight,
+			Width: slot * (1 - chartBarSpace),
+			Label: fmt.Sprintf("#%d", r.Repeat),
+			Value: "-",
+		}
+		if r.Metric != nil && max > 0 {
+			b.Height = r.Metric.Value / max * plotHeight
+			b.Y -= b.Height
+			b.Value = formatFloat(r.Metric.Value)
+		}
+		b.ValueY = b.Y - 4
+		ch.Bars = append(ch.Bars, b)
+	}
+	return ch
+}
+
+// findNodes returns all the nodes which have run a workload pod or a testing pod.
+func findNodes(run results.Run) []string {
+	set := map[string]bool{}
+	for _, wl := range run.Workloads {
+		for _, c := range wl.TestingCases {
+			for _, r := range c.Repeats {
+				if r.WorkloadNode != "" {
+					set[r.WorkloadNode] = true
+				}
+				if r.TestingNode != "" {
+					set[r.TestingNode] = true
+				}
+			}
+		}
+	}
+	var nodes []string
+	for node := range set {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func formatFloat(f float64) string {
+	return fmt.Sprintf("%.2f", f)
+}
+
+func duration(start, end *time.Time) string {
+	if start == nil || end == nil {
+		return "-"
+	}
+	return end.Sub(*start).Round(time.Second).String()
+}
@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/cluster"
+	"github.com/ZJU-SEL/capstan/pkg/results"
+)
+
+func testRun() results.Run {
+	start := time.Date(2018, 5, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+	return results.Run{
+		UUID:     "test-run",
+		Provider: "aliyun",
+		State:    results.StatePartiallyFailed,
+		Workloads: []results.Workload{{
+			Name:        "nginx",
+			Image:       "nginx:1.14",
+			TestingTool: "wrk",
+			Frequency:   2,
+			TestingCases: []results.Case{{
+				Name: "benchmarkSameNode",
+				Repeats: []results.Repeat{
+					{
+						Repeat: 1, State: results.StateSucceeded, StartTime: &start, EndTime: &end,
+						WorkloadNode: "node-1", TestingNode: "node-2",
+						Metric:  &results.Metric{Name: "qps", Unit: "requests/sec", Value: 1234.5},
+						Metrics: []results.Metric{{Name: "qps", Unit: "requests/sec", Value: 1234.5}},
+					},
+					{Repeat: 2, State: results.StateFailed, Error: "<script>alert(1)</script>"},
+				},
+			}},
+		}},
+	}
+}
+
+func writeRunDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "capstan-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRender(t *testing.T) {
+	fingerprint := `{"serverVersion": "v1.10.2", "cni": "calico", "kubeProxyMode": "ipvs",
+		"nodes": [{"name": "node-1", "kernelVersion": "4.4.0", "allocatable": {"cpu": "4"}}]}`
+	tests := []struct {
+		name  string
+		files map[string]string
+		// want and notWant are the parts of the report which are present or absent.
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "results only",
+			want:    []string{"capstan report test-run", "node-1, node-2", "1234.50 requests/sec", "<svg"},
+			notWant: []string{"<h2>Config</h2>", "Kubernetes version"},
+		},
+		{
+			name: "config and cluster",
+			files: map[string]string{
+				results.ConfigFile:      `{"Provider":"aliyun"}`,
+				cluster.FingerprintFile: fingerprint,
+			},
+			want: []string{"<h2>Config</h2>", `&#34;Provider&#34;: &#34;aliyun&#34;`, "v1.10.2", "calico", "ipvs", "4.4.0"},
+		},
+		{
+			// Files which are not valid JSON are shown as they are, or skipped.
+			name: "malformed config and cluster",
+			files: map[string]string{
+				results.ConfigFile:      `Provider: aliyun`,
+				cluster.FingerprintFile: `{"serverVersion": `,
+			},
+			want:    []string{"<h2>Config</h2>", "Provider: aliyun"},
+			notWant: []string{"Kubernetes version"},
+		},
+	}
+	for _, test := range tests {
+		dir := writeRunDir(t, test.files)
+		defer os.RemoveAll(dir)
+		if err := results.WriteJSON(path.Join(dir, results.ResultsFile), testRun()); err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := Render(dir)
+		if err != nil {
+			t.Errorf("%s: failed to render: %v", test.name, err)
+			continue
+		}
+		report := string(data)
+		// The errors of the repeats are escaped.
+		test.want = append(test.want, "&lt;script&gt;")
+		test.notWant = append(test.notWant, "<script>")
+		for _, want := range test.want {
+			if !strings.Contains(report, want) {
+				t.Errorf("%s: the report does not contain %q", test.name, want)
+			}
+		}
+		for _, notWant := range test.notWant {
+			if strings.Contains(report, notWant) {
+				t.Errorf("%s: the report contains %q", test.name, notWant)
+			}
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writeRunDir(t, nil)
+	defer os.RemoveAll(dir)
+
+	// A run without results has no report.
+	if _, err := Generate(dir); err == nil {
+		t.Errorf("generating the report of a run without results succeeded")
+	}
+	if _, err := os.Stat(path.Join(dir, ReportFile)); !os.IsNotExist(err) {
+		t.Errorf("the report of a run without results is saved: %v", err)
+	}
+
+	if err := results.WriteJSON(path.Join(dir, results.ResultsFile), testRun()); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Generate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != path.Join(dir, ReportFile) {
+		t.Errorf("the report is saved as %s, want %s", out, path.Join(dir, ReportFile))
+	}
+	if data, err := ioutil.ReadFile(out); err != nil || !strings.Contains(string(data), "test-run") {
+		t.Errorf("the saved report is not the report of the run: %v", err)
+	}
+}
+
+func TestBuildChart(t *testing.T) {
+	c := testRun().Workloads[0].TestingCases[0]
+	ch := buildChart(c)
+	if ch == nil || len(ch.Bars) != 2 {
+		t.Fatalf("chart %+v, want a bar for every repeat", ch)
+	}
+	// The repeat with the max metric fills the plot, the failed one is empty.
+	if ch.Metric != "qps" || ch.Bars[0].Height != chartHeight-2*chartPadding || ch.Bars[1].Height != 0 || ch.Bars[1].Value != "-" {
+		t.Errorf("chart %+v", ch)
+	}
+
+	c.Repeats[0].Metric = nil
+	if ch := buildChart(c); ch != nil {
+		t.Errorf("chart %+v of the repeats without metrics, want none", ch)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package report
+
+const (
+	reportTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>capstan report {{ .Run.UUID }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #24292e; }
+h1 { border-bottom: 2px solid #e1e4e8; padding-bottom: .3em; }
+h2 { border-bottom: 1px solid #e1e4e8; padding-bottom: .2em; margin-top: 2em; }
+table { border-collapse: collapse; margin: .5em 0 1em; }
+th, td { border: 1px solid #d1d5da; padding: 4px 10px; text-align: left; font-size: 14px; }
+th { background: #f6f8fa; }
+pre { background: #f6f8fa; padding: 1em; overflow: auto; font-size: 13px; }
+.state-succeeded { color: #22863a; }
+.state-failed { color: #cb2431; }
+.state-aborted { color: #b08800; }
+.state-partially-failed { color: #b08800; }
+.state-running { color: #0366d6; }
+.state-pending { color: #6a737d; }
+.case { display: flex; flex-wrap: wrap; gap: 2em; align-items: flex-start; }
+svg text { font-size: 11px; fill: #24292e; }
+svg .bar { fill: #0366d6; }
+svg .axis { stroke: #6a737d; stroke-width: 1; }
+</style>
+</head>
+<body>
+<h1>capstan report</h1>
+
+<h2>Cluster</h2>
+<table>
+<tr><th>UUID</th><td>{{ .Run.UUID }}</td></tr>
+<tr><th>Provider</th><td>{{ .Run.Provider }}</td></tr>
+<tr><th>State</th><td class="state-{{ .Run.State }}">{{ .Run.State }}</td></tr>
+<tr><th>Start time</th><td>{{ formatTime .Run.StartTime }}</td></tr>
+<tr><th>End time</th><td>{{ formatTime .Run.EndTime }}</td></tr>
+<tr><th>Nodes</th><td>{{ range $i, $n := .Nodes }}{{ if $i }}, {{ end }}{{ $n }}{{ else }}-{{ end }}</td></tr>
+{{ with .Cluster }}<tr><th>Kubernetes version</th><td>{{ .ServerVersion }}</td></tr>
+<tr><th>CNI</th><td>{{ .CNI }}</td></tr>
+<tr><th>kube-proxy mode</th><td>{{ .KubeProxyMode }}</td></tr>
+<tr><th>Storage classes</th><td>{{ range $i, $c := .StorageClasses }}{{ if $i }}, {{ end }}{{ $c.Name }} ({{ $c.Provisioner }}{{ if $c.Default }}, default{{ end }}){{ else }}-{{ end }}</td></tr>
+{{ end }}</table>
+{{ with .Cluster }}
+<table>
+<tr><th>Node</th><th>Instance type</th><th>Zone</th><th>Kernel</th><th>OS</th><th>Container runtime</th><th>Kubelet</th><th>CPU</th><th>Memory</th></tr>
+{{ range .Nodes }}<tr>
+<td>{{ .Name }}</td>
+<td>{{ .InstanceType }}</td>
+<td>{{ .Zone }}</td>
+<td>{{ .KernelVersion }}</td>
+<td>{{ .OSImage }}</td>
+<td>{{ .ContainerRuntimeVersion }}</td>
+<td>{{ .KubeletVersion }}</td>
+<td>{{ index .Allocatable "cpu" }}</td>
+<td>{{ index .Allocatable "memory" }}</td>
+</tr>
+{{ end }}</table>
+{{ end }}
+
+{{ range .Workloads }}
+<h2>Workload {{ .Name }}</h2>
+<table>
+<tr><th>Image</th><td>{{ .Image }}</td></tr>
+<tr><th>Testing tool</th><td>{{ .TestingTool }}</td></tr>
+<tr><th>Frequency</th><td>{{ .Frequency }}</td></tr>
+</table>
+{{ range .Cases }}
+<h3>{{ .Name }}</h3>
+<div class="case">
+<div>
+<table>
+<tr><th>Repeat</th><th>State</th><th>Start time</th><th>Duration</th><th>Workload node</th><th>Testing node</th><th>Metric</th><th>Pods</th><th>Error</th></tr>
+{{ range .Repeats }}<tr>
+<td>{{ .Repeat }}</td>
+<td class="state-{{ .State }}">{{ .State }}</td>
+<td>{{ formatTime .StartTime }}</td>
+<td>{{ duration .StartTime .EndTime }}</td>
+<td>{{ .WorkloadNode }}</td>
+<td>{{ .TestingNode }}</td>
+<td>{{ with .Metric }}{{ formatFloat .Value }} {{ .Unit }}{{ else }}-{{ end }}</td>
+<td>{{ range .Pods }}{{ .Name }}: scheduled {{ formatFloat .Scheduled }}s, image pull {{ formatFloat .ImagePull }}s, started {{ formatFloat .Started }}s<br>{{ else }}-{{ end }}</td>
+<td>{{ .Error }}</td>
+</tr>
+{{ end }}</table>
+{{ with .Summaries }}
+<table>
+<tr><th>Metric</th><th>Count</th><th>Mean</th><th>Median</th><th>Stddev</th><th>Min</th><th>Max</th><th>P90</th><th>P95</th><th>CV</th></tr>
+{{ range . }}<tr>
+<td>{{ .Metric }} ({{ .Unit }})</td>
+<td>{{ .Count }}</td>
+<td>{{ formatFloat .Mean }}</td>
+<td>{{ formatFloat .Median }}</td>
+<td>{{ formatFloat .Stddev }}</td>
+<td>{{ formatFloat .Min }}</td>
+<td>{{ formatFloat .Max }}</td>
+<td>{{ formatFloat .P90 }}</td>
+<td>{{ formatFloat .P95 }}</td>
+<td>{{ formatFloat .CV }}</td>
+</tr>
+{{ end }}</table>
+{{ end }}
+</div>
+{{ with .Chart }}
+<svg xmlns="http://www.w3.org/2000/svg" width="{{ .Width }}" height="{{ .Height }}" viewBox="0 0 {{ .Width }} {{ .Height }}">
+<text x="30" y="18">{{ .Metric }} ({{ .Unit }})</text>
+<line class="axis" x1="30" y1="{{ .AxisY }}" x2="{{ .AxisX2 }}" y2="{{ .AxisY }}"></line>
+{{ $labelY := .LabelY }}{{ range .Bars }}<rect class="bar" x="{{ printf "%.1f" .X }}" y="{{ printf "%.1f" .Y }}" width="{{ printf "%.1f" .Width }}" height="{{ printf "%.1f" .Height }}"><title>{{ .Label }}: {{ .Value }}</title></rect>
+<text x="{{ printf "%.1f" .X }}" y="{{ printf "%.1f" .ValueY }}">{{ .Value }}</text>
+<text x="{{ printf "%.1f" .X }}" y="{{ $labelY }}">{{ .Label }}</text>
+{{ end }}</svg>
+{{ end }}
+</div>
+{{ end }}
+{{ end }}
+
+{{ with .Run.Failures }}
+<h2>Failures</h2>
+<table>
+<tr><th>Workload</th><th>Testing case</th><th>Repeat</th><th>Attempt</th><th>Time</th><th>Error</th><th>Directory</th></tr>
+{{ range . }}<tr>
+<td>{{ .Workload }}</td>
+<td>{{ .TestingCase }}</td>
+<td>{{ .Repeat }}</td>
+<td>{{ .Attempt }}</td>
+<td>{{ .Time.Format "2006-01-02 15:04:05" }}</td>
+<td>{{ .Error }}</td>
+<td>{{ .Dir }}</td>
+</tr>
+{{ end }}</table>
+{{ end }}
+
+{{ if .Config }}
+<h2>Config</h2>
+<pre>{{ .Config }}</pre>
+{{ end }}
+
+<p><small>Generated by capstan at {{ .GeneratedAt.Format "2006-01-02 15:04:05" }}</small></p>
+</body>
+</html>
+`
+)
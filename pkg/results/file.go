@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConfigFile is the file name of the capstan config used by a run.
+	ConfigFile = "config.json"
+	// ResultsFile is the file name of the run-state of a run.
+	ResultsFile = "results.json"
+	// ManifestFile is the file name of the manifest of a downloaded run.
+	ManifestFile = "manifest.json"
+)
+
+// Save writes the current state of the capstan run to a file as JSON.
+func (s *Store) Save(filename string) error {
+	return WriteJSON(filename, s.Snapshot())
+}
+
+// Load reads the state of a capstan run from a file written by Save.
+func Load(filename string) (Run, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Run{}, errors.WithStack(err)
+	}
+	run := Run{}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, errors.Wrapf(err, "failed to parse %s", filename)
+	}
+	return run, nil
+}
+
+// WriteJSON writes obj to a file as indented JSON, the parent directory is created if needed.
+func WriteJSON(filename string, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(path.Dir(filename), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
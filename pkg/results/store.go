@@ -0,0 +1,275 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// DefaultStore is the run-state store of the current capstan run.
+var DefaultStore = NewStore()
+
+// Store is a concurrency-safe store of the state of a capstan run.
+type Store struct {
+	mu  sync.RWMutex
+	run Run
+}
+
+// NewStore creates a new empty run-state store.
+func NewStore() *Store {
+	return &Store{
+		run: Run{State: StatePending},
+	}
+}
+
+// Init resets the store for a new capstan run.
+func (s *Store) Init(uuid, provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.run = Run{
+		UUID:     uuid,
+		Provider: provider,
+		State:    StatePending,
+	}
+}
+
+// AddWorkload adds a testing workload with all of its testing cases in pending state,
+// every testing case will be repeated frequency times.
+func (s *Store) AddWorkload(name, image, testingTool string, frequency int, testingCases []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wl := Workload{
+		Name:        name,
+		Image:       image,
+		TestingTool: testingTool,
+		Frequency:   frequency,
+	}
+	for _, name := range testingCases {
+		c := Case{Name: name}
+		for i := 1; i <= frequency; i++ {
+			c.Repeats = append(c.Repeats, Repeat{Repeat: i, State: StatePending})
+		}
+		wl.TestingCases = append(wl.TestingCases, c)
+	}
+	s.run.Workloads = append(s.run.Workloads, wl)
+}
+
+// StartRun marks the capstan run as running.
+func (s *Store) StartRun() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.run.State = StateRunning
+	s.run.StartTime = &now
+}
+
+// FinishRun marks the capstan run as finished with the given state.
+func (s *Store) FinishRun(state State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.run.State = state
+	s.run.EndTime = &now
+}
+
+// StartCase marks a repeat of a testing case as running, it is called for every attempt of the repeat.
+func (s *Store) StartCase(workload, testingCase string, repeat int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.findRepeat(workload, testingCase, repeat)
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	r.State = StateRunning
+	r.Attempts++
+	r.StartTime = &now
+	r.EndTime = nil
+	r.WorkloadNode = ""
+	r.TestingNode = ""
+	r.Metric = nil
+	r.Metrics = nil
+	r.Error = ""
+	r.Pods = nil
+}
+
+// SetMetrics records the metrics of the running repeat of a testing case, the first one
+// is the headline metric.
+func (s *Store) SetMetrics(workload, testingCase string, metrics []Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.findRunningRepeat(workload, testingCase); r != nil && len(metrics) != 0 {
+		r.Metrics = append([]Metric(nil), metrics...)
+		m := metrics[0]
+		r.Metric = &m
+	}
+}
+
+// SetNodes records the nodes of the workload pod and the testing pod of the running repeat of a testing case.
+func (s *Store) SetNodes(workload, testingCase, workloadNode, testingNode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.findRunningRepeat(workload, testingCase); r != nil {
+		r.WorkloadNode = workloadNode
+		r.TestingNode = testingNode
+	}
+}
+
+// SetPods records the metadata of the pods of the running repeat of a testing case.
+func (s *Store) SetPods(workload, testingCase string, pods []Pod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.findRunningRepeat(workload, testingCase); r != nil {
+		r.Pods = pods
+	}
+}
+
+// FinishCase marks a repeat of a testing case as succeeded, or as failed if err is not nil.
+func (s *Store) FinishCase(workload, testingCase string, repeat int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.findRepeat(workload, testingCase, repeat)
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	r.EndTime = &now
+	if err != nil {
+		r.State = StateFailed
+		r.Error = err.Error()
+		return
+	}
+	r.State = StateSucceeded
+}
+
+// AddFailure adds a failed attempt of a testing case to the failure ledger of the run.
+func (s *Store) AddFailure(failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.run.Failures = append(s.run.Failures, failure)
+}
+
+// FailedCases returns the number of failed repeats and the number of all repeats of testing cases.
+func (s *Store) FailedCases() (int, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	failed, total := 0, 0
+	for _, wl := range s.run.Workloads {
+		for _, c := range wl.TestingCases {
+			for _, r := range c.Repeats {
+				if r.State == StateFailed {
+					failed++
+				}
+				total++
+			}
+		}
+	}
+	return failed, total
+}
+
+// AbortCase marks a repeat of a testing case as aborted.
+func (s *Store) AbortCase(workload, testingCase string, repeat int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.findRepeat(workload, testingCase, repeat)
+	if r == nil {
+		return
+	}
+	now := time.Now()
+	r.EndTime = &now
+	r.State = StateAborted
+}
+
+// Snapshot returns a copy of the current state of the capstan run.
+func (s *Store) Snapshot() Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	run := s.run
+	run.Failures = append([]Failure(nil), s.run.Failures...)
+	run.Workloads = make([]Workload, len(s.run.Workloads))
+	for i, wl := range s.run.Workloads {
+		wl.TestingCases = make([]Case, len(s.run.Workloads[i].TestingCases))
+		for j, c := range s.run.Workloads[i].TestingCases {
+			c.Repeats = append([]Repeat(nil), c.Repeats...)
+			wl.TestingCases[j] = c
+		}
+		run.Workloads[i] = wl
+	}
+	return run
+}
+
+// findCase finds a testing case of a workload, the caller must hold the lock.
+func (s *Store) findCase(workload, testingCase string) *Case {
+	for i := range s.run.Workloads {
+		if s.run.Workloads[i].Name != workload {
+			continue
+		}
+		for j := range s.run.Workloads[i].TestingCases {
+			if s.run.Workloads[i].TestingCases[j].Name == testingCase {
+				return &s.run.Workloads[i].TestingCases[j]
+			}
+		}
+	}
+	glog.Warningf("Testing case %q of %s not found in run-state store", testingCase, workload)
+	return nil
+}
+
+// findRepeat finds a repeat of a testing case, the caller must hold the lock.
+func (s *Store) findRepeat(workload, testingCase string, repeat int) *Repeat {
+	c := s.findCase(workload, testingCase)
+	if c == nil {
+		return nil
+	}
+	for i := range c.Repeats {
+		if c.Repeats[i].Repeat == repeat {
+			return &c.Repeats[i]
+		}
+	}
+	glog.Warningf("Repeat %d of testing case %q of %s not found in run-state store", repeat, testingCase, workload)
+	return nil
+}
+
+// findRunningRepeat finds the running repeat of a testing case, the caller must hold the lock.
+func (s *Store) findRunningRepeat(workload, testingCase string) *Repeat {
+	c := s.findCase(workload, testingCase)
+	if c == nil {
+		return nil
+	}
+	for i := range c.Repeats {
+		if c.Repeats[i].State == StateRunning {
+			return &c.Repeats[i]
+		}
+	}
+	glog.Warningf("No running repeat of testing case %q of %s", testingCase, workload)
+	return nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/ZJU-SEL/capstan/pkg/stats"
+	"github.com/pkg/errors"
+)
+
+// SummaryFile is the file name of the statistics of all testing cases of a run.
+const SummaryFile = "summary.json"
+
+// Summary is the statistics of a metric over all succeeded repeats of a testing case, the
+// repeats without the metric are left out.
+type Summary struct {
+	Workload      string `json:"workload"`
+	TestingTool   string `json:"testingTool"`
+	TestingCase   string `json:"testingCase"`
+	Metric        string `json:"metric"`
+	Unit          string `json:"unit"`
+	LowerIsBetter bool   `json:"lowerIsBetter,omitempty"`
+	// Repeats is the number of repeats of the testing case, and Failed is the number of failed ones.
+	Repeats int       `json:"repeats"`
+	Failed  int       `json:"failed"`
+	Values  []float64 `json:"values"`
+	stats.Summary
+}
+
+// Summarize aggregates every metric of the repeats of each testing case of the run into
+// statistics by the name of the metric, the headline metric of a testing case comes first.
+func Summarize(run Run) []Summary {
+	var summaries []Summary
+	for _, wl := range run.Workloads {
+		for _, c := range wl.TestingCases {
+			var metrics []Summary
+			for _, r := range c.Repeats {
+				if r.State != StateSucceeded {
+					continue
+				}
+				for _, m := range repeatMetrics(r) {
+					metrics = addValue(metrics, Summary{
+						Workload:      wl.Name,
+						TestingTool:   wl.TestingTool,
+						TestingCase:   c.Name,
+						Metric:        m.Name,
+						Unit:          m.Unit,
+						LowerIsBetter: m.LowerIsBetter,
+					}, m.Value)
+				}
+			}
+			failed := 0
+			for _, r := range c.Repeats {
+				if r.State == StateFailed {
+					failed++
+				}
+			}
+			for i := range metrics {
+				metrics[i].Repeats = len(c.Repeats)
+				metrics[i].Failed = failed
+				metrics[i].Summary = stats.Summarize(metrics[i].Values)
+			}
+			summaries = append(summaries, metrics...)
+		}
+	}
+	return summaries
+}
+
+// LoadSummaries reads the statistics of a run from a summary file.
+func LoadSummaries(filename string) ([]Summary, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var summaries []Summary
+	if err := json.Unmarshal(data, &summaries); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", filename)
+	}
+	return summaries, nil
+}
+
+// repeatMetrics returns the metrics of a repeat, the results of older runs only have
+// the headline metric.
+func repeatMetrics(r Repeat) []Metric {
+	if len(r.Metrics) == 0 && r.Metric != nil {
+		return []Metric{*r.Metric}
+	}
+	return r.Metrics
+}
+
+// addValue appends value to the summary of the same metric, or adds a new summary for it.
+func addValue(summaries []Summary, s Summary, value float64) []Summary {
+	for i := range summaries {
+		if summaries[i].Metric == s.Metric {
+			summaries[i].Values = append(summaries[i].Values, value)
+			return summaries
+		}
+	}
+	s.Values = []float64{value}
+	return append(summaries, s)
+}
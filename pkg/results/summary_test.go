@@ -0,0 +1,110 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	metrics := func(qps, latency float64) []Metric {
+		return []Metric{
+			{Name: "qps", Unit: "requests/sec", Value: qps},
+			{Name: "latency_p99", Unit: "ms", Value: latency, LowerIsBetter: true},
+		}
+	}
+	run := Run{Workloads: []Workload{{
+		Name:        "nginx",
+		TestingTool: "wrk",
+		TestingCases: []Case{
+			{
+				Name: "benchmarkSameNode",
+				Repeats: []Repeat{
+					{Repeat: 1, State: StateSucceeded, Metrics: metrics(100, 3)},
+					{Repeat: 2, State: StateSucceeded, Metrics: metrics(200, 5)},
+					// the latency is not parsed from the results of every repeat.
+					{Repeat: 3, State: StateSucceeded, Metrics: metrics(300, 0)[:1]},
+					{Repeat: 4, State: StateFailed, Metrics: metrics(1000, 1)},
+				},
+			},
+			{
+				// the results of older runs only have the headline metric.
+				Name: "benchmarkDiffNode",
+				Repeats: []Repeat{
+					{Repeat: 1, State: StateSucceeded, Metric: &Metric{Name: "qps", Unit: "requests/sec", Value: 50}},
+					{Repeat: 2, State: StateSucceeded, Metric: &Metric{Name: "qps", Unit: "requests/sec", Value: 70}},
+				},
+			},
+		},
+	}}}
+
+	summaries := Summarize(run)
+	type key struct {
+		testingCase, metric string
+		lowerIsBetter       bool
+		repeats, failed     int
+	}
+	var got []key
+	values := map[key][]float64{}
+	for _, s := range summaries {
+		k := key{s.TestingCase, s.Metric, s.LowerIsBetter, s.Repeats, s.Failed}
+		got = append(got, k)
+		values[k] = s.Values
+	}
+	want := []key{
+		{"benchmarkSameNode", "qps", false, 4, 1},
+		{"benchmarkSameNode", "latency_p99", true, 4, 1},
+		{"benchmarkDiffNode", "qps", false, 2, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("summaries %+v, want %+v", got, want)
+	}
+
+	wantValues := [][]float64{{100, 200, 300}, {3, 5}, {50, 70}}
+	for i, k := range want {
+		if !reflect.DeepEqual(values[k], wantValues[i]) {
+			t.Errorf("values of %s %s: %v, want %v", k.testingCase, k.metric, values[k], wantValues[i])
+		}
+	}
+	if summaries[0].Mean != 200 || summaries[1].Count != 2 || summaries[1].Mean != 4 {
+		t.Errorf("statistics %+v, %+v", summaries[0].Summary, summaries[1].Summary)
+	}
+}
+
+func TestSetMetrics(t *testing.T) {
+	s := NewStore()
+	s.AddWorkload("nginx", "nginx", "wrk", 1, []string{"benchmarkSameNode"})
+	s.StartCase("nginx", "benchmarkSameNode", 1)
+	metrics := []Metric{{Name: "qps", Value: 100}, {Name: "latency_p99", Value: 3, LowerIsBetter: true}}
+	s.SetMetrics("nginx", "benchmarkSameNode", metrics)
+	metrics[0].Value = 0
+
+	r := s.Snapshot().Workloads[0].TestingCases[0].Repeats[0]
+	if r.Metric == nil || r.Metric.Name != "qps" || r.Metric.Value != 100 {
+		t.Errorf("headline metric %+v, want qps", r.Metric)
+	}
+	if len(r.Metrics) != 2 || r.Metrics[0].Value != 100 || r.Metrics[1].Name != "latency_p99" {
+		t.Errorf("metrics %+v, want a copy of the metrics", r.Metrics)
+	}
+
+	// a retry of the repeat starts without metrics.
+	s.StartCase("nginx", "benchmarkSameNode", 1)
+	if r := s.Snapshot().Workloads[0].TestingCases[0].Repeats[0]; r.Metric != nil || r.Metrics != nil {
+		t.Errorf("metrics %+v of a retried repeat, want none", r.Metrics)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package results
+
+import (
+	"time"
+)
+
+// State is the state of a capstan run or of a repeat of a testing case.
+type State string
+
+const (
+	// StatePending means the testing case has not started yet.
+	StatePending State = "pending"
+	// StateRunning means the testing case is running.
+	StateRunning State = "running"
+	// StateSucceeded means the testing case has finished successfully.
+	StateSucceeded State = "succeeded"
+	// StateFailed means the testing case has failed.
+	StateFailed State = "failed"
+	// StateAborted means the testing case was interrupted before it finished.
+	StateAborted State = "aborted"
+	// StatePartiallyFailed means the run has finished, but some of its testing cases have failed.
+	StatePartiallyFailed State = "partially-failed"
+)
+
+// Run is the internal representation of a capstan run.
+type Run struct {
+	UUID      string     `json:"uuid"`
+	Provider  string     `json:"provider"`
+	State     State      `json:"state"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+	Workloads []Workload `json:"workloads"`
+	// Failures is the ledger of all failed attempts of testing cases.
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Workload is the internal representation of a testing workload of a run.
+type Workload struct {
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	TestingTool  string `json:"testingTool"`
+	Frequency    int    `json:"frequency"`
+	TestingCases []Case `json:"testingCases"`
+}
+
+// Case is the internal representation of a testing case of a workload.
+type Case struct {
+	Name    string   `json:"name"`
+	Repeats []Repeat `json:"repeats"`
+}
+
+// Repeat is the internal representation of a single repeat of a testing case.
+type Repeat struct {
+	Repeat       int        `json:"repeat"`
+	State        State      `json:"state"`
+	StartTime    *time.Time `json:"startTime,omitempty"`
+	EndTime      *time.Time `json:"endTime,omitempty"`
+	WorkloadNode string     `json:"workloadNode,omitempty"`
+	TestingNode  string     `json:"testingNode,omitempty"`
+	// Metric is the headline metric of the repeat, i.e. the first one of Metrics.
+	Metric *Metric `json:"metric,omitempty"`
+	// Metrics is every metric parsed from the testing results of the repeat.
+	Metrics []Metric `json:"metrics,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	// Attempts is the number of attempts of the repeat, it is more than 1 if the repeat was retried.
+	Attempts int `json:"attempts,omitempty"`
+	// Pods is the metadata of the workload and testing pods of the last attempt of the repeat.
+	Pods []Pod `json:"pods,omitempty"`
+}
+
+// Pod is the metadata of a pod of a testing case, the times are in seconds
+// since the pod was created.
+type Pod struct {
+	Name string `json:"name"`
+	Node string `json:"node,omitempty"`
+	// Scheduled is the time when the pod was bound to its node.
+	Scheduled float64 `json:"scheduledSeconds"`
+	// ImagePull is how long the images of the pod were pulled for, it is 0 if they were present on the node.
+	ImagePull float64 `json:"imagePullSeconds"`
+	// Started is the time when the first container of the pod started.
+	Started float64 `json:"startedSeconds"`
+}
+
+// Failure is a failed attempt of a repeat of a testing case.
+type Failure struct {
+	Workload    string    `json:"workload"`
+	TestingCase string    `json:"testingCase"`
+	Repeat      int       `json:"repeat"`
+	Attempt     int       `json:"attempt"`
+	Time        time.Time `json:"time"`
+	Error       string    `json:"error"`
+	// Dir is the directory of the error, pod events and logs of the failure, relative to the results directory of the run.
+	Dir string `json:"dir"`
+}
+
+// Metric is a metric parsed from the testing results of a testing case, the first one of a
+// testing case is its headline metric, e.g. the QPS of wrk, the bandwidth of iperf3 or the
+// TpmC of tpcc-mysql.
+type Metric struct {
+	Name  string  `json:"name"`
+	Unit  string  `json:"unit"`
+	Value float64 `json:"value"`
+	// LowerIsBetter is true for metrics like latency, where a smaller value is an improvement.
+	LowerIsBetter bool `json:"lowerIsBetter,omitempty"`
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// maxExactSamples is the max sample size for which the exact distribution of U is used.
+const maxExactSamples = 20
+
+// MannWhitneyU performs a two-sided Mann-Whitney U test on the samples x and y,
+// it returns the U statistic of x and the p-value of the hypothesis that x and y
+// are drawn from the same distribution. The exact distribution of U is used for
+// small samples without ties, otherwise the normal approximation with tie correction.
+// The p-value is NaN if a sample has less than two values.
+func MannWhitneyU(x, y []float64) (float64, float64) {
+	n1, n2 := len(x), len(y)
+	if n1 < 2 || n2 < 2 {
+		return 0, math.NaN()
+	}
+
+	type sample struct {
+		value float64
+		fromX bool
+	}
+	all := make([]sample, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range y {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Rank all samples, tied samples get the average of their ranks.
+	var rankSumX, tieSum float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromX {
+				rankSumX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieSum += t*t*t - t
+		}
+		i = j
+	}
+
+	u := rankSumX - float64(n1*(n1+1))/2
+	mean := float64(n1*n2) / 2
+
+	if !ties && n1 <= maxExactSamples && n2 <= maxExactSamples {
+		return u, exactPValue(n1, n2, u)
+	}
+
+	n := float64(n1 + n2)
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieSum/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	// Apply the continuity correction.
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// MinPValue returns the smallest two-sided p-value MannWhitneyU can return for sample
+// sizes n1 and n2, i.e. that of the samples which do not overlap at all. It is NaN if a
+// sample has less than two values. No difference between the samples is significant at
+// a level at or below it, e.g. 0.1 for 3 against 3 repeats.
+func MinPValue(n1, n2 int) float64 {
+	if n1 < 2 || n2 < 2 {
+		return math.NaN()
+	}
+	// Only 2 of the C(n1+n2, n1) arrangements of the samples are as extreme.
+	arrangements := 1.0
+	for i := 1; i <= n1; i++ {
+		arrangements = arrangements * float64(n2+i) / float64(i)
+	}
+	return math.Min(1, 2/arrangements)
+}
+
+// exactPValue returns the two-sided p-value of U using the exact distribution of U
+// for sample sizes n1 and n2 without ties.
+func exactPValue(n1, n2 int, u float64) float64 {
+	max := n1 * n2
+	// counts[i][j][k] is the number of arrangements of i x-samples and j y-samples with U = k,
+	// only the last two rows of i are kept.
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, max+1)
+		prev[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		for j := range cur {
+			cur[j] = make([]float64, max+1)
+			for k := 0; k <= max; k++ {
+				if j == 0 {
+					if k == 0 {
+						cur[j][k] = 1
+					}
+					continue
+				}
+				// The largest sample is either from x (adds j to U) or from y.
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				cur[j][k] += cur[j-1][k]
+			}
+		}
+		prev = cur
+	}
+
+	dist := prev[n2]
+	var total float64
+	for _, c := range dist {
+		total += c
+	}
+
+	// Two-sided: probability of a U at least as extreme as the observed one.
+	lower := math.Min(u, float64(max)-u)
+	var tail float64
+	for k := 0; k <= max && float64(k) <= lower; k++ {
+		tail += dist[k]
+	}
+	return math.Min(1, 2*tail/total)
+}
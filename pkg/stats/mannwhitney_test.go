@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   []float64
+		u      float64
+		pValue float64
+	}{
+		{"3v3 apart", []float64{1, 2, 3}, []float64{4, 5, 6}, 0, 0.1},
+		{"3v3 apart reversed", []float64{4, 5, 6}, []float64{1, 2, 3}, 9, 0.1},
+		{"3v3 interleaved", []float64{1, 3, 5}, []float64{2, 4, 6}, 3, 0.7},
+		{"5v5 apart", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0, 2.0 / 252},
+		// Ties use the normal approximation with tie and continuity correction.
+		{"3v3 ties", []float64{1, 1, 2}, []float64{2, 3, 3}, 0.5, 0.11014892418594698},
+		{"all tied", []float64{1, 1, 1}, []float64{1, 1, 1}, 4.5, 1},
+		{"1v3", []float64{1}, []float64{4, 5, 6}, 0, math.NaN()},
+		{"empty", nil, []float64{4, 5, 6}, 0, math.NaN()},
+	}
+	for _, test := range tests {
+		u, pValue := MannWhitneyU(test.x, test.y)
+		if u != test.u {
+			t.Errorf("%s: U = %v, want %v", test.name, u, test.u)
+		}
+		if !equal(pValue, test.pValue) {
+			t.Errorf("%s: p-value = %v, want %v", test.name, pValue, test.pValue)
+		}
+	}
+}
+
+func TestMinPValue(t *testing.T) {
+	tests := []struct {
+		n1, n2 int
+		want   float64
+	}{
+		{2, 2, 1.0 / 3},
+		{3, 3, 0.1},
+		{3, 5, 2.0 / 56},
+		{5, 5, 2.0 / 252},
+		{1, 3, math.NaN()},
+		{0, 0, math.NaN()},
+	}
+	for _, test := range tests {
+		if got := MinPValue(test.n1, test.n2); !equal(got, test.want) {
+			t.Errorf("MinPValue(%d, %d) = %v, want %v", test.n1, test.n2, got, test.want)
+		}
+	}
+
+	// MinPValue is the p-value of the samples which do not overlap at all.
+	for n := 2; n <= 6; n++ {
+		var x, y []float64
+		for i := 0; i < n; i++ {
+			x = append(x, float64(i))
+			y = append(y, float64(n+i))
+		}
+		if _, pValue := MannWhitneyU(x, y); !equal(pValue, MinPValue(n, n)) {
+			t.Errorf("%dv%d apart: p-value = %v, want MinPValue %v", n, n, pValue, MinPValue(n, n))
+		}
+	}
+}
+
+func equal(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
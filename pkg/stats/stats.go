@@ -0,0 +1,104 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary is the descriptive statistics of a set of samples.
+type Summary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Stddev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	// CV is the coefficient of variation (Stddev / Mean).
+	CV float64 `json:"cv"`
+}
+
+// Summarize computes the descriptive statistics of the samples.
+func Summarize(samples []float64) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	s := Summary{
+		Count:  len(sorted),
+		Mean:   Mean(sorted),
+		Median: Percentile(sorted, 50),
+		Stddev: Stddev(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		P90:    Percentile(sorted, 90),
+		P95:    Percentile(sorted, 95),
+	}
+	if s.Mean != 0 {
+		s.CV = s.Stddev / math.Abs(s.Mean)
+	}
+	return s
+}
+
+// Mean returns the arithmetic mean of the samples.
+func Mean(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	return sum / float64(len(samples))
+}
+
+// Stddev returns the sample standard deviation of the samples.
+func Stddev(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	mean := Mean(samples)
+	var sum float64
+	for _, v := range samples {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(samples)-1))
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the sorted samples,
+// using linear interpolation between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []float64
+		want    Summary
+	}{
+		{"empty", nil, Summary{}},
+		{"single sample", []float64{7}, Summary{Count: 1, Mean: 7, Median: 7, Min: 7, Max: 7, P90: 7, P95: 7}},
+		// The population stddev is 2, Stddev is the sample stddev sqrt(32/7).
+		{"sample stddev", []float64{2, 4, 4, 4, 5, 5, 7, 9}, Summary{
+			Count: 8, Mean: 5, Median: 4.5, Stddev: math.Sqrt(32.0 / 7), Min: 2, Max: 9,
+			// The ranks are 0.9*7 = 6.3 and 0.95*7 = 6.65, between 7 and 9.
+			P90: 7.6, P95: 8.3, CV: math.Sqrt(32.0/7) / 5,
+		}},
+		{"unsorted", []float64{5, 1, 3}, Summary{
+			Count: 3, Mean: 3, Median: 3, Stddev: 2, Min: 1, Max: 5, P90: 4.6, P95: 4.8, CV: 2.0 / 3,
+		}},
+		{"zero mean", []float64{-1, 1}, Summary{
+			Count: 2, Mean: 0, Median: 0, Stddev: math.Sqrt2, Min: -1, Max: 1, P90: 0.8, P95: 0.9, CV: 0,
+		}},
+		// CV is relative to the absolute mean, so it is never negative.
+		{"negative mean", []float64{-2, -4}, Summary{
+			Count: 2, Mean: -3, Median: -3, Stddev: math.Sqrt2, Min: -4, Max: -2, P90: -2.2, P95: -2.1, CV: math.Sqrt2 / 3,
+		}},
+	}
+	for _, test := range tests {
+		samples := append([]float64(nil), test.samples...)
+		got := Summarize(samples)
+		if got.Count != test.want.Count {
+			t.Errorf("%s: Count = %d, want %d", test.name, got.Count, test.want.Count)
+		}
+		for _, f := range []struct {
+			name      string
+			got, want float64
+		}{
+			{"Mean", got.Mean, test.want.Mean},
+			{"Median", got.Median, test.want.Median},
+			{"Stddev", got.Stddev, test.want.Stddev},
+			{"Min", got.Min, test.want.Min},
+			{"Max", got.Max, test.want.Max},
+			{"P90", got.P90, test.want.P90},
+			{"P95", got.P95, test.want.P95},
+			{"CV", got.CV, test.want.CV},
+		} {
+			if !equal(f.got, f.want) {
+				t.Errorf("%s: %s = %v, want %v", test.name, f.name, f.got, f.want)
+			}
+		}
+		if !reflect.DeepEqual(samples, test.samples) {
+			t.Errorf("%s: Summarize changed the samples to %v", test.name, samples)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{-1, 10},
+		{0, 10},
+		{25, 17.5},
+		{50, 25},
+		{90, 37},
+		{95, 38.5},
+		{100, 40},
+		{101, 40},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.p); !equal(got, test.want) {
+			t.Errorf("Percentile(%v, %v) = %v, want %v", sorted, test.p, got, test.want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile(nil, 50) = %v, want 0", got)
+	}
+}
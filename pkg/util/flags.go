@@ -51,7 +51,33 @@ func InitFlags() {
 
 	pflag.Parse()
 
-	path := pflag.Lookup("log-dir").Value.String()
+	ensureLogDir(pflag.CommandLine)
+}
+
+// InitFlagSet normalizes and parses the flags of a subcommand, the global
+// command line flags and the go flags (e.g. glog flags) are added to the flag set.
+func InitFlagSet(fs *pflag.FlagSet, args []string) error {
+	fs.SetNormalizeFunc(WordSepNormalizeFunc)
+	fs.AddGoFlagSet(goflag.CommandLine)
+	fs.AddFlagSet(pflag.CommandLine)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Mark the go flags as parsed, the values have been set through the flag set.
+	_ = goflag.CommandLine.Parse([]string{})
+
+	ensureLogDir(fs)
+	return nil
+}
+
+// ensureLogDir creates the glog log directory if it does not exist.
+func ensureLogDir(fs *pflag.FlagSet) {
+	flag := fs.Lookup("log-dir")
+	if flag == nil || flag.Value.String() == "" {
+		return
+	}
+	path := flag.Value.String()
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		_ = os.MkdirAll(path, 0755)
 	}
@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"path"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// PodsFile is the file of the pod objects of a testing case.
+	PodsFile = "pods.json"
+	// EventsFile is the file of the events of the pods of a testing case.
+	EventsFile = "events.json"
+)
+
+// CaptureTestingCase saves the pod objects (status, conditions, node and container statuses)
+// and the events of the workload and testing pods of a testing case, named podNames, into dir,
+// and returns the scheduling, image pulling and starting times of the pods. It must be called
+// before the pods are cleaned up.
+func CaptureTestingCase(kubeClient kubernetes.Interface, namespace, testingCase string, podNames []string, dir string) ([]results.Pod, error) {
+	pods, err := listTestingCasePods(kubeClient, namespace, testingCase, podNames)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []v1.Event{}
+	var metadata []results.Pod
+	for i := range pods {
+		pod := pods[i]
+		list, err := kubeClient.CoreV1().Events(namespace).List(apismetav1.ListOptions{FieldSelector: podEventsSelector(&pod)})
+		if err != nil {
+			glog.Warningf("Failed to list the events of pod %s: %v", pod.Name, err)
+			list = &v1.EventList{}
+		}
+		events = append(events, list.Items...)
+		metadata = append(metadata, podMetadata(pod, list.Items))
+	}
+
+	if err := results.WriteJSON(path.Join(dir, PodsFile), pods); err != nil {
+		return nil, err
+	}
+	if err := results.WriteJSON(path.Join(dir, EventsFile), events); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// listTestingCasePods lists the pods created by capstan for the testing case and named podNames,
+// which are not the pods of a testing case of the same name of another workload of a parallel run.
+func listTestingCasePods(kubeClient kubernetes.Interface, namespace, testingCase string, podNames []string) ([]v1.Pod, error) {
+	list, err := kubeClient.CoreV1().Pods(namespace).List(apismetav1.ListOptions{LabelSelector: CapstanSelector})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list the pods of testing case %s", testingCase)
+	}
+	pods := []v1.Pod{}
+	for _, pod := range list.Items {
+		if pod.Annotations[testingCaseAnnotation] == testingCase && contains(podNames, pod.Name) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// podMetadata returns the times of a pod since it was created: when it was scheduled from
+// its PodScheduled condition, how long its images were pulled for from the Pulling and
+// Pulled events, and when its first container started from the container statuses.
+func podMetadata(pod v1.Pod, events []v1.Event) results.Pod {
+	created := pod.CreationTimestamp.Time
+	metadata := results.Pod{
+		Name: pod.Name,
+		Node: pod.Spec.NodeName,
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionTrue {
+			metadata.Scheduled = sinceCreated(created, cond.LastTransitionTime.Time)
+		}
+	}
+
+	var pulling, pulled time.Time
+	for _, event := range events {
+		switch event.Reason {
+		case "Pulling":
+			if pulling.IsZero() || event.FirstTimestamp.Time.Before(pulling) {
+				pulling = event.FirstTimestamp.Time
+			}
+		case "Pulled":
+			if event.LastTimestamp.Time.After(pulled) {
+				pulled = event.LastTimestamp.Time
+			}
+		}
+	}
+	if !pulling.IsZero() && pulled.After(pulling) {
+		metadata.ImagePull = pulled.Sub(pulling).Seconds()
+	}
+
+	var started time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		var t time.Time
+		switch {
+		case status.State.Running != nil:
+			t = status.State.Running.StartedAt.Time
+		case status.State.Terminated != nil:
+			t = status.State.Terminated.StartedAt.Time
+		}
+		if !t.IsZero() && (started.IsZero() || t.Before(started)) {
+			started = t
+		}
+	}
+	metadata.Started = sinceCreated(created, started)
+
+	return metadata
+}
+
+// sinceCreated returns the seconds from the creation of a pod to t, or 0 if t is unknown.
+func sinceCreated(created, t time.Time) float64 {
+	if t.IsZero() || t.Before(created) {
+		return 0
+	}
+	return t.Sub(created).Seconds()
+}
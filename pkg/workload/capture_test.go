@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCaptureTestingCase(t *testing.T) {
+	created := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	pod := func(name, uid, testingCase string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: apismetav1.ObjectMeta{
+				Name:              name,
+				UID:               types.UID(uid),
+				Labels:            map[string]string{"component": "capstan", "testing": name},
+				Annotations:       map[string]string{testingCaseAnnotation: testingCase},
+				CreationTimestamp: apismetav1.NewTime(created),
+			},
+			Spec: v1.PodSpec{NodeName: "node-1"},
+			Status: v1.PodStatus{Conditions: []v1.PodCondition{
+				{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: apismetav1.NewTime(created.Add(time.Second))},
+			}},
+		}
+	}
+	event := func(name, uid, reason string, first, last time.Time) v1.Event {
+		return v1.Event{
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: name, UID: types.UID(uid)},
+			Reason:         reason,
+			FirstTimestamp: apismetav1.NewTime(first),
+			LastTimestamp:  apismetav1.NewTime(last),
+		}
+	}
+
+	workloadPod := "capstan-redis-benchmarksamenode-workload"
+	testingPod := "capstan-redis-redis-benchmark-benchmarksamenode-testing"
+	kubeClient := &fakeClient{
+		pods: []v1.Pod{
+			pod(workloadPod, "uid-1", "benchmarkSameNode"),
+			pod(testingPod, "uid-2", "benchmarkSameNode"),
+			// the pods of a testing case of the same name of another workload of a parallel run.
+			pod("capstan-redis-cache-benchmarksamenode-workload", "uid-3", "benchmarkSameNode"),
+			pod("capstan-nginx-benchmarksamenode-workload", "uid-4", "benchmarkSameNode"),
+			// the pod of another testing case.
+			pod("capstan-redis-benchmarkdiffnode-workload", "uid-5", "benchmarkDiffNode"),
+		},
+		events: []v1.Event{
+			event(workloadPod, "uid-1", "Pulling", created.Add(2*time.Second), created.Add(2*time.Second)),
+			event(workloadPod, "uid-1", "Pulled", created.Add(5*time.Second), created.Add(5*time.Second)),
+			// the events of the workload pod of the former repeat.
+			event(workloadPod, "uid-0", "Pulling", created.Add(-time.Minute), created.Add(-time.Minute)),
+			event(workloadPod, "uid-0", "Pulled", created.Add(-time.Minute), created.Add(time.Minute)),
+			event("capstan-redis-cache-benchmarksamenode-workload", "uid-3", "Pulling", created, created),
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "capstan-capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	got, err := CaptureTestingCase(kubeClient, "capstan", "benchmarkSameNode", []string{workloadPod, testingPod}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []results.Pod{
+		{Name: workloadPod, Node: "node-1", Scheduled: 1, ImagePull: 3},
+		{Name: testingPod, Node: "node-1", Scheduled: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pods %+v, want %+v", got, want)
+	}
+
+	var pods []v1.Pod
+	readJSON(t, path.Join(dir, PodsFile), &pods)
+	if len(pods) != 2 || pods[0].Name != workloadPod || pods[1].Name != testingPod {
+		t.Errorf("%s has %d pods, want the workload and testing pods", PodsFile, len(pods))
+	}
+	var events []v1.Event
+	readJSON(t, path.Join(dir, EventsFile), &events)
+	if len(events) != 2 {
+		t.Errorf("%s has %d events, want the 2 events of the workload pod", EventsFile, len(events))
+	}
+	for _, e := range events {
+		if e.InvolvedObject.UID != "uid-1" {
+			t.Errorf("%s has the event %s of pod %s", EventsFile, e.Reason, e.InvolvedObject.UID)
+		}
+	}
+}
+
+func readJSON(t *testing.T, filename string, obj interface{}) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		t.Fatal(err)
+	}
+}
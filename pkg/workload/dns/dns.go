@@ -0,0 +1,42 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+// WorkloadName is the name of the dns workload in the capstan config.
+const WorkloadName = "dns"
+
+func init() {
+	workload.Register(WorkloadName, workload.Factory{
+		ToolName:       ToolName,
+		TestingCaseSet: TestingCaseSet,
+		NoWorkloadPod:  true,
+		New: func(wl workload.Workload, opts workload.RunOptions) workload.Interface {
+			return NewWorkload(wl, opts)
+		},
+		Validate: Validate,
+	})
+}
+
+// NewWorkload creates a new dns workload from the given workload definition,
+// whose testing cases run with the options.
+func NewWorkload(wl workload.Workload, opts workload.RunOptions) *workload.Runner {
+	return workload.NewRunner(wl, opts, &Driver{})
+}
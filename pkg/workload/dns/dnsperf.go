@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Bucket is a bucket of the latency histogram of dnsperf, the latencies are in seconds.
+type Bucket struct {
+	Min   float64
+	Max   float64
+	Count int64
+}
+
+// Result is the statistics printed by dnsperf.
+type Result struct {
+	QueriesSent      int64
+	QueriesCompleted int64
+	QueriesLost      int64
+	// Timeouts is the number of the queries reported as timed out.
+	Timeouts int64
+	// ResponseCodes maps the response codes, e.g. NOERROR, to their counts.
+	ResponseCodes  map[string]int64
+	QueriesPerSec  float64
+	RunTimeSeconds float64
+	// The latencies are in seconds.
+	LatencyAvg    float64
+	LatencyMin    float64
+	LatencyMax    float64
+	LatencyStddev float64
+	// Histogram is only printed by dnsperf with -O latency-histogram.
+	Histogram []Bucket
+}
+
+// ParseDnsperf parses the output of dnsperf, it fails if the output has no queries per second.
+func ParseDnsperf(data []byte) (*Result, error) {
+	r := &Result{ResponseCodes: map[string]int64{}}
+	foundQPS := false
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value := splitLine(line)
+		fields := strings.Fields(value)
+
+		var err error
+		switch {
+		case strings.HasPrefix(line, "[Timeout]"):
+			r.Timeouts++
+		case key == "Queries sent":
+			r.QueriesSent, err = parseInt(fields)
+		case key == "Queries completed":
+			r.QueriesCompleted, err = parseInt(fields)
+		case key == "Queries lost":
+			r.QueriesLost, err = parseInt(fields)
+		case key == "Response codes":
+			// NOERROR 100000 (81.04%), NXDOMAIN 23400 (18.96%)
+			for _, code := range strings.Split(value, ",") {
+				codeFields := strings.Fields(code)
+				if len(codeFields) < 2 {
+					continue
+				}
+				if r.ResponseCodes[codeFields[0]], err = strconv.ParseInt(codeFields[1], 10, 64); err != nil {
+					break
+				}
+			}
+		case key == "Run time (s)":
+			r.RunTimeSeconds, err = parseFloat(fields)
+		case key == "Queries per second":
+			r.QueriesPerSec, err = parseFloat(fields)
+			foundQPS = err == nil
+		case key == "Average Latency (s)":
+			// 0.001234 (min 0.000123, max 0.123456)
+			if len(fields) == 5 {
+				if r.LatencyAvg, err = parseFloat(fields); err == nil {
+					if r.LatencyMin, err = parseFloat(fields[2:]); err == nil {
+						r.LatencyMax, err = parseFloat([]string{strings.TrimSuffix(fields[4], ")")})
+					}
+				}
+			}
+		case key == "Latency StdDev (s)":
+			r.LatencyStddev, err = parseFloat(fields)
+		case len(fields) == 1 && strings.Contains(key, " - "):
+			// 0.000100 - 0.000110: 3
+			bounds := strings.SplitN(key, " - ", 2)
+			b := Bucket{}
+			if b.Min, err = strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64); err != nil {
+				break
+			}
+			if b.Max, err = strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64); err != nil {
+				break
+			}
+			if b.Count, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+				r.Histogram = append(r.Histogram, b)
+			}
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid line %q", line)
+		}
+	}
+
+	if !foundQPS {
+		return nil, errors.Errorf("results not contain Queries per second")
+	}
+	return r, nil
+}
+
+// Percentile returns the latency in seconds below which the fraction q of the answers are,
+// estimated by the upper bound of the bucket of the histogram. It returns false if there is
+// no histogram.
+func (r *Result) Percentile(q float64) (float64, bool) {
+	var total int64
+	for _, b := range r.Histogram {
+		total += b.Count
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	buckets := append([]Bucket(nil), r.Histogram...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Min < buckets[j].Min })
+	rank := int64(math.Ceil(q * float64(total)))
+	var count int64
+	for _, b := range buckets {
+		count += b.Count
+		if count >= rank {
+			return b.Max, true
+		}
+	}
+	return buckets[len(buckets)-1].Max, true
+}
+
+// splitLine splits a line of the statistics of dnsperf into its key and value, e.g.
+// "Queries sent:         123456".
+func splitLine(line string) (string, string) {
+	i := strings.Index(line, ":")
+	if i <= 0 {
+		return "", ""
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+}
+
+func parseInt(fields []string) (int64, error) {
+	if len(fields) == 0 {
+		return 0, errors.New("missing value")
+	}
+	v, err := strconv.ParseInt(fields[0], 10, 64)
+	return v, errors.WithStack(err)
+}
+
+func parseFloat(fields []string) (float64, error) {
+	if len(fields) == 0 {
+		return 0, errors.New("missing value")
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], ","), 64)
+	return v, errors.WithStack(err)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseDnsperf(t *testing.T) {
+	tests := []struct {
+		file      string
+		want      Result
+		histogram int
+	}{
+		{
+			file: "dnsperf-histogram.txt",
+			want: Result{
+				QueriesSent:      1260342,
+				QueriesCompleted: 1260340,
+				QueriesLost:      2,
+				Timeouts:         2,
+				ResponseCodes:    map[string]int64{"NOERROR": 504136, "NXDOMAIN": 756204},
+				QueriesPerSec:    42011.17528,
+				RunTimeSeconds:   30.000612,
+				LatencyAvg:       0.002318,
+				LatencyMin:       0.000088,
+				LatencyMax:       0.041207,
+				LatencyStddev:    0.001673,
+			},
+			histogram: 7,
+		},
+		{
+			file: "dnsperf.txt",
+			want: Result{
+				QueriesSent:      2875021,
+				QueriesCompleted: 2875021,
+				ResponseCodes:    map[string]int64{"NOERROR": 2875021},
+				QueriesPerSec:    95833.70078,
+				RunTimeSeconds:   30.000104,
+				LatencyAvg:       0.001011,
+				LatencyMin:       0.000031,
+				LatencyMax:       0.012093,
+				LatencyStddev:    0.000412,
+			},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseDnsperf(readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		histogram := got.Histogram
+		got.Histogram = nil
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.file, *got, test.want)
+		}
+		if len(histogram) != test.histogram {
+			t.Errorf("%s: %d buckets, want %d", test.file, len(histogram), test.histogram)
+		}
+	}
+
+	if _, err := ParseDnsperf([]byte("Error: unable to open datafile: /tmp/queries.txt\n")); err == nil {
+		t.Errorf("parsing the output without Queries per second succeeded")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	r, err := ParseDnsperf(readTestdata(t, "dnsperf-histogram.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1260340 answers: 126034 below 0.6ms, 504136 below 1.1ms, 1134306 below 2.1ms,
+	// 1247736 below 4.5ms and 1259078 below 9ms.
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.1, 0.0006},
+		{0.2, 0.0011},
+		{0.5, 0.0021},
+		{0.9, 0.0021},
+		{0.98, 0.0045},
+		{0.99, 0.009},
+		{0.999, 0.045},
+	}
+	for _, test := range tests {
+		if got, ok := r.Percentile(test.q); !ok || got != test.want {
+			t.Errorf("Percentile(%v) = %v, %v, want %v", test.q, got, ok, test.want)
+		}
+	}
+
+	r, err = ParseDnsperf(readTestdata(t, "dnsperf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Percentile(0.5); ok {
+		t.Errorf("Percentile without a histogram succeeded")
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	metrics, err := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "dnsperf-histogram.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics[0].Name != "qps" || metrics[0].Value != 42011.17528 {
+		t.Errorf("headline metric %+v, want qps 42011.17528", metrics[0])
+	}
+	want := map[string]float64{
+		"latency_avg":    2.318,
+		"latency_p99":    9,
+		"queries_lost":   2,
+		"timeouts":       2,
+		"lost_percent":   2 * 100 / 1260342.0,
+		"rcode_noerror":  504136,
+		"rcode_nxdomain": 756204,
+	}
+	values := map[string]float64{}
+	for _, m := range metrics {
+		values[m.Name] = m.Value
+	}
+	for name, v := range want {
+		if got, found := values[name]; !found || math.Abs(got-v) > 1e-9 {
+			t.Errorf("%s = %v (found %v), want %v", name, got, found, v)
+		}
+	}
+
+	metrics, err = (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "dnsperf.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range metrics {
+		if strings.HasPrefix(m.Name, "latency_p") {
+			t.Errorf("unexpected metric %s without a histogram", m.Name)
+		}
+	}
+}
+
+func TestGenerateQueries(t *testing.T) {
+	names := clusterNames("capstan", "cluster.local")
+	domains := searchDomains("capstan", "cluster.local")
+
+	fqdn := generateQueries(names, domains, false)
+	wantFQDN := `kubernetes.default.svc.cluster.local. A
+kube-dns.kube-system.svc.cluster.local. A
+POD_IP.capstan.pod.cluster.local. A
+kubernetes.io. A
+github.com. A
+`
+	if fqdn != wantFQDN {
+		t.Errorf("FQDN queries:\n%s\nwant:\n%s", fqdn, wantFQDN)
+	}
+
+	// a resolver tries the search domains in order until the name resolves, and the
+	// name itself last.
+	searchPath := generateQueries(names[:1], domains, true)
+	wantSearchPath := `kubernetes.default.capstan.svc.cluster.local. A
+kubernetes.default.svc.cluster.local. A
+`
+	if searchPath != wantSearchPath {
+		t.Errorf("search path queries of a service:\n%s\nwant:\n%s", searchPath, wantSearchPath)
+	}
+	searchPath = generateQueries(names[4:], domains, true)
+	wantSearchPath = `github.com.capstan.svc.cluster.local. A
+github.com.svc.cluster.local. A
+github.com.cluster.local. A
+github.com. A
+`
+	if searchPath != wantSearchPath {
+		t.Errorf("search path queries of an external name:\n%s\nwant:\n%s", searchPath, wantSearchPath)
+	}
+}
+
+func TestTestingPodArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"", []string{"-l", "30", "-O", "latency-histogram"}},
+		{"-Q 10000", []string{"-Q", "10000", "-l", "30", "-O", "latency-histogram"}},
+		{"-l 60", []string{"-l", "60", "-O", "latency-histogram"}},
+		{"-n 3 -O latency-histogram", []string{"-n", "3", "-O", "latency-histogram"}},
+	}
+	for _, test := range tests {
+		pod, err := (&Driver{}).TestingPod(workload.PodArgs{
+			Name:        "capstan-dns-dnsperf-benchmarkfqdn-testing",
+			TestingName: benchmarkFQDN,
+			Image:       "wadelee/dnsperf",
+			Namespace:   "capstan",
+			TestingCase: workload.TestingCase{Name: benchmarkFQDN, TestingToolArgs: test.args},
+		})
+		if err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+		if got := pod.Spec.Containers[0].Args; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: args %q, want %q", test.args, got, test.want)
+		}
+	}
+}
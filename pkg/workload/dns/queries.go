@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"fmt"
+	"strings"
+)
+
+// podIPPlaceholder is replaced with the IP of the testing pod with dashes, e.g. 10-0-0-1,
+// in the query file by the testing pod.
+const podIPPlaceholder = "POD_IP"
+
+// name is a name queried by dnsperf: the name as an application looks it up, and the
+// fully qualified name which the cluster DNS answers.
+type name struct {
+	short string
+	fqdn  string
+}
+
+// clusterNames returns the names queried by the testing cases: service names, the pod name
+// of the testing pod and external names.
+func clusterNames(namespace, clusterDomain string) []name {
+	return []name{
+		{"kubernetes.default", "kubernetes.default.svc." + clusterDomain},
+		{"kube-dns.kube-system", "kube-dns.kube-system.svc." + clusterDomain},
+		{podIPPlaceholder + "." + namespace + ".pod", podIPPlaceholder + "." + namespace + ".pod." + clusterDomain},
+		{"kubernetes.io", "kubernetes.io"},
+		{"github.com", "github.com"},
+	}
+}
+
+// searchDomains returns the search domains of the resolv.conf of a pod in the namespace.
+func searchDomains(namespace, clusterDomain string) []string {
+	return []string{
+		namespace + ".svc." + clusterDomain,
+		"svc." + clusterDomain,
+		clusterDomain,
+	}
+}
+
+// generateQueries returns the query file of dnsperf for the names. With searchPath, the
+// queries are those sent by a resolver with the search domains of a pod and ndots:5: every
+// search domain is tried in order until the name is found, then the name itself.
+// Otherwise only the fully qualified names are queried.
+func generateQueries(names []name, domains []string, searchPath bool) string {
+	var queries []string
+	for _, n := range names {
+		if !searchPath {
+			queries = append(queries, fmt.Sprintf("%s. A", n.fqdn))
+			continue
+		}
+
+		found := false
+		for _, domain := range domains {
+			candidate := n.short + "." + domain
+			queries = append(queries, fmt.Sprintf("%s. A", candidate))
+			if candidate == n.fqdn {
+				found = true
+				break
+			}
+		}
+		if !found {
+			queries = append(queries, fmt.Sprintf("%s. A", n.short))
+		}
+	}
+	return strings.Join(queries, "\n") + "\n"
+}
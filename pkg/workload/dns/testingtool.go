@@ -0,0 +1,199 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ToolName is the name of the testing tool of the dns workload.
+	ToolName            = "dnsperf"
+	benchmarkFQDN       = "benchmarkFQDN"
+	benchmarkSearchPath = "benchmarkSearchPath"
+
+	// optionServer is the option of a testing case setting the IP of the DNS server, it
+	// defaults to the nameserver of the testing pod, i.e. the cluster DNS service.
+	optionServer = "server"
+	// optionNodeLocalDNSCache is the option of a testing case querying NodeLocal DNSCache
+	// on its default address instead of the cluster DNS service if it is "true".
+	optionNodeLocalDNSCache = "nodeLocalDNSCache"
+	// optionClusterDomain is the option of a testing case setting the cluster domain, it
+	// defaults to cluster.local.
+	optionClusterDomain = "clusterDomain"
+
+	// defaultTimeLimit is the seconds dnsperf runs for unless the testing case sets -l or -n.
+	defaultTimeLimit = "30"
+
+	defaultClusterDomain = "cluster.local"
+	// nodeLocalDNSCacheAddress is the default link-local address of NodeLocal DNSCache.
+	nodeLocalDNSCacheAddress = "169.254.20.10"
+)
+
+// TestingCaseSet is the list of dns defined testing cases.
+var TestingCaseSet = []string{
+	"benchmarkFQDN",
+	"benchmarkSearchPath",
+}
+
+// Driver supplies the manifests and the result parser of the dnsperf testing tool, whose
+// testing cases only run a testing pod querying the DNS server of the cluster.
+type Driver struct{}
+
+// Ensure dnsperf Driver implements workload.Driver interface.
+var _ workload.Driver = &Driver{}
+
+// WorkloadPod returns no workload pod, dnsperf queries the DNS server of the cluster (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return nil, nil
+}
+
+// TestingPod returns the dnsperf pod of a testing case with the query file of the testing
+// case in its QUERIES environment variable, and the DNS server in DNS_SERVER if it is not
+// the nameserver of the pod. dnsperf runs for 30 seconds unless -l or -n is given, and always
+// prints its latency histogram (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	var searchPath bool
+	switch args.TestingName {
+	case benchmarkFQDN:
+	case benchmarkSearchPath:
+		searchPath = true
+	default:
+		return nil, errors.Errorf("unknown testing case %s of dnsperf", args.TestingName)
+	}
+
+	flags := strings.Fields(args.TestingCase.TestingToolArgs)
+	// without a limit dnsperf sends the few queries of the query file only once.
+	if !hasFlag(flags, "-l") && !hasFlag(flags, "-n") {
+		flags = append(flags, "-l", defaultTimeLimit)
+	}
+	if !strings.Contains(args.TestingCase.TestingToolArgs, "latency-histogram") {
+		flags = append(flags, "-O", "latency-histogram")
+	}
+	args.Args = workload.FomatArgs(strings.Join(flags, " "))
+	pod, err := workload.PodFromTemplate(dnsperfPod, args)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterDomain := getClusterDomain(args.TestingCase)
+	queries := generateQueries(clusterNames(args.Namespace, clusterDomain), searchDomains(args.Namespace, clusterDomain), searchPath)
+	container := &pod.Spec.Containers[0]
+	container.Env = append(container.Env, v1.EnvVar{Name: "QUERIES", Value: queries})
+	if server := getServer(args.TestingCase); server != "" {
+		container.Env = append(container.Env, v1.EnvVar{Name: "DNS_SERVER", Value: server})
+	}
+	return pod, nil
+}
+
+// ParseResults parses the statistics of dnsperf into metrics, the queries per second is the
+// headline metric (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, err := ParseDnsperf(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get queries per second")
+	}
+
+	lostPercent := 0.0
+	if r.QueriesSent > 0 {
+		lostPercent = float64(r.QueriesLost) * 100 / float64(r.QueriesSent)
+	}
+	metrics := []results.Metric{
+		{Name: "qps", Unit: "queries/sec", Value: r.QueriesPerSec},
+		{Name: "latency_avg", Unit: "ms", Value: r.LatencyAvg * 1e3, LowerIsBetter: true},
+		{Name: "latency_min", Unit: "ms", Value: r.LatencyMin * 1e3, LowerIsBetter: true},
+		{Name: "latency_max", Unit: "ms", Value: r.LatencyMax * 1e3, LowerIsBetter: true},
+		{Name: "latency_stddev", Unit: "ms", Value: r.LatencyStddev * 1e3, LowerIsBetter: true},
+	}
+	for _, p := range []struct {
+		name string
+		q    float64
+	}{{"p50", 0.5}, {"p90", 0.9}, {"p99", 0.99}, {"p99_9", 0.999}} {
+		if v, ok := r.Percentile(p.q); ok {
+			metrics = append(metrics, results.Metric{Name: "latency_" + p.name, Unit: "ms", Value: v * 1e3, LowerIsBetter: true})
+		}
+	}
+	metrics = append(metrics,
+		results.Metric{Name: "queries_sent", Unit: "queries", Value: float64(r.QueriesSent)},
+		results.Metric{Name: "queries_lost", Unit: "queries", Value: float64(r.QueriesLost), LowerIsBetter: true},
+		results.Metric{Name: "lost_percent", Unit: "%", Value: lostPercent, LowerIsBetter: true},
+		results.Metric{Name: "timeouts", Unit: "queries", Value: float64(r.Timeouts), LowerIsBetter: true},
+	)
+	var codes []string
+	for code := range r.ResponseCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		metrics = append(metrics, results.Metric{Name: "rcode_" + strings.ToLower(code), Unit: "responses", Value: float64(r.ResponseCodes[code])})
+	}
+	return metrics, nil
+}
+
+// Validate checks the options of the testing cases of a dns workload.
+func Validate(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		optionsPath := fldPath.Child("testingTool", "testingCaseSet").Index(i).Child("options")
+		if server, found := testingCase.Options[optionServer]; found && net.ParseIP(server) == nil {
+			allErrs = append(allErrs, field.Invalid(optionsPath.Child(optionServer), server, "must be an IP address"))
+		}
+		if value, found := testingCase.Options[optionNodeLocalDNSCache]; found {
+			if _, err := strconv.ParseBool(value); err != nil {
+				allErrs = append(allErrs, field.Invalid(optionsPath.Child(optionNodeLocalDNSCache), value, "must be true or false"))
+			}
+		}
+	}
+	return allErrs
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// getServer returns the IP of the DNS server of a testing case, or "" for the nameserver of the testing pod.
+func getServer(testingCase workload.TestingCase) string {
+	if server := testingCase.Options[optionServer]; server != "" {
+		return server
+	}
+	if nodeLocal, _ := strconv.ParseBool(testingCase.Options[optionNodeLocalDNSCache]); nodeLocal {
+		return nodeLocalDNSCacheAddress
+	}
+	return ""
+}
+
+// getClusterDomain returns the cluster domain of a testing case.
+func getClusterDomain(testingCase workload.TestingCase) string {
+	if domain := testingCase.Options[optionClusterDomain]; domain != "" {
+		return strings.Trim(domain, ".")
+	}
+	return defaultClusterDomain
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// FailurePolicyAbort stops the run at the first failed testing case.
+	FailurePolicyAbort = "abort"
+	// FailurePolicySkipCase records a failed testing case and continues with the next one.
+	FailurePolicySkipCase = "skip-case"
+	// FailurePolicyRetry retries a failed testing case up to N times, e.g. retry-3,
+	// and records it and continues if it still fails.
+	FailurePolicyRetry = "retry"
+
+	// testingCaseAnnotation is the annotation of the testing case on the pods of the testing case.
+	testingCaseAnnotation = "capstan-testingcase"
+	// CapstanSelector selects all the pods created by capstan.
+	CapstanSelector = "component=capstan"
+)
+
+// FailurePolicy is what to do when a testing case fails.
+type FailurePolicy struct {
+	// Action is one of FailurePolicyAbort, FailurePolicySkipCase and FailurePolicyRetry.
+	Action string
+	// Retries is the max number of retries of a failed testing case.
+	Retries int
+}
+
+// ParseFailurePolicy parses a failure policy: abort (the default if empty), skip-case or retry-N.
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch {
+	case s == "" || s == FailurePolicyAbort:
+		return FailurePolicy{Action: FailurePolicyAbort}, nil
+	case s == FailurePolicySkipCase:
+		return FailurePolicy{Action: FailurePolicySkipCase}, nil
+	case strings.HasPrefix(s, FailurePolicyRetry+"-"):
+		retries, err := strconv.Atoi(strings.TrimPrefix(s, FailurePolicyRetry+"-"))
+		if err == nil && retries > 0 {
+			return FailurePolicy{Action: FailurePolicyRetry, Retries: retries}, nil
+		}
+	}
+	return FailurePolicy{}, errors.Errorf("unknown failure policy %q, must be %s, %s or %s-N", s, FailurePolicyAbort, FailurePolicySkipCase, FailurePolicyRetry)
+}
+
+// String returns the failure policy in the form accepted by ParseFailurePolicy.
+func (p FailurePolicy) String() string {
+	if p.Action == FailurePolicyRetry {
+		return fmt.Sprintf("%s-%d", FailurePolicyRetry, p.Retries)
+	}
+	if p.Action == "" {
+		return FailurePolicyAbort
+	}
+	return p.Action
+}
+
+// RecordFailure saves the error of a failed attempt of a testing case, and the events and
+// logs of the pods of the testing case, named podNames, into dir. It must be called before
+// the pods are cleaned up, problems of collecting the events and logs are only logged.
+func RecordFailure(kubeClient kubernetes.Interface, namespace, testingCase string, podNames []string, dir string, failure error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "error.txt"), []byte(fmt.Sprintf("%+v\n", failure)), 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the pods and events are saved as well, as for every testing case.
+	if _, err := CaptureTestingCase(kubeClient, namespace, testingCase, podNames, dir); err != nil {
+		glog.Warningf("Failed to capture the pods of testing case %s: %v", testingCase, err)
+		return nil
+	}
+	pods, err := listTestingCasePods(kubeClient, namespace, testingCase, podNames)
+	if err != nil {
+		glog.Warningf("Failed to list the pods of testing case %s: %v", testingCase, err)
+		return nil
+	}
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			body, err := kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: container.Name}).Do().Raw()
+			if err != nil {
+				glog.V(4).Infof("Failed to get the log of container %s of pod %s: %v", container.Name, pod.Name, err)
+				continue
+			}
+			logfile := path.Join(dir, pod.Name+"-"+container.Name+".log")
+			if err := ioutil.WriteFile(logfile, body, 0644); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+func TestParseFailurePolicy(t *testing.T) {
+	tests := []struct {
+		s      string
+		policy FailurePolicy
+		err    bool
+	}{
+		{"", FailurePolicy{Action: FailurePolicyAbort}, false},
+		{"abort", FailurePolicy{Action: FailurePolicyAbort}, false},
+		{"skip-case", FailurePolicy{Action: FailurePolicySkipCase}, false},
+		{"retry-1", FailurePolicy{Action: FailurePolicyRetry, Retries: 1}, false},
+		{"retry-10", FailurePolicy{Action: FailurePolicyRetry, Retries: 10}, false},
+		{"retry-0", FailurePolicy{}, true},
+		{"retry--1", FailurePolicy{}, true},
+		{"retry-", FailurePolicy{}, true},
+		{"retry-x", FailurePolicy{}, true},
+		{"retry", FailurePolicy{}, true},
+		{"retry-3 ", FailurePolicy{}, true},
+		{"Abort", FailurePolicy{}, true},
+		{"skip", FailurePolicy{}, true},
+	}
+	for _, test := range tests {
+		policy, err := ParseFailurePolicy(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("ParseFailurePolicy(%q): error %v, want error %v", test.s, err, test.err)
+			continue
+		}
+		if policy != test.policy {
+			t.Errorf("ParseFailurePolicy(%q) = %+v, want %+v", test.s, policy, test.policy)
+		}
+		// The parsed policies are printed in the form they are parsed from.
+		if err == nil {
+			if again, err := ParseFailurePolicy(policy.String()); err != nil || again != policy {
+				t.Errorf("ParseFailurePolicy(%q) = %+v, %v, want %+v", policy.String(), again, err, policy)
+			}
+		}
+	}
+}
+
+type fakeWorkload struct {
+	Interface
+	name string
+}
+
+func (w *fakeWorkload) GetName() string {
+	return w.name
+}
+
+// fakeTool is a testing tool whose attempts fail with the errors in turn, and succeed
+// once the errors run out.
+type fakeTool struct {
+	Tool
+	errs []error
+	// cancel cancels the ctx during the attempt numbered cancelled, if it is not 0.
+	cancel    context.CancelFunc
+	cancelled int
+	// cleanupErr is the error of cleaning up a succeeded attempt.
+	cleanupErr error
+
+	attempts int
+	cleanups int
+}
+
+func (f *fakeTool) Run(ctx context.Context, kubeClient kubernetes.Interface, testingCase TestingCase) error {
+	f.attempts++
+	return nil
+}
+
+func (f *fakeTool) GetTestingResults(ctx context.Context, kubeClient kubernetes.Interface) error {
+	if f.attempts == f.cancelled {
+		f.cancel()
+		return ctx.Err()
+	}
+	if f.attempts <= len(f.errs) {
+		return f.errs[f.attempts-1]
+	}
+	return nil
+}
+
+func (f *fakeTool) Cleanup(ctx context.Context, kubeClient kubernetes.Interface) error {
+	f.cleanups++
+	if f.attempts > len(f.errs) {
+		return f.cleanupErr
+	}
+	return nil
+}
+
+func (f *fakeTool) GetName() string {
+	return "wrk"
+}
+
+func TestRunTestingCase(t *testing.T) {
+	failure := errors.New("no results")
+	tests := []struct {
+		name       string
+		policy     string
+		errs       []error
+		cancelled  int
+		cleanupErr error
+		// err is a part of the error returned, none if it is empty.
+		err      string
+		attempts int
+		state    results.State
+		// failures are the attempts recorded in the failure ledger.
+		failures []int
+	}{
+		{name: "succeeded", policy: "abort", attempts: 1, state: results.StateSucceeded},
+		{name: "abort", policy: "abort", errs: []error{failure}, err: "no results", attempts: 1, state: results.StateFailed, failures: []int{1}},
+		{name: "skip-case", policy: "skip-case", errs: []error{failure}, attempts: 1, state: results.StateFailed, failures: []int{1}},
+		{name: "retried", policy: "retry-2", errs: []error{failure}, attempts: 2, state: results.StateSucceeded, failures: []int{1}},
+		{name: "retries exhausted", policy: "retry-2", errs: []error{failure, failure, failure}, attempts: 3, state: results.StateFailed, failures: []int{1, 2, 3}},
+		// A cancelled testing case is neither retried nor recorded as a failure.
+		{name: "cancelled", policy: "retry-2", errs: []error{failure}, cancelled: 2, err: "aborted", attempts: 2, state: results.StateAborted, failures: []int{1}},
+		{name: "cleanup failed", policy: "skip-case", cleanupErr: errors.New("pod stuck"), attempts: 1, state: results.StateFailed, failures: []int{1}},
+	}
+
+	dir, err := ioutil.TempDir("", "capstan-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range tests {
+		policy, err := ParseFailurePolicy(test.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		tool := &fakeTool{errs: test.errs, cancel: cancel, cancelled: test.cancelled, cleanupErr: test.cleanupErr}
+		w := &fakeWorkload{name: "nginx"}
+		testingCase := TestingCase{Name: "benchmarkSameNode"}
+		opts := RunOptions{Namespace: "capstan", FailurePolicy: policy, ResultsDir: path.Join(dir, test.name)}
+		results.DefaultStore.Init("uuid", "test")
+		results.DefaultStore.AddWorkload(w.name, "nginx", tool.GetName(), 1, []string{testingCase.Name})
+
+		err = RunTestingCase(ctx, &fakeClient{}, w, tool, testingCase, 1, opts)
+		cancel()
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: error %v, want an error containing %q", test.name, err, test.err)
+		}
+		if tool.attempts != test.attempts || tool.cleanups != test.attempts {
+			t.Errorf("%s: %d attempts and %d cleanups, want %d", test.name, tool.attempts, tool.cleanups, test.attempts)
+		}
+
+		run := results.DefaultStore.Snapshot()
+		repeat := run.Workloads[0].TestingCases[0].Repeats[0]
+		if repeat.State != test.state || repeat.Attempts != test.attempts {
+			t.Errorf("%s: repeat %s after %d attempts, want %s after %d", test.name, repeat.State, repeat.Attempts, test.state, test.attempts)
+		}
+		var failures []int
+		for _, f := range run.Failures {
+			failures = append(failures, f.Attempt)
+			// The error of every failed attempt is saved in its directory.
+			if _, err := os.Stat(path.Join(opts.ResultsDir, f.Dir, "error.txt")); err != nil {
+				t.Errorf("%s: the failure of attempt %d is not saved: %v", test.name, f.Attempt, err)
+			}
+		}
+		if !reflect.DeepEqual(failures, test.failures) {
+			t.Errorf("%s: failed attempts %v, want %v", test.name, failures, test.failures)
+		}
+	}
+}
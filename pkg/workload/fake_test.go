@@ -0,0 +1,144 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+// fakeClient is a kubernetes.Interface serving the pods and events of the tests, as the
+// apiserver does with the label and field selectors of the lists. Calling the other methods
+// of the clients panics.
+type fakeClient struct {
+	kubernetes.Interface
+	pods   []v1.Pod
+	events []v1.Event
+	// watch returns the watchers of the pods in turn, it is called with the options of the watch.
+	watch func(opts apismetav1.ListOptions) (watch.Interface, error)
+	// logs returns the followed log of a pod.
+	logs func(name string) io.ReadCloser
+}
+
+func (c *fakeClient) CoreV1() corev1.CoreV1Interface {
+	return &fakeCore{client: c}
+}
+
+type fakeCore struct {
+	corev1.CoreV1Interface
+	client *fakeClient
+}
+
+func (c *fakeCore) Pods(namespace string) corev1.PodInterface {
+	return &fakePods{client: c.client}
+}
+
+func (c *fakeCore) Events(namespace string) corev1.EventInterface {
+	return &fakeEvents{client: c.client}
+}
+
+type fakePods struct {
+	corev1.PodInterface
+	client *fakeClient
+}
+
+func (p *fakePods) Get(name string, options apismetav1.GetOptions) (*v1.Pod, error) {
+	for i := range p.client.pods {
+		if p.client.pods[i].Name == name {
+			pod := p.client.pods[i]
+			return &pod, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("pods"), name)
+}
+
+func (p *fakePods) List(opts apismetav1.ListOptions) (*v1.PodList, error) {
+	labelSelector, fieldSelector, err := parseSelectors(opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.PodList{}
+	for _, pod := range p.client.pods {
+		if labelSelector.Matches(labels.Set(pod.Labels)) && fieldSelector.Matches(fields.Set{"metadata.name": pod.Name}) {
+			list.Items = append(list.Items, pod)
+		}
+	}
+	return list, nil
+}
+
+func (p *fakePods) Watch(opts apismetav1.ListOptions) (watch.Interface, error) {
+	return p.client.watch(opts)
+}
+
+func (p *fakePods) GetLogs(name string, opts *v1.PodLogOptions) *rest.Request {
+	client := fakeHTTPClient(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: p.client.logs(name)}, nil
+	})
+	return rest.NewRequest(client, "GET", &url.URL{Scheme: "http", Host: "apiserver"}, "", rest.ContentConfig{}, rest.Serializers{}, nil, nil)
+}
+
+// fakeHTTPClient serves the requests of the rest client.
+type fakeHTTPClient func(req *http.Request) (*http.Response, error)
+
+func (f fakeHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type fakeEvents struct {
+	corev1.EventInterface
+	client *fakeClient
+}
+
+func (e *fakeEvents) List(opts apismetav1.ListOptions) (*v1.EventList, error) {
+	_, fieldSelector, err := parseSelectors(opts)
+	if err != nil {
+		return nil, err
+	}
+	list := &v1.EventList{}
+	for _, event := range e.client.events {
+		if fieldSelector.Matches(fields.Set{
+			"involvedObject.name": event.InvolvedObject.Name,
+			"involvedObject.uid":  string(event.InvolvedObject.UID),
+		}) {
+			list.Items = append(list.Items, event)
+		}
+	}
+	return list, nil
+}
+
+func parseSelectors(opts apismetav1.ListOptions) (labels.Selector, fields.Selector, error) {
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, nil, err
+	}
+	return labelSelector, fieldSelector, nil
+}
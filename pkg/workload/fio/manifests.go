@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fio
+
+const (
+	fioPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Name }}
+  annotations:
+    capstan-testing: fio
+    capstan-testingcase: {{ .TestingName }}
+  labels:
+    component: capstan
+    testing: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  containers:
+  - name: testing-fio
+    image: {{ .Image }}
+    imagePullPolicy: Always
+    args: [{{ .Args }}]
+    volumeMounts:
+    - name: data
+      mountPath: /data
+  volumes:
+  - name: data
+    persistentVolumeClaim:
+      claimName: {{ .WorkloadName }}
+  restartPolicy: Never
+  tolerations:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/master
+    operator: Exists
+  - key: CriticalAddonsOnly
+    operator: Exists
+`
+)
@@ -0,0 +1,108 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fio
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the part of the JSON output of fio --output-format=json which capstan uses.
+type Result struct {
+	Version string `json:"fio version"`
+	Jobs    []Job  `json:"jobs"`
+}
+
+// Job is the result of a job, or of all jobs with --group_reporting.
+type Job struct {
+	Name  string `json:"jobname"`
+	Error int    `json:"error"`
+	Read  IO     `json:"read"`
+	Write IO     `json:"write"`
+	// Sync is the fsync latency, it is only reported by fio 3.5 and later.
+	Sync struct {
+		Lat      Latency `json:"lat_ns"`
+		TotalIOs int64   `json:"total_ios"`
+	} `json:"sync"`
+}
+
+// IO is the result of the reads or the writes of a job.
+type IO struct {
+	IOBytes int64 `json:"io_bytes"`
+	// BwBytes is the bandwidth in bytes per second.
+	BwBytes int64   `json:"bw_bytes"`
+	IOPS    float64 `json:"iops"`
+	// ClatNs is the completion latency in nanoseconds, fio 2 reports it as Clat in microseconds.
+	ClatNs *Latency `json:"clat_ns,omitempty"`
+	Clat   *Latency `json:"clat,omitempty"`
+}
+
+// Latency is the distribution of a latency.
+type Latency struct {
+	Mean float64 `json:"mean"`
+	// Percentile maps the percentiles, e.g. "99.000000", to the latency.
+	Percentile map[string]float64 `json:"percentile,omitempty"`
+}
+
+// CompletionLatency returns the completion latency in milliseconds, and whether fio reported it.
+func (io IO) CompletionLatency() (Latency, bool) {
+	switch {
+	case io.ClatNs != nil:
+		return io.ClatNs.scaled(1e-6), true
+	case io.Clat != nil:
+		return io.Clat.scaled(1e-3), true
+	}
+	return Latency{}, false
+}
+
+// scaled returns the latency multiplied by the factor.
+func (l Latency) scaled(factor float64) Latency {
+	scaled := Latency{Mean: l.Mean * factor, Percentile: map[string]float64{}}
+	for p, v := range l.Percentile {
+		scaled.Percentile[p] = v * factor
+	}
+	return scaled
+}
+
+// ParseResult parses the JSON output of fio in a log, the log may have other lines before
+// the JSON document, e.g. warnings, and after it, e.g. the TestingDoneMark.
+func ParseResult(data []byte) (*Result, []byte, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, nil, errors.Errorf("results not contain the JSON output of fio")
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&raw); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid JSON output of fio")
+	}
+	r := &Result{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid JSON output of fio")
+	}
+	if len(r.Jobs) == 0 {
+		return nil, nil, errors.Errorf("results not contain the jobs of fio")
+	}
+	for _, job := range r.Jobs {
+		if job.Error != 0 {
+			return nil, nil, errors.Errorf("fio job %s has failed with error %d", job.Name, job.Error)
+		}
+	}
+	return r, raw, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func metricValues(metrics []results.Metric) map[string]float64 {
+	values := map[string]float64{}
+	for _, m := range metrics {
+		values[m.Name] = m.Value
+	}
+	return values
+}
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		file        string
+		testingCase string
+		headline    results.Metric
+		want        map[string]float64
+		absent      []string
+	}{
+		{
+			file:        "randread.json",
+			testingCase: benchmarkRandRead,
+			headline:    results.Metric{Name: "iops", Unit: "IOPS", Value: 3003.635},
+			want: map[string]float64{
+				"read_iops":       3003.635,
+				"read_bandwidth":  12302886.0 / (1 << 20),
+				"read_clat_mean":  10.646183512,
+				"read_clat_p50":   10.420224,
+				"read_clat_p99":   19.00544,
+				"read_clat_p99_9": 39.059456,
+			},
+			absent: []string{"write_iops", "fsync_lat_mean"},
+		},
+		{
+			file:        "fsync.json",
+			testingCase: benchmarkFsyncLatency,
+			headline:    results.Metric{Name: "fsync_latency", Unit: "ms", Value: 26.083328, LowerIsBetter: true},
+			want: map[string]float64{
+				"write_iops":      88.296,
+				"write_clat_mean": 0.024551331,
+				"write_clat_p99":  0.072192,
+				"fsync_lat_mean":  11.256773412,
+				"fsync_lat_p90":   15.663104,
+				"fsync_lat_p99":   26.083328,
+			},
+			absent: []string{"read_iops"},
+		},
+	}
+	for _, test := range tests {
+		metrics, err := (&Driver{}).ParseResults(workload.TestingCase{Name: test.testingCase}, readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		headline := metrics[0]
+		if headline.Name != test.headline.Name || headline.Unit != test.headline.Unit ||
+			headline.LowerIsBetter != test.headline.LowerIsBetter || math.Abs(headline.Value-test.headline.Value) > 1e-9 {
+			t.Errorf("%s: headline metric %+v, want %+v", test.file, headline, test.headline)
+		}
+		values := metricValues(metrics)
+		for name, want := range test.want {
+			if got, found := values[name]; !found || math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: %s = %v (found %v), want %v", test.file, name, got, found, want)
+			}
+		}
+		for _, name := range test.absent {
+			if _, found := values[name]; found {
+				t.Errorf("%s: unexpected metric %s", test.file, name)
+			}
+		}
+	}
+}
+
+func TestFsyncLatencyWithoutSync(t *testing.T) {
+	// fio before 3.5 reports no fsync latency, the write latency includes it.
+	data := bytes.Replace(readTestdata(t, "fsync.json"), []byte(`"total_ios" : 5297`), []byte(`"total_ios" : 0`), 1)
+	metrics, err := (&Driver{}).ParseResults(workload.TestingCase{Name: benchmarkFsyncLatency}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics[0].Name != "fsync_latency" || math.Abs(metrics[0].Value-0.072192) > 1e-9 {
+		t.Errorf("headline metric %+v, want fsync_latency of the write latency 0.072192", metrics[0])
+	}
+
+	// a read job has no latency of fsync or of writes.
+	if _, err := (&Driver{}).ParseResults(workload.TestingCase{Name: benchmarkFsyncLatency}, readTestdata(t, "randread.json")); err == nil {
+		t.Errorf("the fsync latency of a read job succeeded")
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	r, raw, err := ParseResult(readTestdata(t, "fsync.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != "fio-3.33" || len(r.Jobs) != 1 || r.Jobs[0].Name != "benchmarkFsyncLatency" {
+		t.Errorf("result %+v, want the benchmarkFsyncLatency job of fio-3.33", r)
+	}
+	if !bytes.HasPrefix(raw, []byte("{")) || !bytes.HasSuffix(raw, []byte("}")) {
+		t.Errorf("raw output is not the JSON document")
+	}
+
+	tests := []struct {
+		name string
+		log  []byte
+		err  string
+	}{
+		{"job error", readTestdata(t, "error.json"), "benchmarkSeqWrite has failed with error 28"},
+		{"no JSON", []byte("fio: failed to create /data: Permission denied\n"), "not contain the JSON output"},
+		{"no jobs", []byte(`{"fio version" : "fio-3.33", "jobs" : []}`), "not contain the jobs"},
+	}
+	for _, test := range tests {
+		_, _, err := ParseResult(test.log)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
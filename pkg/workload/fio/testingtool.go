@@ -0,0 +1,216 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fio
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ToolName is the name of the testing tool of the fio workload.
+	ToolName              = "fio"
+	benchmarkRandRead     = "benchmarkRandRead"
+	benchmarkRandWrite    = "benchmarkRandWrite"
+	benchmarkSeqRead      = "benchmarkSeqRead"
+	benchmarkSeqWrite     = "benchmarkSeqWrite"
+	benchmarkMixedRandRW  = "benchmarkMixedRandRW"
+	benchmarkFsyncLatency = "benchmarkFsyncLatency"
+
+	// optionStorageSize is the option of a testing case setting the size of its persistent
+	// volume claim, it defaults to defaultStorageSize.
+	optionStorageSize = "storageSize"
+	// optionStorageClass is the option of a testing case setting the storage class of its
+	// persistent volume claim, it defaults to the default storage class.
+	optionStorageClass = "storageClass"
+	defaultStorageSize = "10Gi"
+
+	// ResultFile is the JSON results of fio in the results directory of a testing case.
+	ResultFile = "fio.json"
+)
+
+// TestingCaseSet is the list of fio defined testing cases.
+var TestingCaseSet = []string{
+	"benchmarkRandRead",
+	"benchmarkRandWrite",
+	"benchmarkSeqRead",
+	"benchmarkSeqWrite",
+	"benchmarkMixedRandRW",
+	"benchmarkFsyncLatency",
+}
+
+// profiles are the fio job options of the testing cases, the testingToolArgs of a testing
+// case come after them and override them.
+var profiles = map[string][]string{
+	benchmarkRandRead:     {"--rw=randread", "--bs=4k", "--iodepth=32", "--ioengine=libaio", "--direct=1"},
+	benchmarkRandWrite:    {"--rw=randwrite", "--bs=4k", "--iodepth=32", "--ioengine=libaio", "--direct=1"},
+	benchmarkSeqRead:      {"--rw=read", "--bs=1M", "--iodepth=16", "--ioengine=libaio", "--direct=1"},
+	benchmarkSeqWrite:     {"--rw=write", "--bs=1M", "--iodepth=16", "--ioengine=libaio", "--direct=1"},
+	benchmarkMixedRandRW:  {"--rw=randrw", "--rwmixread=70", "--bs=4k", "--iodepth=32", "--ioengine=libaio", "--direct=1"},
+	benchmarkFsyncLatency: {"--rw=write", "--bs=4k", "--iodepth=1", "--ioengine=sync", "--fsync=1"},
+}
+
+// percentiles are the clat percentiles of fio published as metrics.
+var percentiles = []struct{ key, name string }{
+	{"50.000000", "p50"},
+	{"90.000000", "p90"},
+	{"99.000000", "p99"},
+	{"99.900000", "p99_9"},
+}
+
+// Driver supplies the manifests and the result parser of the fio testing tool, whose testing
+// cases only run a testing pod mounting a persistent volume claim.
+type Driver struct{}
+
+// Ensure fio Driver implements workload.Driver, workload.VolumeClaimer and workload.ResultsWriter interfaces.
+var (
+	_ workload.Driver        = &Driver{}
+	_ workload.VolumeClaimer = &Driver{}
+	_ workload.ResultsWriter = &Driver{}
+)
+
+// WorkloadPod returns no workload pod, fio runs against the volume of the testing pod (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return nil, nil
+}
+
+// VolumeClaim returns the persistent volume claim of a testing case (to adhere to workload.VolumeClaimer interface).
+func (d *Driver) VolumeClaim(args workload.PodArgs) (*v1.PersistentVolumeClaim, error) {
+	return workload.NewPersistentVolumeClaim(args.TestingCase.Options[optionStorageClass], getStorageSize(args.TestingCase))
+}
+
+// TestingPod returns the fio pod of a testing case, which runs the job profile of the testing
+// case in the volume with its results as JSON (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	profile, found := profiles[args.TestingName]
+	if !found {
+		return nil, errors.Errorf("unknown testing case %s of fio", args.TestingName)
+	}
+
+	flags := []string{"--name=" + args.TestingName, "--directory=/data", "--size=1G"}
+	flags = append(flags, profile...)
+	flags = append(flags, strings.Fields(args.TestingCase.TestingToolArgs)...)
+	flags = append(flags, "--group_reporting", "--output-format=json")
+	args.Args = workload.FomatArgs(strings.Join(flags, " "))
+	return workload.PodFromTemplate(fioPod, args)
+}
+
+// ParseResults parses the JSON results of fio into metrics. The headline metric is the IOPS
+// of the random testing cases, the bandwidth of the sequential ones and the 99th percentile
+// fsync latency of the fsync one (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, _, err := ParseResult(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse the results of fio")
+	}
+	job := r.Jobs[0]
+
+	var metrics []results.Metric
+	for _, rw := range []struct {
+		name  string
+		stats IO
+	}{{"read", job.Read}, {"write", job.Write}} {
+		if rw.stats.IOBytes == 0 {
+			continue
+		}
+		metrics = append(metrics,
+			results.Metric{Name: rw.name + "_iops", Unit: "IOPS", Value: rw.stats.IOPS},
+			results.Metric{Name: rw.name + "_bandwidth", Unit: "MiB/sec", Value: float64(rw.stats.BwBytes) / (1 << 20)},
+		)
+		if clat, ok := rw.stats.CompletionLatency(); ok {
+			metrics = append(metrics, latencyMetrics(rw.name+"_clat", clat)...)
+		}
+	}
+	if job.Sync.TotalIOs > 0 {
+		metrics = append(metrics, latencyMetrics("fsync_lat", job.Sync.Lat.scaled(1e-6))...)
+	}
+
+	headline, err := headlineMetric(testingCase.Name, job, metrics)
+	if err != nil {
+		return nil, err
+	}
+	return append([]results.Metric{headline}, metrics...), nil
+}
+
+// WriteResults writes the JSON results of fio to the results directory of the testing
+// case (to adhere to workload.ResultsWriter interface).
+func (d *Driver) WriteResults(testingCase workload.TestingCase, log []byte, dir string) error {
+	_, raw, err := ParseResult(log)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse the results of fio")
+	}
+	return errors.WithStack(ioutil.WriteFile(path.Join(dir, ResultFile), raw, 0644))
+}
+
+// Validate checks the options of the testing cases of a fio workload.
+func Validate(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		if _, err := workload.NewPersistentVolumeClaim("", getStorageSize(testingCase)); err != nil {
+			optionPath := fldPath.Child("testingTool", "testingCaseSet").Index(i).Child("options", optionStorageSize)
+			allErrs = append(allErrs, field.Invalid(optionPath, testingCase.Options[optionStorageSize], "must be a quantity, e.g. 10Gi"))
+		}
+	}
+	return allErrs
+}
+
+// headlineMetric returns the headline metric of a testing case.
+func headlineMetric(testingCase string, job Job, metrics []results.Metric) (results.Metric, error) {
+	switch testingCase {
+	case benchmarkRandRead, benchmarkRandWrite, benchmarkMixedRandRW:
+		return results.Metric{Name: "iops", Unit: "IOPS", Value: job.Read.IOPS + job.Write.IOPS}, nil
+	case benchmarkSeqRead, benchmarkSeqWrite:
+		return results.Metric{Name: "bandwidth", Unit: "MiB/sec", Value: float64(job.Read.BwBytes+job.Write.BwBytes) / (1 << 20)}, nil
+	case benchmarkFsyncLatency:
+		// fio before 3.5 does not report the fsync latency, the write latency includes it.
+		for _, name := range []string{"fsync_lat_p99", "write_clat_p99"} {
+			for _, m := range metrics {
+				if m.Name == name {
+					return results.Metric{Name: "fsync_latency", Unit: "ms", Value: m.Value, LowerIsBetter: true}, nil
+				}
+			}
+		}
+		return results.Metric{}, errors.Errorf("results not contain the fsync latency")
+	}
+	return results.Metric{}, errors.Errorf("unknown testing case %s of fio", testingCase)
+}
+
+// latencyMetrics returns the mean and the percentiles of a latency in milliseconds.
+func latencyMetrics(name string, lat Latency) []results.Metric {
+	metrics := []results.Metric{{Name: name + "_mean", Unit: "ms", Value: lat.Mean, LowerIsBetter: true}}
+	for _, p := range percentiles {
+		if v, found := lat.Percentile[p.key]; found {
+			metrics = append(metrics, results.Metric{Name: name + "_" + p.name, Unit: "ms", Value: v, LowerIsBetter: true})
+		}
+	}
+	return metrics
+}
+
+// getStorageSize returns the size of the persistent volume claim of a testing case.
+func getStorageSize(testingCase workload.TestingCase) string {
+	if size := testingCase.Options[optionStorageSize]; size != "" {
+		return size
+	}
+	return defaultStorageSize
+}
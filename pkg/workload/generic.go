@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ReadinessRunning is met when the server pod is running and has an IP.
+	ReadinessRunning = "running"
+	// ReadinessReady is met when the server pod is ready, i.e. its readiness probes have passed.
+	ReadinessReady = "ready"
+)
+
+// GenericWorkload is the definition of a workload of the generic type, which is specified
+// entirely in the capstan config: a server pod is started and waited for, then a client
+// pod is started, and the metrics are extracted from the log of the client pod once it
+// prints the TestingDoneMark.
+type GenericWorkload struct {
+	// Server is the pod spec of the server pod, the image of its first container defaults
+	// to the image of the workload, and its args to the workloadArgs of the testing case.
+	Server v1.PodSpec `json:"server"`
+	// Readiness is the condition of the server pod before the client pod starts,
+	// ReadinessRunning (the default) or ReadinessReady.
+	Readiness string `json:"readiness,omitempty"`
+	// Client is the pod spec of the client pod, which has a single container. Its image
+	// defaults to the image of the testing tool, and its args to the testingToolArgs of the
+	// testing case. The IP of the server pod is set in its ENDPOINT environment variable.
+	Client v1.PodSpec `json:"client"`
+	// Metrics are extracted from the log of the client pod, the first one is the headline metric.
+	Metrics []MetricRegex `json:"metrics"`
+}
+
+// MetricRegex extracts a metric from the log of a testing pod.
+type MetricRegex struct {
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+	// Regex is matched against the log, the last match is used.
+	Regex string `json:"regex"`
+	// Group is the capture group of the regex which is the value of the metric, it defaults to 1.
+	Group         int  `json:"group,omitempty"`
+	LowerIsBetter bool `json:"lowerIsBetter,omitempty"`
+}
+
+// GetGroup returns the capture group of the regex which is the value of the metric.
+func (m MetricRegex) GetGroup() int {
+	if m.Group > 0 {
+		return m.Group
+	}
+	return 1
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"regexp"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WorkloadName is the type of the generic workloads in the capstan config.
+const WorkloadName = "generic"
+
+func init() {
+	workload.Register(WorkloadName, workload.Factory{
+		New: func(wl workload.Workload, opts workload.RunOptions) workload.Interface {
+			return NewWorkload(wl, opts)
+		},
+		Validate: Validate,
+	})
+}
+
+// NewWorkload creates a new generic workload from the given workload definition,
+// whose testing cases run with the options.
+func NewWorkload(wl workload.Workload, opts workload.RunOptions) *workload.Runner {
+	return workload.NewRunner(wl, opts, &Driver{Spec: wl.Generic})
+}
+
+// Validate checks the generic section of a generic workload.
+func Validate(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	genericPath := fldPath.Child("generic")
+	if wl.Generic == nil {
+		return append(allErrs, field.Required(genericPath, "the pods and metrics of a generic workload are required"))
+	}
+
+	if len(wl.Generic.Server.Containers) == 0 {
+		allErrs = append(allErrs, field.Required(genericPath.Child("server", "containers"), ""))
+	}
+	if len(wl.Generic.Client.Containers) != 1 {
+		allErrs = append(allErrs, field.Invalid(genericPath.Child("client", "containers"), len(wl.Generic.Client.Containers), "must have exactly one container"))
+	}
+	switch wl.Generic.Readiness {
+	case "", workload.ReadinessRunning, workload.ReadinessReady:
+	default:
+		allErrs = append(allErrs, field.NotSupported(genericPath.Child("readiness"), wl.Generic.Readiness, []string{workload.ReadinessRunning, workload.ReadinessReady}))
+	}
+
+	metricsPath := genericPath.Child("metrics")
+	if len(wl.Generic.Metrics) == 0 {
+		allErrs = append(allErrs, field.Required(metricsPath, "at least one metric is required"))
+	}
+	for i, metric := range wl.Generic.Metrics {
+		if metric.Name == "" {
+			allErrs = append(allErrs, field.Required(metricsPath.Index(i).Child("name"), ""))
+		}
+		regexPath := metricsPath.Index(i).Child("regex")
+		if metric.Regex == "" {
+			allErrs = append(allErrs, field.Required(regexPath, ""))
+			continue
+		}
+		re, err := regexp.Compile(metric.Regex)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(regexPath, metric.Regex, err.Error()))
+			continue
+		}
+		if metric.Group < 0 || metric.GetGroup() > re.NumSubexp() {
+			allErrs = append(allErrs, field.Invalid(metricsPath.Index(i).Child("group"), metric.Group, "must be a capture group of the regex"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,206 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const clientLog = `warming up
+qps: 100 latency: 2.5ms
+qps: 120 latency: 2.1ms
+qps: 130 latency: 1.9ms
+TestingDoneMark
+`
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric workload.MetricRegex
+		value  float64
+		err    bool
+	}{
+		// The last match is used, e.g. the final report of a tool printing progress.
+		{"last match", workload.MetricRegex{Name: "qps", Regex: `qps: (\d+)`}, 130, false},
+		{"capture group", workload.MetricRegex{Name: "latency", Regex: `qps: (\d+) latency: ([\d.]+)ms`, Group: 2}, 1.9, false},
+		{"no match", workload.MetricRegex{Name: "errors", Regex: `errors: (\d+)`}, 0, true},
+		{"not a number", workload.MetricRegex{Name: "unit", Regex: `latency: [\d.]+(ms)`}, 0, true},
+		{"invalid regex", workload.MetricRegex{Name: "qps", Regex: `qps: (\d+`}, 0, true},
+	}
+	for _, test := range tests {
+		value, err := extract(test.metric, []byte(clientLog))
+		if (err != nil) != test.err {
+			t.Errorf("%s: error %v, want error %v", test.name, err, test.err)
+			continue
+		}
+		if value != test.value {
+			t.Errorf("%s: value %v, want %v", test.name, value, test.value)
+		}
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	d := &Driver{Spec: &workload.GenericWorkload{Metrics: []workload.MetricRegex{
+		{Name: "qps", Unit: "requests/sec", Regex: `qps: (\d+)`},
+		{Name: "latency", Unit: "ms", Regex: `latency: ([\d.]+)ms`, LowerIsBetter: true},
+	}}}
+	metrics, err := d.ParseResults(workload.TestingCase{}, []byte(clientLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []results.Metric{
+		{Name: "qps", Unit: "requests/sec", Value: 130},
+		{Name: "latency", Unit: "ms", Value: 1.9, LowerIsBetter: true},
+	}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("metrics %+v, want %+v", metrics, want)
+	}
+
+	// A testing case fails if any of its metrics is missing.
+	d.Spec.Metrics = append(d.Spec.Metrics, workload.MetricRegex{Name: "errors", Regex: `errors: (\d+)`})
+	if _, err := d.ParseResults(workload.TestingCase{}, []byte(clientLog)); err == nil || !strings.Contains(err.Error(), "errors") {
+		t.Errorf("parsing the results without the errors metric: %v, want an error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *workload.GenericWorkload {
+		return &workload.GenericWorkload{
+			Server:  v1.PodSpec{Containers: []v1.Container{{Name: "server"}}},
+			Client:  v1.PodSpec{Containers: []v1.Container{{Name: "client"}}},
+			Metrics: []workload.MetricRegex{{Name: "qps", Regex: `qps: (\d+)`}},
+		}
+	}
+	tests := []struct {
+		name string
+		edit func(spec *workload.GenericWorkload) *workload.GenericWorkload
+		errs []string
+	}{
+		{"valid", func(spec *workload.GenericWorkload) *workload.GenericWorkload { return spec }, nil},
+		{"missing", func(spec *workload.GenericWorkload) *workload.GenericWorkload { return nil }, []string{"Workloads[0].generic"}},
+		{"no server container", func(spec *workload.GenericWorkload) *workload.GenericWorkload {
+			spec.Server.Containers = nil
+			return spec
+		}, []string{"Workloads[0].generic.server.containers"}},
+		{"two client containers", func(spec *workload.GenericWorkload) *workload.GenericWorkload {
+			spec.Client.Containers = append(spec.Client.Containers, v1.Container{Name: "sidecar"})
+			return spec
+		}, []string{"Workloads[0].generic.client.containers"}},
+		{"readiness", func(spec *workload.GenericWorkload) *workload.GenericWorkload {
+			spec.Readiness = "started"
+			return spec
+		}, []string{"Workloads[0].generic.readiness"}},
+		{"no metrics", func(spec *workload.GenericWorkload) *workload.GenericWorkload {
+			spec.Metrics = nil
+			return spec
+		}, []string{"Workloads[0].generic.metrics"}},
+		{"invalid metrics", func(spec *workload.GenericWorkload) *workload.GenericWorkload {
+			spec.Metrics = []workload.MetricRegex{
+				{Regex: `qps: (\d+)`},
+				{Name: "latency"},
+				{Name: "latency", Regex: `latency: (\d+`},
+				{Name: "latency", Regex: `latency: (\d+)`, Group: 2},
+				{Name: "latency", Regex: `latency: (\d+)`, Group: -1},
+			}
+			return spec
+		}, []string{
+			"Workloads[0].generic.metrics[0].name",
+			"Workloads[0].generic.metrics[1].regex",
+			"Workloads[0].generic.metrics[2].regex",
+			"Workloads[0].generic.metrics[3].group",
+			"Workloads[0].generic.metrics[4].group",
+		}},
+	}
+	for _, test := range tests {
+		wl := workload.Workload{Name: "echo", Type: WorkloadName, Generic: test.edit(valid())}
+		var errs []string
+		for _, err := range Validate(field.NewPath("Workloads").Index(0), wl) {
+			errs = append(errs, err.Field)
+		}
+		if !reflect.DeepEqual(errs, test.errs) {
+			t.Errorf("%s: errors on %q, want %q", test.name, errs, test.errs)
+		}
+	}
+}
+
+func TestTestingPod(t *testing.T) {
+	d := &Driver{Spec: &workload.GenericWorkload{
+		Client: v1.PodSpec{Containers: []v1.Container{{Name: "client", Args: []string{"-d", "10"}}}},
+	}}
+	tests := []struct {
+		placement    string
+		affinity     bool
+		antiAffinity bool
+	}{
+		{"", false, false},
+		{workload.PlacementSameNode, true, false},
+		{workload.PlacementDifferentNode, false, true},
+	}
+	for _, test := range tests {
+		pod, err := d.TestingPod(workload.PodArgs{
+			Name:         "capstan-echo-client-samenode-testing",
+			TestingName:  "sameNode",
+			Image:        "client:1.0",
+			WorkloadName: "capstan-echo-samenode-workload",
+			PodIP:        "10.0.0.1",
+			TestingCase:  workload.TestingCase{Name: "sameNode", Placement: test.placement},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		container := pod.Spec.Containers[0]
+		if container.Image != "client:1.0" || !reflect.DeepEqual(container.Args, []string{"-d", "10"}) {
+			t.Errorf("%q: image %q and args %q, want the image of the testing tool and the args of the spec", test.placement, container.Image, container.Args)
+		}
+		if !reflect.DeepEqual(container.Env, []v1.EnvVar{{Name: "ENDPOINT", Value: "10.0.0.1"}}) {
+			t.Errorf("%q: env %v, want the ENDPOINT of the server pod", test.placement, container.Env)
+		}
+		if pod.Labels["testing"] != pod.Name || pod.Spec.RestartPolicy != v1.RestartPolicyNever {
+			t.Errorf("%q: labels %v and restart policy %q", test.placement, pod.Labels, pod.Spec.RestartPolicy)
+		}
+
+		var terms []v1.PodAffinityTerm
+		affinity := pod.Spec.Affinity
+		if hasAffinity := affinity != nil && affinity.PodAffinity != nil; hasAffinity != test.affinity {
+			t.Errorf("%q: pod affinity %v, want %v", test.placement, hasAffinity, test.affinity)
+		} else if hasAffinity {
+			terms = affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		}
+		if hasAntiAffinity := affinity != nil && affinity.PodAntiAffinity != nil; hasAntiAffinity != test.antiAffinity {
+			t.Errorf("%q: pod anti-affinity %v, want %v", test.placement, hasAntiAffinity, test.antiAffinity)
+		} else if hasAntiAffinity {
+			terms = affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		}
+		for _, term := range terms {
+			if term.TopologyKey != workload.LabelHostname || term.LabelSelector.MatchLabels["testing"] != "capstan-echo-samenode-workload" {
+				t.Errorf("%q: affinity term %+v, want the hostname of the server pod", test.placement, term)
+			}
+		}
+	}
+
+	// The spec is copied, so the pods of the testing cases don't share their affinity.
+	if d.Spec.Client.Affinity != nil || len(d.Spec.Client.Containers[0].Env) != 0 {
+		t.Errorf("building the testing pods changed the client spec %+v", d.Spec.Client)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Driver builds the pods of a generic workload from its pod specs, and extracts its
+// metrics from the log of the client pod with its regexes.
+type Driver struct {
+	Spec *workload.GenericWorkload
+}
+
+// Ensure generic Driver implements workload.Driver and workload.WorkloadConditioner interfaces.
+var (
+	_ workload.Driver              = &Driver{}
+	_ workload.WorkloadConditioner = &Driver{}
+)
+
+// WorkloadPod builds the server pod of a testing case from the server pod spec (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	if d.Spec == nil {
+		return nil, errors.New("The generic section of the workload is required")
+	}
+	pod := newPod(args.Name, args.TestingName, d.Spec.Server)
+	container := &pod.Spec.Containers[0]
+	if container.Image == "" {
+		container.Image = args.Image
+	}
+	if args.TestingCase.WorkloadArgs != "" {
+		container.Args = strings.Fields(args.TestingCase.WorkloadArgs)
+	}
+	return pod, nil
+}
+
+// TestingPod builds the client pod of a testing case from the client pod spec, it is placed
+// relative to the server pod as the testing case requires (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	if d.Spec == nil {
+		return nil, errors.New("The generic section of the workload is required")
+	}
+	pod := newPod(args.Name, args.TestingName, d.Spec.Client)
+	container := &pod.Spec.Containers[0]
+	if container.Image == "" {
+		container.Image = args.Image
+	}
+	if args.TestingCase.TestingToolArgs != "" {
+		container.Args = strings.Fields(args.TestingCase.TestingToolArgs)
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: "ENDPOINT", Value: args.PodIP})
+
+	term := v1.PodAffinityTerm{
+		LabelSelector: &apismetav1.LabelSelector{
+			MatchLabels: map[string]string{"testing": args.WorkloadName},
+		},
+		TopologyKey: workload.LabelHostname,
+	}
+	switch args.TestingCase.Placement {
+	case workload.PlacementSameNode:
+		if pod.Spec.Affinity == nil {
+			pod.Spec.Affinity = &v1.Affinity{}
+		}
+		pod.Spec.Affinity.PodAffinity = &v1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{term},
+		}
+	case workload.PlacementDifferentNode:
+		if pod.Spec.Affinity == nil {
+			pod.Spec.Affinity = &v1.Affinity{}
+		}
+		pod.Spec.Affinity.PodAntiAffinity = &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{term},
+		}
+	}
+	return pod, nil
+}
+
+// ParseResults extracts the metrics from the log of the client pod (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	if d.Spec == nil {
+		return nil, errors.New("The generic section of the workload is required")
+	}
+	var metrics []results.Metric
+	for _, metric := range d.Spec.Metrics {
+		value, err := extract(metric, log)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to get %s", metric.Name)
+		}
+		metrics = append(metrics, results.Metric{
+			Name:          metric.Name,
+			Unit:          metric.Unit,
+			Value:         value,
+			LowerIsBetter: metric.LowerIsBetter,
+		})
+	}
+	return metrics, nil
+}
+
+// WorkloadCondition returns the readiness condition of the server pod (to adhere to workload.WorkloadConditioner interface).
+func (d *Driver) WorkloadCondition() workload.PodCondition {
+	if d.Spec != nil && d.Spec.Readiness == workload.ReadinessReady {
+		return workload.PodReady
+	}
+	return workload.PodRunning
+}
+
+// newPod builds a pod of a testing case from a pod spec with the labels and tolerations
+// of all pods created by capstan.
+func newPod(name, testingCase string, spec v1.PodSpec) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: apismetav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				"capstan-testingcase": testingCase,
+			},
+			Labels: map[string]string{
+				"component": "capstan",
+				"testing":   name,
+			},
+		},
+		Spec: *spec.DeepCopy(),
+	}
+	if pod.Spec.RestartPolicy == "" {
+		pod.Spec.RestartPolicy = v1.RestartPolicyNever
+	}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations,
+		v1.Toleration{Key: "node-role.kubernetes.io/master", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+		v1.Toleration{Key: "CriticalAddonsOnly", Operator: v1.TolerationOpExists},
+	)
+	return pod
+}
+
+// extract returns the value of the metric in the log, from the last match of its regex.
+func extract(m workload.MetricRegex, data []byte) (float64, error) {
+	re, err := regexp.Compile(m.Regex)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Invalid regex of metric %s", m.Name)
+	}
+	matches := re.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return 0, errors.Errorf("results not contain %q", m.Regex)
+	}
+	group := matches[len(matches)-1][m.GetGroup()]
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(group)), 64)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return value, nil
+}
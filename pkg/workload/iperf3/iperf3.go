@@ -17,99 +17,24 @@ limitations under the License.
 package iperf3
 
 import (
-	"time"
-
 	"github.com/ZJU-SEL/capstan/pkg/workload"
-	"github.com/golang/glog"
-	"github.com/pkg/errors"
-	"k8s.io/client-go/kubernetes"
 )
 
-// Workload represents the iperf3 workload.
-type Workload struct {
-	workload  workload.Workload
-	Name      string
-	Image     string
-	Frequency int
-}
-
-// Ensure iperf3 Workload implements workload.Interface
-var _ workload.Interface = &Workload{}
-
-// NewWorkload creates a new iperf3 workload from the given workload definition.
-func NewWorkload(wl workload.Workload) *Workload {
-	return &Workload{
-		workload:  wl,
-		Name:      wl.Name,
-		Image:     wl.Image,
-		Frequency: wl.Frequency,
-	}
-}
-
-// Run runs a iperf3 workload (to adhere to workload.Interface).
-func (w *Workload) Run(kubeClient kubernetes.Interface) error {
-	// initialize a new testing tool for this iperf3 workload.
-	testingTool, err := w.TestingTool()
-	if err != nil {
-		return err
-	}
-
-	for i := 1; i <= w.Frequency; i++ {
-		for _, testingCase := range testingTool.GetTestingCaseSet() {
-			// running a testing case.
-			glog.V(1).Infof("Repeat %d: Running the testing case %q of %s", i, testingCase.Name, w.GetName())
-			err := testingTool.Run(kubeClient, testingCase)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to create the resouces belong to testing case %q of %s", testingCase.Name, w.GetName())
-			}
-
-			// get the testing results of the testing case.
-			glog.V(4).Infof("Repeat %d: Starting fetch the testing results of the testing case %q", i, testingCase.Name)
-			err = testingTool.GetTestingResults(kubeClient)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to gets the testing results of the testing case %s", testingCase.Name)
-			}
-
-			// clean up all the resouces created by the testing case.
-			glog.V(4).Infof("Repeat %d: Cleaning up all the resouces created by the testing case %q", i, testingCase.Name)
-			err = testingTool.Cleanup(kubeClient)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to cleanup the resouces created by the testing case %s", testingCase.Name)
-			}
-
-			// sleep some seconds between testing cases.
-			glog.V(4).Infof("Repeat %d: Sleeping %v and starting next testing case.", i, testingTool.GetSteps())
-			time.Sleep(testingTool.GetSteps())
-		}
-	}
-	return nil
-}
-
-// TestingTool initializes a new testing tool for this iperf3 workload (to adhere to workload.Interface).
-func (w *Workload) TestingTool() (workload.Tool, error) {
-	if w.workload.TestingTool.Name != toolName {
-		return nil, errors.Errorf("Wrong parameter(%q), the testing tool name must be %q", w.workload.TestingTool.Name, toolName)
-	}
-
-	if err := workload.TestingCaseSetHasDefined(w.workload.TestingTool.TestingCaseSet, TestingCaseSet); err != nil {
-		return nil, err
-	}
-
-	return &TestingTool{
-		Workload:       w,
-		Name:           toolName,
-		Image:          w.workload.TestingTool.Image,
-		Steps:          time.Duration(w.workload.TestingTool.Steps) * time.Second,
-		TestingCaseSet: w.workload.TestingTool.TestingCaseSet,
-	}, nil
-}
-
-// GetName returns the name of this iperf3 workload (to adhere to workload.Interface).
-func (w *Workload) GetName() string {
-	return w.Name
+// WorkloadName is the name of the iperf3 workload in the capstan config.
+const WorkloadName = "iperf3"
+
+func init() {
+	workload.Register(WorkloadName, workload.Factory{
+		ToolName:       ToolName,
+		TestingCaseSet: TestingCaseSet,
+		New: func(wl workload.Workload, opts workload.RunOptions) workload.Interface {
+			return NewWorkload(wl, opts)
+		},
+	})
 }
 
-// GetImage returns the image name of this iperf3 workload (to adhere to workload.Interface).
-func (w *Workload) GetImage() string {
-	return w.Image
+// NewWorkload creates a new iperf3 workload from the given workload definition,
+// whose testing cases run with the options.
+func NewWorkload(wl workload.Workload, opts workload.RunOptions) *workload.Runner {
+	return workload.NewRunner(wl, opts, &Driver{})
 }
@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iperf3
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Result is the part of the JSON output of iperf3 -J which capstan uses.
+type Result struct {
+	Start     ResultStart `json:"start"`
+	Intervals []Interval  `json:"intervals"`
+	End       ResultEnd   `json:"end"`
+	// Error is set if iperf3 has failed.
+	Error string `json:"error,omitempty"`
+}
+
+// ResultStart describes the test.
+type ResultStart struct {
+	TestStart struct {
+		Protocol string `json:"protocol"`
+		Duration int    `json:"duration"`
+	} `json:"test_start"`
+}
+
+// Interval is the throughput of an interval of the test, of all streams.
+type Interval struct {
+	Sum Sum `json:"sum"`
+}
+
+// Sum is the throughput of all streams of an interval or of the whole test. Retransmits
+// are only reported for TCP, and jitter and lost packets for UDP.
+type Sum struct {
+	Start         float64 `json:"start"`
+	End           float64 `json:"end"`
+	Seconds       float64 `json:"seconds"`
+	Bytes         int64   `json:"bytes"`
+	BitsPerSecond float64 `json:"bits_per_second"`
+	Retransmits   int64   `json:"retransmits"`
+	JitterMs      float64 `json:"jitter_ms"`
+	LostPackets   int64   `json:"lost_packets"`
+	Packets       int64   `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
+}
+
+// ResultEnd is the summary of the test.
+type ResultEnd struct {
+	// Sum is the summary of a UDP test.
+	Sum            *Sum `json:"sum,omitempty"`
+	SumSent        *Sum `json:"sum_sent,omitempty"`
+	SumReceived    *Sum `json:"sum_received,omitempty"`
+	CPUUtilization struct {
+		HostTotal   float64 `json:"host_total"`
+		RemoteTotal float64 `json:"remote_total"`
+	} `json:"cpu_utilization_percent"`
+}
+
+// IsUDP returns whether the test is a UDP test.
+func (r *Result) IsUDP() bool {
+	return r.Start.TestStart.Protocol == "UDP"
+}
+
+// Sender returns the summary of the sender.
+func (r *Result) Sender() Sum {
+	if r.End.SumSent != nil {
+		return *r.End.SumSent
+	}
+	if r.End.Sum != nil {
+		return *r.End.Sum
+	}
+	return Sum{}
+}
+
+// Receiver returns the summary of the receiver, a UDP test of older iperf3 versions
+// only reports the summary of the receiver in the sum.
+func (r *Result) Receiver() Sum {
+	if r.IsUDP() && r.End.Sum != nil {
+		return *r.End.Sum
+	}
+	if r.End.SumReceived != nil {
+		return *r.End.SumReceived
+	}
+	return Sum{}
+}
+
+// ParseResult parses the JSON output of iperf3 -J in a log, the log may have other lines
+// before the JSON document, e.g. the TestingDoneMark after it.
+func ParseResult(data []byte) (*Result, []byte, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, nil, errors.Errorf("results not contain the JSON output of iperf3")
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&raw); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid JSON output of iperf3")
+	}
+	r := &Result{}
+	if err := json.Unmarshal(raw, r); err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid JSON output of iperf3")
+	}
+	if r.Error != "" {
+		return nil, nil, errors.Errorf("iperf3 has failed: %s", r.Error)
+	}
+	if r.End.SumReceived == nil && r.End.Sum == nil {
+		return nil, nil, errors.Errorf("results not contain the summary of the receiver")
+	}
+	return r, raw, nil
+}
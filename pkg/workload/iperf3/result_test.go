@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iperf3
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		file      string
+		udp       bool
+		intervals int
+		receiver  Sum
+		sender    Sum
+		cpuHost   float64
+	}{
+		{
+			file:      "tcp.json",
+			intervals: 2,
+			receiver:  Sum{Bytes: 2302672896, BitsPerSecond: 9208163553.6},
+			sender:    Sum{Bytes: 2305818624, BitsPerSecond: 9222758349.5, Retransmits: 357},
+			cpuHost:   38.51726,
+		},
+		{
+			// iperf3 before 3.9 only reports the sum of a UDP test.
+			file:      "udp.json",
+			udp:       true,
+			intervals: 2,
+			receiver:  Sum{Bytes: 249997312, BitsPerSecond: 999952199.2, JitterMs: 0.021, LostPackets: 214, Packets: 30517, LostPercent: 0.701248},
+			sender:    Sum{Bytes: 249997312, BitsPerSecond: 999952199.2, JitterMs: 0.021, LostPackets: 214, Packets: 30517, LostPercent: 0.701248},
+			cpuHost:   22.604813,
+		},
+	}
+	for _, test := range tests {
+		// the log may have other lines around the JSON output.
+		log := append([]byte("warning: something was printed first\n"), readTestdata(t, test.file)...)
+		r, raw, err := ParseResult(log)
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if r.IsUDP() != test.udp || len(r.Intervals) != test.intervals || r.Start.TestStart.Duration != 2 {
+			t.Errorf("%s: udp %v, %d intervals, duration %d, want %v, %d, 2", test.file, r.IsUDP(), len(r.Intervals), r.Start.TestStart.Duration, test.udp, test.intervals)
+		}
+		for _, sums := range []struct {
+			name      string
+			got, want Sum
+		}{{"receiver", r.Receiver(), test.receiver}, {"sender", r.Sender(), test.sender}} {
+			got, want := sums.got, sums.want
+			if got.Bytes != want.Bytes || !near(got.BitsPerSecond, want.BitsPerSecond) || got.Retransmits != want.Retransmits ||
+				!near(got.JitterMs, want.JitterMs) || got.LostPackets != want.LostPackets || got.Packets != want.Packets || !near(got.LostPercent, want.LostPercent) {
+				t.Errorf("%s: %s %+v, want %+v", test.file, sums.name, got, want)
+			}
+		}
+		if !near(r.End.CPUUtilization.HostTotal, test.cpuHost) {
+			t.Errorf("%s: host CPU %v, want %v", test.file, r.End.CPUUtilization.HostTotal, test.cpuHost)
+		}
+		// the raw JSON output is kept without the lines around it.
+		if !json.Valid(raw) || !bytes.HasPrefix(raw, []byte("{")) || !bytes.HasSuffix(raw, []byte("}")) {
+			t.Errorf("%s: raw output is not the JSON document: %q...", test.file, raw[:20])
+		}
+	}
+}
+
+func TestParseResultFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		log  []byte
+		err  string
+	}{
+		{"error", readTestdata(t, "error.json"), "unable to connect to server: Connection refused"},
+		{"no JSON", []byte("iperf3: error - unable to connect to server\n"), "not contain the JSON output"},
+		{"truncated", readTestdata(t, "tcp.json")[:512], "invalid JSON output"},
+	}
+	for _, test := range tests {
+		_, _, err := ParseResult(test.log)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	tests := []struct {
+		file  string
+		names []string
+	}{
+		{"tcp.json", []string{"bandwidth", "sender_bandwidth", "cpu_host", "cpu_remote", "retransmits"}},
+		{"udp.json", []string{"bandwidth", "sender_bandwidth", "cpu_host", "cpu_remote", "jitter", "lost_percent"}},
+	}
+	for _, test := range tests {
+		metrics, err := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		var names []string
+		for _, metric := range metrics {
+			names = append(names, metric.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.names, ",") {
+			t.Errorf("%s: metrics %v, want %v", test.file, names, test.names)
+		}
+	}
+
+	metrics, _ := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "tcp.json"))
+	if !near(metrics[0].Value, 9208.1635536) {
+		t.Errorf("bandwidth %v Mbits/sec, want 9208.1635536", metrics[0].Value)
+	}
+}
@@ -17,30 +17,30 @@ limitations under the License.
 package iperf3
 
 import (
-	"bufio"
 	"bytes"
+	"encoding/csv"
 	"io/ioutil"
-	"os"
 	"path"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
 	"github.com/ZJU-SEL/capstan/pkg/workload"
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/push"
 	v1 "k8s.io/api/core/v1"
-	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	toolName             = "iperf3"
+	// ToolName is the name of the testing tool of the iperf3 workload.
+	ToolName             = "iperf3"
 	benchmarkTCPSameNode = "benchmarkTCPSameNode"
 	benchmarkTCPDiffNode = "benchmarkTCPDiffNode"
+
+	// ResultFile is the JSON results of iperf3 in the results directory of a testing case.
+	ResultFile = "iperf3.json"
+	// IntervalsFile is the throughput of every interval of iperf3 as CSV in the results directory of a testing case.
+	IntervalsFile = "intervals.csv"
 )
 
 // TestingCaseSet is the list of iperf3 defined testing cases.
@@ -49,212 +49,122 @@ var TestingCaseSet = []string{
 	"benchmarkTCPDiffNode",
 }
 
-// TestingTool represents the iperf3 testing tool.
-type TestingTool struct {
-	Workload       *Workload
-	Name           string
-	Image          string
-	Steps          time.Duration
-	StartTime      time.Time
-	WorkloadNode   string
-	CurrentTesting workload.TestingCase
-	TestingCaseSet []workload.TestingCase
-}
-
-// Ensure iperf3 testing tool implements workload.Tool interface.
-var _ workload.Tool = &TestingTool{}
+// Driver supplies the manifests and the result parser of the iperf3 testing tool.
+type Driver struct{}
 
-// Run runs the defined testing case set for iperf3 testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Run(kubeClient kubernetes.Interface, testingCase workload.TestingCase) error {
-	t.CurrentTesting = testingCase
-	t.StartTime = time.Now()
+// Ensure iperf3 Driver implements workload.Driver, workload.CollectorDriver and workload.ResultsWriter interfaces.
+var (
+	_ workload.Driver          = &Driver{}
+	_ workload.CollectorDriver = &Driver{}
+	_ workload.ResultsWriter   = &Driver{}
+)
 
-	// 1. start a workload for the testing case.
-	workloadPodName := workload.BuildWorkloadPodName(t.Workload.GetName()+"-server", testingCase.Name)
-	tempWorkloadArgs := struct{ Name, TestingName, Image string }{
-		Name:        workloadPodName,
-		TestingName: testingCase.Name,
-		Image:       t.Workload.GetImage(),
-	}
+// WorkloadPod returns the iperf3 server pod of a testing case (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return workload.PodFromTemplate(iperfServerPod, args)
+}
 
-	iperfServerPodBytes, err := workload.ParseTemplate(iperfServerPod, tempWorkloadArgs)
-	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", iperfServerPod, tempWorkloadArgs)
+// TestingPod returns the iperf3 pod of a testing case, which runs on the node of the
+// workload pod or on another node. iperf3 always prints its results as JSON (to adhere
+// to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	if !hasJSONFlag(args.TestingCase.TestingToolArgs) {
+		args.Args = workload.FomatArgs(strings.TrimSpace(args.TestingCase.TestingToolArgs + " -J"))
 	}
 
-	glog.V(4).Infof("Creating workload %q of testing case %s", workloadPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, iperfServerPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the %s workload for testing case %s", t.Workload.GetName(), testingCase.Name)
+	switch args.TestingName {
+	case benchmarkTCPDiffNode:
+		return workload.PodFromTemplate(iperfClientPodAntiAffinity, args)
+	case benchmarkTCPSameNode:
+		return workload.PodFromTemplate(iperfClientPodAffinity, args)
 	}
+	return nil, errors.Errorf("unknown testing case %s of iperf3", args.TestingName)
+}
 
-	// 2. get the podIP and hostIP of the workload until workload is running.
-	glog.V(4).Infof("Geting the podIP and hostIP of workload %s", workloadPodName)
-	podIP, hostIP, err := workload.GetIPs(kubeClient, workloadPodName)
+// ParseResults parses the JSON results of iperf3 into metrics, the bandwidth of the receiver
+// is the headline metric (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, _, err := ParseResult(log)
 	if err != nil {
-		return errors.Wrapf(err, "unable to get podIP and hostIP of pod %s created by the %s workload for testing case %s", workloadPodName, t.Workload.GetName(), testingCase.Name)
-	}
-	t.WorkloadNode = hostIP
-
-	// 3. start a testing pod for testing the workload.
-	testingPodName := workload.BuildTestingPodName(t.GetName()+"-client", testingCase.Name)
-	testingPod, args := t.findTemplate(testingCase.Name)
-	tempTestingArgs := struct{ Name, TestingName, Image, WorkloadName, Args, PodIP string }{
-		Name:         testingPodName,
-		TestingName:  testingCase.Name,
-		Image:        t.GetImage(),
-		WorkloadName: workloadPodName,
-		Args:         workload.FomatArgs(args),
-		PodIP:        podIP,
+		return nil, errors.Wrapf(err, "Failed to get bandwidth")
 	}
 
-	testingPodBytes, err := workload.ParseTemplate(testingPod, tempTestingArgs)
-	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", testingPod, tempTestingArgs)
+	metrics := []results.Metric{
+		{Name: "bandwidth", Unit: "Mbits/sec", Value: r.Receiver().BitsPerSecond / 1e6},
+		{Name: "sender_bandwidth", Unit: "Mbits/sec", Value: r.Sender().BitsPerSecond / 1e6},
+		{Name: "cpu_host", Unit: "%", Value: r.End.CPUUtilization.HostTotal, LowerIsBetter: true},
+		{Name: "cpu_remote", Unit: "%", Value: r.End.CPUUtilization.RemoteTotal, LowerIsBetter: true},
 	}
-
-	glog.V(4).Infof("Creating testing pod %q of testing case %s", testingPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, testingPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the testing pod for testing case %s", testingCase.Name)
+	if r.IsUDP() {
+		metrics = append(metrics,
+			results.Metric{Name: "jitter", Unit: "ms", Value: r.Receiver().JitterMs, LowerIsBetter: true},
+			results.Metric{Name: "lost_percent", Unit: "%", Value: r.Receiver().LostPercent, LowerIsBetter: true},
+		)
+	} else {
+		metrics = append(metrics, results.Metric{Name: "retransmits", Unit: "retransmits", Value: float64(r.Sender().Retransmits), LowerIsBetter: true})
 	}
-
-	return nil
+	return metrics, nil
 }
 
-// GetTestingResults gets the testing results of iperf3 testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingResults(kubeClient kubernetes.Interface) error {
-	name := workload.BuildTestingPodName(t.GetName()+"-client", t.CurrentTesting.Name)
-	for {
-		// Sleep between each poll
-		// TODO(mozhuli): Use a watcher instead of polling.
-		time.Sleep(30 * time.Second)
-
-		// Make sure there's a pod.
-		pod, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).Get(name, apismetav1.GetOptions{})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		// Make sure the pod isn't failing.
-		if isFailing, err := workload.IsPodFailing(pod); isFailing {
-			return err
-		}
-
-		// Check testing has done.
-		body, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).GetLogs(
-			name,
-			&v1.PodLogOptions{},
-		).Do().Raw()
-
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		glog.V(5).Infof("Checking testing has done:\n%s", string(body))
-		if workload.HasTestingDone(body) {
-			glog.V(4).Infof("Testing case %s has done", t.CurrentTesting.Name)
-
-			// export to capstan result directory.
-			outdir := path.Join(types.ResultsDir, types.UUID, "workloads", t.Workload.GetName(), t.GetName(), t.CurrentTesting.Name)
-			if err = os.MkdirAll(outdir, 0755); err != nil {
-				return errors.WithStack(err)
-			}
-
-			outfile := path.Join(outdir, t.GetName()) + ".log"
-			if err = ioutil.WriteFile(outfile, body, 0644); err != nil {
-				return errors.WithStack(err)
-			}
-
-			// export to prometheus pushGateway.
-			data, err := getBandwidth(body)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to get bandwidth")
-			}
-
-			bandwidth := prometheus.NewGauge(prometheus.GaugeOpts{
-				Name: "capstan_iperf3_bandwidth",
-				Help: "The bandwidth of iperf3 testing case",
-			})
-			bandwidth.Set(data)
-			if err := push.Collectors(
-				"iperf3",
-				map[string]string{
-					"uid":          types.UUID,
-					"provider":     types.Provider,
-					"startTime":    t.StartTime.Format("2006-01-02 15:04:05"),
-					"endTime":      time.Now().Format("2006-01-02 15:04:05"),
-					"workloadNode": t.WorkloadNode,
-					"testingNode":  pod.Status.HostIP,
-					"workloadName": t.Workload.GetName(),
-					"testingName":  t.GetName(),
-					"testingCase":  t.CurrentTesting.Name,
-				},
-				types.PushgatewayEndpoint,
-				bandwidth,
-			); err != nil {
-				return errors.Wrapf(err, "Could not push metrics to Pushgateway")
-			}
+// Collectors returns the bandwidth of every interval of iperf3 (to adhere to workload.CollectorDriver interface).
+func (d *Driver) Collectors(testingCase workload.TestingCase, log []byte) ([]prometheus.Collector, error) {
+	r, _, err := ParseResult(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get bandwidth")
+	}
 
-			return nil
-		}
+	intervals := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "capstan_iperf3_interval_bandwidth",
+		Help: "The bandwidth in Mbits/sec of every interval of iperf3 testing case",
+	}, []string{"interval"})
+	for i, interval := range r.Intervals {
+		intervals.WithLabelValues(strconv.Itoa(i)).Set(interval.Sum.BitsPerSecond / 1e6)
 	}
+	return []prometheus.Collector{intervals}, nil
 }
 
-// Cleanup cleans up all resources created by a testing case for iperf3 testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Cleanup(kubeClient kubernetes.Interface) error {
-	if err := workload.DeletePod(kubeClient, workload.BuildTestingPodName(t.GetName()+"-client", t.CurrentTesting.Name)); err != nil {
-		return err
+// WriteResults writes the JSON results of iperf3 and its intervals as CSV to the results
+// directory of the testing case (to adhere to workload.ResultsWriter interface).
+func (d *Driver) WriteResults(testingCase workload.TestingCase, log []byte, dir string) error {
+	r, raw, err := ParseResult(log)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get bandwidth")
 	}
-	if err := workload.DeletePod(kubeClient, workload.BuildWorkloadPodName(t.Workload.GetName()+"-server", t.CurrentTesting.Name)); err != nil {
-		return err
+	if err := ioutil.WriteFile(path.Join(dir, ResultFile), raw, 0644); err != nil {
+		return errors.WithStack(err)
 	}
-	return nil
-}
 
-// GetName returns the name of iperf3 testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetName() string {
-	return t.Name
-}
-
-// GetImage returns the image name of iperf3 testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetImage() string {
-	return t.Image
-}
-
-// GetSteps returns the steps between each testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetSteps() time.Duration {
-	return t.Steps
-}
-
-// GetTestingCaseSet returns the testing case set which the iperf3 testing tool will run (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingCaseSet() []workload.TestingCase {
-	return t.TestingCaseSet
-}
-
-// findTemplate returns the true testing tool template and arguments for different testing cases.
-func (t *TestingTool) findTemplate(name string) (string, string) {
-	if t.CurrentTesting.Name == benchmarkTCPDiffNode {
-		return iperfClientPodAntiAffinity, t.CurrentTesting.TestingToolArgs
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"start", "end", "bytes", "bits_per_second", "retransmits", "jitter_ms", "lost_percent"})
+	for _, interval := range r.Intervals {
+		w.Write([]string{
+			formatFloat(interval.Sum.Start),
+			formatFloat(interval.Sum.End),
+			strconv.FormatInt(interval.Sum.Bytes, 10),
+			formatFloat(interval.Sum.BitsPerSecond),
+			strconv.FormatInt(interval.Sum.Retransmits, 10),
+			formatFloat(interval.Sum.JitterMs),
+			formatFloat(interval.Sum.LostPercent),
+		})
 	}
-	if t.CurrentTesting.Name == benchmarkTCPSameNode {
-		return iperfClientPodAffinity, t.CurrentTesting.TestingToolArgs
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.WithStack(err)
 	}
-	return "", ""
+	return errors.WithStack(ioutil.WriteFile(path.Join(dir, IntervalsFile), buf.Bytes(), 0644))
 }
 
-func getBandwidth(data []byte) (float64, error) {
-	scanner := bufio.NewScanner(bytes.NewBuffer(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.Contains(line, "receiver") {
-			bw, err := strconv.ParseFloat(strings.Fields(line)[6], 64)
-			if err != nil {
-				return 0, errors.WithStack(err)
-			}
-			if strings.Fields(line)[7] == "Gbits/sec" {
-				return bw * 1024, nil
-			}
-			return bw, nil
+// hasJSONFlag returns whether the args of iperf3 make it print JSON.
+func hasJSONFlag(args string) bool {
+	for _, arg := range strings.Fields(args) {
+		if arg == "-J" || arg == "--json" {
+			return true
 		}
 	}
-	return 0, errors.Errorf("results not contain receiver")
+	return false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
@@ -28,7 +28,7 @@ metadata:
   labels:
     component: capstan
     testing: {{ .Name }}
-  namespace: capstan
+  namespace: {{ .Namespace }}
 spec:
   containers:
   - name: workload-mysql
@@ -58,7 +58,7 @@ metadata:
   labels:
     component: capstan
     testing: {{ .Name }}
-  namespace: capstan
+  namespace: {{ .Namespace }}
 spec:
   affinity:
     podAntiAffinity:
@@ -97,7 +97,7 @@ metadata:
   labels:
     component: capstan
     testing: {{ .Name }}
-  namespace: capstan
+  namespace: {{ .Namespace }}
 spec:
   affinity:
     podAffinity:
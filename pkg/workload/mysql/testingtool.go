@@ -17,30 +17,28 @@ limitations under the License.
 package mysql
 
 import (
-	"bufio"
 	"bytes"
+	"encoding/csv"
 	"io/ioutil"
-	"os"
 	"path"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
 	"github.com/ZJU-SEL/capstan/pkg/workload"
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/push"
 	v1 "k8s.io/api/core/v1"
-	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	toolName              = "tpcc-mysql"
+	// ToolName is the name of the testing tool of the mysql workload.
+	ToolName              = "tpcc-mysql"
 	benchmarkTPMCSameNode = "benchmarkTPMCSameNode"
 	benchmarkTPMCDiffNode = "benchmarkTPMCDiffNode"
+
+	// IntervalsFile is the throughput and response times of every interval of tpcc_start as CSV
+	// in the results directory of a testing case.
+	IntervalsFile = "intervals.csv"
 )
 
 // TestingCaseSet is the list of mysql defined testing cases.
@@ -49,209 +47,100 @@ var TestingCaseSet = []string{
 	"benchmarkTPMCDiffNode",
 }
 
-// TestingTool represents the mysql testing tool.
-type TestingTool struct {
-	Workload       *Workload
-	Name           string
-	Image          string
-	Steps          time.Duration
-	StartTime      time.Time
-	WorkloadNode   string
-	CurrentTesting workload.TestingCase
-	TestingCaseSet []workload.TestingCase
-}
+// Driver supplies the manifests and the result parser of the tpcc-mysql testing tool.
+type Driver struct{}
 
-// Ensure mysql testing tool implements workload.Tool interface.
-var _ workload.Tool = &TestingTool{}
+// Ensure tpcc-mysql Driver implements workload.Driver and workload.ResultsWriter interfaces.
+var (
+	_ workload.Driver        = &Driver{}
+	_ workload.ResultsWriter = &Driver{}
+)
 
-// Run runs the defined testing case set for mysql testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Run(kubeClient kubernetes.Interface, testingCase workload.TestingCase) error {
-	t.CurrentTesting = testingCase
-	t.StartTime = time.Now()
+// WorkloadPod returns the mysql pod of a testing case (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return workload.PodFromTemplate(mysqlPod, args)
+}
 
-	// 1. start a workload for the testing case.
-	workloadPodName := workload.BuildWorkloadPodName(t.Workload.GetName(), testingCase.Name)
-	tempWorkloadArgs := struct{ Name, TestingName, Image string }{
-		Name:        workloadPodName,
-		TestingName: testingCase.Name,
-		Image:       t.Workload.GetImage(),
+// TestingPod returns the tpcc-mysql pod of a testing case, which runs on the node of the
+// workload pod or on another node (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	switch args.TestingName {
+	case benchmarkTPMCDiffNode:
+		return workload.PodFromTemplate(mysqlTPCCPodAntiAffinity, args)
+	case benchmarkTPMCSameNode:
+		return workload.PodFromTemplate(mysqlTPCCPodAffinity, args)
 	}
+	return nil, errors.Errorf("unknown testing case %s of tpcc-mysql", args.TestingName)
+}
 
-	mysqlServerPodBytes, err := workload.ParseTemplate(mysqlPod, tempWorkloadArgs)
+// ParseResults parses the results of tpcc_start into metrics, the TpmC is the headline metric. The
+// testing case fails if the response time constraints of TPC-C are not met (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, err := ParseTPCC(log)
 	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", mysqlPod, tempWorkloadArgs)
+		return nil, errors.Wrapf(err, "Failed to get TpmC")
 	}
-
-	glog.V(4).Infof("Creating workload %q of testing case %s", workloadPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, mysqlServerPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the %s workload for testing case %s", t.Workload.GetName(), testingCase.Name)
+	if violations := r.ResponseTimeViolations(); len(violations) != 0 {
+		return nil, errors.Errorf("the response time constraints of %s are not met", strings.Join(violations, ", "))
 	}
 
-	// 2. get the podIP and hostIP of the workload until workload is running.
-	glog.V(4).Infof("Geting the podIP and hostIP of workload %s", workloadPodName)
-	podIP, hostIP, err := workload.GetIPs(kubeClient, workloadPodName)
-	if err != nil {
-		return errors.Wrapf(err, "unable to get podIP and hostIP of pod %s created by the %s workload for testing case %s", workloadPodName, t.Workload.GetName(), testingCase.Name)
-	}
-	t.WorkloadNode = hostIP
-
-	// 3. start a testing pod for testing the workload.
-	testingPodName := workload.BuildTestingPodName(t.GetName(), testingCase.Name)
-	testingPod, args := t.findTemplate(testingCase.Name)
-	tempTestingArgs := struct{ Name, TestingName, Image, WorkloadName, Args, PodIP string }{
-		Name:         testingPodName,
-		TestingName:  testingCase.Name,
-		Image:        t.GetImage(),
-		WorkloadName: workloadPodName,
-		Args:         workload.FomatArgs(args),
-		PodIP:        podIP,
+	metrics := []results.Metric{{Name: "tpmc", Unit: "transactions/min", Value: r.TpmC}}
+	for _, t := range r.Transactions {
+		name := metricName(t.Name)
+		metrics = append(metrics,
+			results.Metric{Name: name + "_success", Unit: "transactions", Value: float64(t.Success)},
+			results.Metric{Name: name + "_late", Unit: "transactions", Value: float64(t.Late), LowerIsBetter: true},
+			results.Metric{Name: name + "_retry", Unit: "transactions", Value: float64(t.Retry), LowerIsBetter: true},
+			results.Metric{Name: name + "_failure", Unit: "transactions", Value: float64(t.Failure), LowerIsBetter: true},
+			results.Metric{Name: name + "_rt_p90", Unit: "seconds", Value: t.Percentile90, LowerIsBetter: true},
+			results.Metric{Name: name + "_rt_max", Unit: "seconds", Value: t.MaxRT, LowerIsBetter: true},
+		)
 	}
+	return metrics, nil
+}
 
-	testingPodBytes, err := workload.ParseTemplate(testingPod, tempTestingArgs)
+// WriteResults writes the intervals of tpcc_start as CSV to the results directory of the
+// testing case (to adhere to workload.ResultsWriter interface).
+func (d *Driver) WriteResults(testingCase workload.TestingCase, log []byte, dir string) error {
+	r, err := ParseTPCC(log)
 	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", testingPod, tempTestingArgs)
+		return errors.Wrapf(err, "Failed to get TpmC")
 	}
 
-	glog.V(4).Infof("Creating testing pod %q of testing case %s", testingPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, testingPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the testing pod for testing case %s", testingCase.Name)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := []string{"seconds", "tpmc"}
+	for _, name := range Transactions {
+		name = metricName(name)
+		header = append(header, name+"_count", name+"_late", name+"_rt_p90", name+"_rt_p95", name+"_rt_p99", name+"_rt_max")
 	}
-
-	return nil
-}
-
-// GetTestingResults gets the testing results of mysql testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingResults(kubeClient kubernetes.Interface) error {
-	name := workload.BuildTestingPodName(t.GetName(), t.CurrentTesting.Name)
-	for {
-		// Sleep between each poll
-		// TODO(mozhuli): Use a watcher instead of polling.
-		time.Sleep(30 * time.Second)
-
-		// Make sure there's a pod.
-		pod, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).Get(name, apismetav1.GetOptions{})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		// Make sure the pod isn't failing.
-		if isFailing, err := workload.IsPodFailing(pod); isFailing {
-			return err
-		}
-
-		// Check testing has done.
-		body, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).GetLogs(
-			name,
-			&v1.PodLogOptions{},
-		).Do().Raw()
-
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		glog.V(5).Infof("Checking testing has done:\n%s", string(body))
-		if workload.HasTestingDone(body) {
-			glog.V(4).Infof("Testing case %s has done", t.CurrentTesting.Name)
-
-			// export to capstan result directory.
-			outdir := path.Join(types.ResultsDir, types.UUID, "workloads", t.Workload.GetName(), t.GetName(), t.CurrentTesting.Name)
-			if err = os.MkdirAll(outdir, 0755); err != nil {
-				return errors.WithStack(err)
-			}
-
-			outfile := path.Join(outdir, t.GetName()) + ".log"
-			if err = ioutil.WriteFile(outfile, body, 0644); err != nil {
-				return errors.WithStack(err)
-			}
-
-			// export to prometheus pushGateway.
-			data, err := getTPMC(body)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to get tpmc")
-			}
-
-			tpmc := prometheus.NewGauge(prometheus.GaugeOpts{
-				Name: "capstan_mysql_tpmc",
-				Help: "The tpmc of mysql testing case",
-			})
-			tpmc.Set(data)
-			if err := push.Collectors(
-				"mysql",
-				map[string]string{
-					"uid":          types.UUID,
-					"provider":     types.Provider,
-					"startTime":    t.StartTime.Format("2006-01-02 15:04:05"),
-					"endTime":      time.Now().Format("2006-01-02 15:04:05"),
-					"workloadNode": t.WorkloadNode,
-					"testingNode":  pod.Status.HostIP,
-					"workloadName": t.Workload.GetName(),
-					"testingName":  t.GetName(),
-					"testingCase":  t.CurrentTesting.Name,
-				},
-				types.PushgatewayEndpoint,
-				tpmc,
-			); err != nil {
-				return errors.Wrapf(err, "Could not push metrics to Pushgateway")
-			}
-
-			return nil
+	w.Write(header)
+	for i, interval := range r.Intervals {
+		record := []string{strconv.Itoa(interval.Seconds), formatFloat(r.IntervalTpmC(i))}
+		for _, stats := range interval.Stats {
+			record = append(record,
+				strconv.FormatInt(stats.Count, 10),
+				strconv.FormatInt(stats.Late, 10),
+				formatFloat(stats.Percentile90),
+				formatFloat(stats.Percentile95),
+				formatFloat(stats.Percentile99),
+				formatFloat(stats.MaxRT),
+			)
 		}
+		w.Write(record)
 	}
-}
-
-// Cleanup cleans up all resources created by a testing case for mysql testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Cleanup(kubeClient kubernetes.Interface) error {
-	if err := workload.DeletePod(kubeClient, workload.BuildTestingPodName(t.GetName(), t.CurrentTesting.Name)); err != nil {
-		return err
-	}
-	if err := workload.DeletePod(kubeClient, workload.BuildWorkloadPodName(t.Workload.GetName(), t.CurrentTesting.Name)); err != nil {
-		return err
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.WithStack(err)
 	}
-	return nil
-}
-
-// GetName returns the name of mysql testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetName() string {
-	return t.Name
+	return errors.WithStack(ioutil.WriteFile(path.Join(dir, IntervalsFile), buf.Bytes(), 0644))
 }
 
-// GetImage returns the image name of mysql testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetImage() string {
-	return t.Image
+// metricName returns the name of a transaction type in metric names, e.g. new_order.
+func metricName(transaction string) string {
+	return strings.ToLower(strings.Replace(transaction, "-", "_", -1))
 }
 
-// GetSteps returns the steps between each testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetSteps() time.Duration {
-	return t.Steps
-}
-
-// GetTestingCaseSet returns the testing case set which the mysql testing tool will run (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingCaseSet() []workload.TestingCase {
-	return t.TestingCaseSet
-}
-
-// findTemplate returns the true testing tool template and arguments for different testing cases.
-func (t *TestingTool) findTemplate(name string) (string, string) {
-	if t.CurrentTesting.Name == benchmarkTPMCDiffNode {
-		return mysqlTPCCPodAntiAffinity, t.CurrentTesting.TestingToolArgs
-	}
-	if t.CurrentTesting.Name == benchmarkTPMCSameNode {
-		return mysqlTPCCPodAffinity, t.CurrentTesting.TestingToolArgs
-	}
-	return "", ""
-}
-
-func getTPMC(data []byte) (float64, error) {
-	scanner := bufio.NewScanner(bytes.NewBuffer(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.Contains(line, " TpmC") {
-			tpmc, err := strconv.ParseFloat(strings.Fields(line)[0], 64)
-			if err != nil {
-				return 0, errors.WithStack(err)
-			}
-			return tpmc, nil
-		}
-	}
-	return 0, errors.Errorf("results not contain TpmC")
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
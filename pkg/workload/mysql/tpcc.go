@@ -0,0 +1,258 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Transactions are the five transaction types of TPC-C in the order printed by tpcc_start.
+var Transactions = []string{"New-Order", "Payment", "Order-Status", "Delivery", "Stock-Level"}
+
+// TransactionStats are the results of a transaction type of the whole measurement.
+type TransactionStats struct {
+	Name string
+	// Success, Late, Retry and Failure are the counts of the transactions from the raw results,
+	// late transactions are successful but exceed the response time limit.
+	Success int64
+	Late    int64
+	Retry   int64
+	Failure int64
+	// Percentile90 and MaxRT are the response times of the transactions in seconds.
+	Percentile90 float64
+	MaxRT        float64
+}
+
+// IntervalStats are the results of a transaction type in an interval of the measurement,
+// the response times are in milliseconds and those not printed by tpcc_start are zero.
+type IntervalStats struct {
+	Count        int64
+	Late         int64
+	Percentile90 float64
+	Percentile95 float64
+	Percentile99 float64
+	MaxRT        float64
+}
+
+// Interval is an interval of the measurement, its stats are in the order of Transactions.
+type Interval struct {
+	// Seconds is the end of the interval since the measurement has started.
+	Seconds int
+	Stats   []IntervalStats
+}
+
+// Constraint is a line of the constraint check of tpcc_start.
+type Constraint struct {
+	// Section is "transaction percentage" or "response time (at least 90% passed)".
+	Section     string
+	Transaction string
+	// Percent is the percentage of the transactions, or of the transactions in time.
+	Percent float64
+	OK      bool
+}
+
+// Result is the result printed by tpcc_start.
+type Result struct {
+	Intervals    []Interval
+	Transactions []TransactionStats
+	Constraints  []Constraint
+	TpmC         float64
+}
+
+var (
+	// 10, trx: 123, 95%: 9.483, 99%: 18.738, max_rt: 213.169, 12|98.778, 12|101.113, 1|4.123, 13|23.432
+	intervalRegexp = regexp.MustCompile(`^(\d+), trx: (\d+), 95%: ([\d.]+), 99%: ([\d.]+), max_rt: ([\d.]+), (\d+)\|([\d.]+), (\d+)\|([\d.]+), (\d+)\|([\d.]+), (\d+)\|([\d.]+)`)
+	// 10, 123(0):1.234|2.345, 123(0):0.456|0.789, 12(0):0.123|0.234, 12(0):2.345|3.456, 12(0):4.567|5.678
+	classicIntervalRegexp = regexp.MustCompile(`^(\d+), (\d+)\((\d+)\):([\d.]+)\|([\d.]+), (\d+)\((\d+)\):([\d.]+)\|([\d.]+), (\d+)\((\d+)\):([\d.]+)\|([\d.]+), (\d+)\((\d+)\):([\d.]+)\|([\d.]+), (\d+)\((\d+)\):([\d.]+)\|([\d.]+)`)
+	// [0] sc:1243 lt:0  rt:0  fl:0 avg_rt: 4.5 (5)
+	rawResultRegexp = regexp.MustCompile(`^\[(\d)\] sc:(\d+)\s+lt:(\d+)\s+rt:(\d+)\s+fl:(\d+)`)
+	// New-Order : 0.379  (1.423)
+	percentileRegexp = regexp.MustCompile(`^([A-Za-z-]+)\s*:\s*([\d.]+)\s+\(([\d.]+)\)`)
+	// Payment: 43.48% (>=43.0%) [OK]
+	constraintRegexp = regexp.MustCompile(`^([A-Za-z-]+):\s*([\d.]+)%.*\[(OK|NG)\]`)
+)
+
+// ParseTPCC parses the output of tpcc_start, it fails if the output has no TpmC.
+func ParseTPCC(data []byte) (*Result, error) {
+	r := &Result{}
+	for _, name := range Transactions {
+		r.Transactions = append(r.Transactions, TransactionStats{Name: name})
+	}
+
+	section := ""
+	constraintSection := ""
+	foundTpmC := false
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "<") {
+			section = line
+			continue
+		}
+
+		var err error
+		switch {
+		case intervalRegexp.MatchString(line):
+			err = r.addInterval(intervalRegexp.FindStringSubmatch(line), false)
+		case classicIntervalRegexp.MatchString(line):
+			err = r.addInterval(classicIntervalRegexp.FindStringSubmatch(line), true)
+		case section == "<Raw Results>" && rawResultRegexp.MatchString(line):
+			m := rawResultRegexp.FindStringSubmatch(line)
+			i, _ := strconv.Atoi(m[1])
+			if i >= len(r.Transactions) {
+				break
+			}
+			t := &r.Transactions[i]
+			counts, err2 := parseInts(m[2:6])
+			if err2 != nil {
+				err = err2
+				break
+			}
+			t.Success, t.Late, t.Retry, t.Failure = counts[0], counts[1], counts[2], counts[3]
+		case strings.HasPrefix(section, "<90th Percentile RT") && percentileRegexp.MatchString(line):
+			m := percentileRegexp.FindStringSubmatch(line)
+			if t := r.transaction(m[1]); t != nil {
+				if t.Percentile90, err = strconv.ParseFloat(m[2], 64); err == nil {
+					t.MaxRT, err = strconv.ParseFloat(m[3], 64)
+				}
+			}
+		case strings.HasPrefix(section, "<Constraint Check>") && strings.HasPrefix(line, "["):
+			constraintSection = strings.Trim(line, "[]")
+		case strings.HasPrefix(section, "<Constraint Check>") && constraintRegexp.MatchString(line):
+			m := constraintRegexp.FindStringSubmatch(line)
+			c := Constraint{Section: constraintSection, Transaction: m[1], OK: m[3] == "OK"}
+			c.Percent, err = strconv.ParseFloat(m[2], 64)
+			r.Constraints = append(r.Constraints, c)
+		case strings.HasSuffix(line, " TpmC"):
+			r.TpmC, err = strconv.ParseFloat(strings.Fields(line)[0], 64)
+			foundTpmC = err == nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid line %q", line)
+		}
+	}
+
+	if !foundTpmC {
+		return nil, errors.Errorf("results not contain TpmC")
+	}
+	return r, nil
+}
+
+// ResponseTimeViolations returns the transaction types which do not meet the response
+// time constraints of TPC-C, i.e. less than 90% of them are in time.
+func (r *Result) ResponseTimeViolations() []string {
+	var violations []string
+	for _, c := range r.Constraints {
+		if strings.HasPrefix(c.Section, "response time") && !c.OK {
+			violations = append(violations, c.Transaction)
+		}
+	}
+	return violations
+}
+
+// IntervalTpmC returns the New-Order transactions per minute of the i-th interval.
+func (r *Result) IntervalTpmC(i int) float64 {
+	start := 0
+	if i > 0 {
+		start = r.Intervals[i-1].Seconds
+	}
+	seconds := r.Intervals[i].Seconds - start
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(r.Intervals[i].Stats[0].Count) * 60 / float64(seconds)
+}
+
+func (r *Result) transaction(name string) *TransactionStats {
+	for i := range r.Transactions {
+		if r.Transactions[i].Name == name {
+			return &r.Transactions[i]
+		}
+	}
+	return nil
+}
+
+// addInterval adds an interval from the submatches of intervalRegexp or classicIntervalRegexp.
+func (r *Result) addInterval(m []string, classic bool) error {
+	seconds, err := strconv.Atoi(m[1])
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	values, err := parseFloats(m[2:])
+	if err != nil {
+		return err
+	}
+
+	interval := Interval{Seconds: seconds}
+	if classic {
+		// count(late):90th|max of every transaction type.
+		for i := 0; i < len(values); i += 4 {
+			interval.Stats = append(interval.Stats, IntervalStats{
+				Count:        int64(values[i]),
+				Late:         int64(values[i+1]),
+				Percentile90: values[i+2],
+				MaxRT:        values[i+3],
+			})
+		}
+	} else {
+		// count, 95th, 99th and max of New-Order, then count|max of the others.
+		interval.Stats = append(interval.Stats, IntervalStats{
+			Count:        int64(values[0]),
+			Percentile95: values[1],
+			Percentile99: values[2],
+			MaxRT:        values[3],
+		})
+		for i := 4; i < len(values); i += 2 {
+			interval.Stats = append(interval.Stats, IntervalStats{
+				Count: int64(values[i]),
+				MaxRT: values[i+1],
+			})
+		}
+	}
+	r.Intervals = append(r.Intervals, interval)
+	return nil
+}
+
+func parseInts(ss []string) ([]int64, error) {
+	var values []int64
+	for _, s := range ss {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseFloats(ss []string) ([]float64, error) {
+	var values []float64
+	for _, s := range ss {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
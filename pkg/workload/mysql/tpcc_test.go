@@ -0,0 +1,130 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysql
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseTPCCPassed(t *testing.T) {
+	r, err := ParseTPCC(readTestdata(t, "tpcc-classic.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TpmC != 7716 {
+		t.Errorf("TpmC %v, want 7716", r.TpmC)
+	}
+
+	if len(r.Intervals) != 2 || r.Intervals[1].Seconds != 20 || len(r.Intervals[1].Stats) != len(Transactions) {
+		t.Fatalf("intervals %+v, want 2 intervals of 5 transaction types", r.Intervals)
+	}
+	if want := (IntervalStats{Count: 1262, Percentile90: 1.234, MaxRT: 3.015}); r.Intervals[0].Stats[0] != want {
+		t.Errorf("New-Order of the first interval %+v, want %+v", r.Intervals[0].Stats[0], want)
+	}
+	if want := (IntervalStats{Count: 131, Percentile90: 3.145, MaxRT: 4.230}); r.Intervals[1].Stats[4] != want {
+		t.Errorf("Stock-Level of the second interval %+v, want %+v", r.Intervals[1].Stats[4], want)
+	}
+	if tpmc := r.IntervalTpmC(1); tpmc != 7860 {
+		t.Errorf("TpmC of the second interval %v, want 7860", tpmc)
+	}
+
+	want := []TransactionStats{
+		{Name: "New-Order", Success: 2572, Percentile90: 1.234, MaxRT: 3.015},
+		{Name: "Payment", Success: 2572, Percentile90: 0.412, MaxRT: 1.102},
+		{Name: "Order-Status", Success: 257, Percentile90: 0.201, MaxRT: 0.533},
+		{Name: "Delivery", Success: 256, Percentile90: 2.119, MaxRT: 3.409},
+		{Name: "Stock-Level", Success: 258, Percentile90: 3.301, MaxRT: 4.772},
+	}
+	if !reflect.DeepEqual(r.Transactions, want) {
+		t.Errorf("transactions %+v, want %+v", r.Transactions, want)
+	}
+
+	if len(r.Constraints) != 9 {
+		t.Errorf("%d constraints, want 9", len(r.Constraints))
+	}
+	if want := (Constraint{Section: "transaction percentage", Transaction: "Payment", Percent: 43.48, OK: true}); len(r.Constraints) == 0 || r.Constraints[0] != want {
+		t.Errorf("first constraint %+v, want %+v", r.Constraints, want)
+	}
+	if violations := r.ResponseTimeViolations(); len(violations) != 0 {
+		t.Errorf("violations %v, want none", violations)
+	}
+
+	metrics, err := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "tpcc-classic.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics[0].Name != "tpmc" || metrics[0].Value != 7716 {
+		t.Errorf("headline metric %+v, want tpmc 7716", metrics[0])
+	}
+}
+
+func TestParseTPCCViolated(t *testing.T) {
+	r, err := ParseTPCC(readTestdata(t, "tpcc-ng.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TpmC != 7692 {
+		t.Errorf("TpmC %v, want 7692", r.TpmC)
+	}
+
+	if len(r.Intervals) != 3 || len(r.Intervals[2].Stats) != len(Transactions) {
+		t.Fatalf("intervals %+v, want 3 intervals of 5 transaction types", r.Intervals)
+	}
+	if want := (IntervalStats{Count: 1283, Percentile95: 12.105, Percentile99: 19.338, MaxRT: 35.402}); r.Intervals[2].Stats[0] != want {
+		t.Errorf("New-Order of the last interval %+v, want %+v", r.Intervals[2].Stats[0], want)
+	}
+	if want := (IntervalStats{Count: 128, MaxRT: 40.563}); r.Intervals[2].Stats[4] != want {
+		t.Errorf("Stock-Level of the last interval %+v, want %+v", r.Intervals[2].Stats[4], want)
+	}
+
+	if want := (TransactionStats{Name: "New-Order", Success: 3, Late: 3843}); r.Transactions[0] != want {
+		t.Errorf("New-Order %+v, want %+v", r.Transactions[0], want)
+	}
+	if want := (TransactionStats{Name: "Stock-Level", Success: 383, Late: 2}); r.Transactions[4] != want {
+		t.Errorf("Stock-Level %+v, want %+v", r.Transactions[4], want)
+	}
+	if violations := r.ResponseTimeViolations(); !reflect.DeepEqual(violations, []string{"New-Order"}) {
+		t.Errorf("violations %v, want [New-Order]", violations)
+	}
+
+	_, err = (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "tpcc-ng.txt"))
+	if err == nil || !strings.Contains(err.Error(), "New-Order") {
+		t.Errorf("error %v, want the response time constraints of New-Order not met", err)
+	}
+}
+
+func TestParseTPCCWithoutTpmC(t *testing.T) {
+	data := readTestdata(t, "tpcc-classic.txt")
+	data = data[:strings.Index(string(data), "<TpmC>")]
+	if _, err := ParseTPCC(data); err == nil {
+		t.Errorf("parsing the output without TpmC succeeded")
+	}
+}
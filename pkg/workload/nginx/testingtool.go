@@ -17,30 +17,25 @@ limitations under the License.
 package nginx
 
 import (
-	"bufio"
-	"bytes"
-	"io/ioutil"
-	"os"
-	"path"
 	"strconv"
-	"strings"
-	"time"
 
-	"github.com/ZJU-SEL/capstan/pkg/capstan/types"
+	"github.com/ZJU-SEL/capstan/pkg/results"
 	"github.com/ZJU-SEL/capstan/pkg/workload"
-	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/push"
 	v1 "k8s.io/api/core/v1"
-	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 const (
-	toolName               = "wrk"
+	// ToolName is the name of the testing tool of the nginx workload.
+	ToolName               = "wrk"
 	benchmarkPodIPSameNode = "benchmarkPodIPSameNode"
 	benchmarkPodIPDiffNode = "benchmarkPodIPDiffNode"
+
+	// optionMaxSocketErrors is the option of a testing case failing it when wrk counts
+	// more socket errors, socket errors are not limited if it is not set.
+	optionMaxSocketErrors = "maxSocketErrors"
 )
 
 // TestingCaseSet is the list of wrk defined testing case.
@@ -49,211 +44,127 @@ var TestingCaseSet = []string{
 	"benchmarkPodIPDiffNode",
 }
 
-// TestingTool represents the wrk testing tool.
-type TestingTool struct {
-	Workload       *Workload
-	Name           string
-	Image          string
-	Steps          time.Duration
-	StartTime      time.Time
-	WorkloadNode   string
-	CurrentTesting workload.TestingCase
-	TestingCaseSet []workload.TestingCase
-}
+// Driver supplies the manifests and the result parser of the wrk testing tool.
+type Driver struct{}
 
-// Ensure wrk testing tool implements workload.Tool interface.
-var _ workload.Tool = &TestingTool{}
+// Ensure wrk Driver implements workload.Driver and workload.CollectorDriver interfaces.
+var (
+	_ workload.Driver          = &Driver{}
+	_ workload.CollectorDriver = &Driver{}
+)
 
-// Run runs the defined testing case set for wrk testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Run(kubeClient kubernetes.Interface, testingCase workload.TestingCase) error {
-	t.CurrentTesting = testingCase
-	t.StartTime = time.Now()
+// WorkloadPod returns the nginx pod of a testing case (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return workload.PodFromTemplate(nginxPod, args)
+}
 
-	// 1. start a workload for the testing case.
-	workloadPodName := workload.BuildWorkloadPodName(t.Workload.GetName(), testingCase.Name)
-	tempWorkloadArgs := struct{ Name, TestingName, Image, Namespace string }{
-		Name:        workloadPodName,
-		TestingName: testingCase.Name,
-		Image:       t.Workload.GetImage(),
-		Namespace:   types.Namespace,
+// TestingPod returns the wrk pod of a testing case, which runs on the node of the
+// workload pod or on another node (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	switch args.TestingName {
+	case benchmarkPodIPDiffNode:
+		return workload.PodFromTemplate(wrkPodAntiAffinity, args)
+	case benchmarkPodIPSameNode:
+		return workload.PodFromTemplate(wrkPodAffinity, args)
 	}
+	return nil, errors.Errorf("unknown testing case %s of wrk", args.TestingName)
+}
 
-	nginxPodBytes, err := workload.ParseTemplate(nginxPod, tempWorkloadArgs)
+// ParseResults parses the results of wrk into metrics, the qps is the headline metric. The testing
+// case fails if the socket errors exceed its maxSocketErrors option (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, err := ParseWrk(log)
 	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", nginxPod, tempWorkloadArgs)
+		return nil, errors.Wrapf(err, "Failed to parse the results of wrk")
 	}
 
-	glog.V(4).Infof("Creating workload %q of testing case %s", workloadPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, nginxPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the %s workload for testing case %s", t.Workload.GetName(), testingCase.Name)
-	}
-
-	// 2. get the podIP and hostIP of the workload until workload is running.
-	glog.V(4).Infof("Geting the podIP and hostIP of workload %s", workloadPodName)
-	podIP, hostIP, err := workload.GetIPs(kubeClient, workloadPodName)
+	maxSocketErrors, err := getMaxSocketErrors(testingCase)
 	if err != nil {
-		return errors.Wrapf(err, "unable to get podIP and hostIP of pod %s created by the %s workload for testing case %s", workloadPodName, t.Workload.GetName(), testingCase.Name)
+		return nil, err
+	}
+	if maxSocketErrors >= 0 && r.SocketErrors.Total() > maxSocketErrors {
+		return nil, errors.Errorf("%d socket errors (connect %d, read %d, write %d, timeout %d) exceed the max %d",
+			r.SocketErrors.Total(), r.SocketErrors.Connect, r.SocketErrors.Read, r.SocketErrors.Write, r.SocketErrors.Timeout, maxSocketErrors)
 	}
-	t.WorkloadNode = hostIP
 
-	// 3. start a testing pod for testing the workload.
-	testingPodName := workload.BuildTestingPodName(t.GetName(), testingCase.Name)
-	testingPod, args := t.findTemplate(testingCase.Name)
-	tempTestingArgs := struct{ Name, TestingName, Image, WorkloadName, Args, PodIP, Namespace string }{
-		Name:         testingPodName,
-		TestingName:  testingCase.Name,
-		Image:        t.GetImage(),
-		WorkloadName: workloadPodName,
-		Args:         workload.FomatArgs(args),
-		PodIP:        podIP,
-		Namespace:    types.Namespace,
+	metrics := []results.Metric{
+		{Name: "qps", Unit: "requests/sec", Value: r.RequestsPerSec},
+		{Name: "latency_avg", Unit: "ms", Value: r.Latency.Avg * 1e3, LowerIsBetter: true},
+		{Name: "latency_stdev", Unit: "ms", Value: r.Latency.Stdev * 1e3, LowerIsBetter: true},
+		{Name: "latency_max", Unit: "ms", Value: r.Latency.Max * 1e3, LowerIsBetter: true},
+		{Name: "thread_qps_avg", Unit: "requests/sec", Value: r.RequestsPerThread.Avg},
+		{Name: "thread_qps_stdev", Unit: "requests/sec", Value: r.RequestsPerThread.Stdev, LowerIsBetter: true},
+		{Name: "thread_qps_max", Unit: "requests/sec", Value: r.RequestsPerThread.Max},
+		{Name: "requests", Unit: "requests", Value: float64(r.Requests)},
+		{Name: "transfer", Unit: "bytes/sec", Value: r.TransferPerSec},
+		{Name: "socket_errors_connect", Unit: "errors", Value: float64(r.SocketErrors.Connect), LowerIsBetter: true},
+		{Name: "socket_errors_read", Unit: "errors", Value: float64(r.SocketErrors.Read), LowerIsBetter: true},
+		{Name: "socket_errors_write", Unit: "errors", Value: float64(r.SocketErrors.Write), LowerIsBetter: true},
+		{Name: "socket_errors_timeout", Unit: "errors", Value: float64(r.SocketErrors.Timeout), LowerIsBetter: true},
+		{Name: "non_2xx_responses", Unit: "responses", Value: float64(r.Non2xx), LowerIsBetter: true},
 	}
+	return metrics, nil
+}
 
-	testingPodBytes, err := workload.ParseTemplate(testingPod, tempTestingArgs)
+// Collectors returns the latency distribution of wrk with --latency as a summary, whose sum is
+// estimated from the average latency (to adhere to workload.CollectorDriver interface).
+func (d *Driver) Collectors(testingCase workload.TestingCase, log []byte) ([]prometheus.Collector, error) {
+	r, err := ParseWrk(log)
 	if err != nil {
-		return errors.Wrapf(err, "unable to parse %v using %v", testingPod, tempTestingArgs)
+		return nil, errors.Wrapf(err, "Failed to parse the results of wrk")
 	}
-
-	glog.V(4).Infof("Creating testing pod %q of testing case %s", testingPodName, testingCase.Name)
-	if err := workload.CreatePod(kubeClient, testingPodBytes); err != nil {
-		return errors.Wrapf(err, "unable to create the testing pod for testing case %s", testingCase.Name)
+	if len(r.LatencyDistribution) == 0 {
+		return nil, nil
 	}
 
-	return nil
+	summary, err := prometheus.NewConstSummary(
+		prometheus.NewDesc("capstan_wrk_latency_seconds", "The latency distribution of wrk testing case", nil, nil),
+		uint64(r.Requests),
+		r.Latency.Avg*float64(r.Requests),
+		r.LatencyDistribution,
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return []prometheus.Collector{constCollector{summary}}, nil
 }
 
-// GetTestingResults gets the testing results of wrk testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingResults(kubeClient kubernetes.Interface) error {
-	name := workload.BuildTestingPodName(t.GetName(), t.CurrentTesting.Name)
-	for {
-		// Sleep between each poll
-		// TODO(mozhuli): Use a watcher instead of polling.
-		time.Sleep(30 * time.Second)
-
-		// Make sure there's a pod.
-		pod, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).Get(name, apismetav1.GetOptions{})
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		// Make sure the pod isn't failing.
-		if isFailing, err := workload.IsPodFailing(pod); isFailing {
-			return err
-		}
-
-		// Check testing has done.
-		body, err := kubeClient.CoreV1().Pods(workload.DefaultNamespace).GetLogs(
-			name,
-			&v1.PodLogOptions{},
-		).Do().Raw()
-
-		if err != nil {
-			return errors.WithStack(err)
-		}
-
-		glog.V(5).Infof("Checking testing has done:\n%s", string(body))
-		if workload.HasTestingDone(body) {
-			glog.V(4).Infof("Testing case %s has done", t.CurrentTesting.Name)
-
-			// export to capstan result directory.
-			outdir := path.Join(types.ResultsDir, types.UUID, "workloads", t.Workload.GetName(), t.GetName(), t.CurrentTesting.Name)
-			if err = os.MkdirAll(outdir, 0755); err != nil {
-				return errors.WithStack(err)
-			}
-
-			outfile := path.Join(outdir, t.GetName()) + ".log"
-			if err = ioutil.WriteFile(outfile, body, 0644); err != nil {
-				return errors.WithStack(err)
-			}
-
-			// export to prometheus pushGateway.
-			data, err := getQPS(body)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to get QPS")
-			}
-
-			qps := prometheus.NewGauge(prometheus.GaugeOpts{
-				Name: "capstan_wrk_qps",
-				Help: "The qps of wrk testing case",
-			})
-			qps.Set(data)
-			if err := push.Collectors(
-				"wrk",
-				map[string]string{
-					"uid":          types.UUID,
-					"provider":     types.Provider,
-					"startTime":    t.StartTime.Format("2006-01-02 15:04:05"),
-					"endTime":      time.Now().Format("2006-01-02 15:04:05"),
-					"workloadNode": t.WorkloadNode,
-					"testingNode":  pod.Status.HostIP,
-					"workloadName": t.Workload.GetName(),
-					"testingName":  t.GetName(),
-					"testingCase":  t.CurrentTesting.Name,
-				},
-				types.PushgatewayEndpoint,
-				qps,
-			); err != nil {
-				return errors.Wrapf(err, "Could not push metrics to Pushgateway")
-			}
-
-			return nil
+// Validate checks the options of the testing cases of a nginx workload.
+func Validate(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		if _, err := getMaxSocketErrors(testingCase); err != nil {
+			optionPath := fldPath.Child("testingTool", "testingCaseSet").Index(i).Child("options", optionMaxSocketErrors)
+			allErrs = append(allErrs, field.Invalid(optionPath, testingCase.Options[optionMaxSocketErrors], "must be a non-negative integer"))
 		}
 	}
+	return allErrs
 }
 
-// Cleanup cleans up all resources created by a testing case for wrk testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) Cleanup(kubeClient kubernetes.Interface) error {
-	if err := workload.DeletePod(kubeClient, workload.BuildTestingPodName(t.GetName(), t.CurrentTesting.Name)); err != nil {
-		return err
+// getMaxSocketErrors returns the maxSocketErrors option of a testing case, or -1 if it is not set.
+func getMaxSocketErrors(testingCase workload.TestingCase) (int64, error) {
+	value, found := testingCase.Options[optionMaxSocketErrors]
+	if !found {
+		return -1, nil
 	}
-	if err := workload.DeletePod(kubeClient, workload.BuildWorkloadPodName(t.Workload.GetName(), t.CurrentTesting.Name)); err != nil {
-		return err
+	max, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || max < 0 {
+		return 0, errors.Errorf("invalid %s option %q of testing case %s", optionMaxSocketErrors, value, testingCase.Name)
 	}
-	return nil
-}
-
-// GetName returns the name of wrk testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetName() string {
-	return t.Name
+	return max, nil
 }
 
-// GetImage returns the image name of wrk testing tool (to adhere to workload.Tool interface).
-func (t *TestingTool) GetImage() string {
-	return t.Image
+// constCollector collects a constant metric.
+type constCollector struct {
+	metric prometheus.Metric
 }
 
-// GetSteps returns the steps between each testing case (to adhere to workload.Tool interface).
-func (t *TestingTool) GetSteps() time.Duration {
-	return t.Steps
+// Describe sends the descriptor of the metric (to adhere to prometheus.Collector interface).
+func (c constCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.metric.Desc()
 }
 
-// GetTestingCaseSet returns the testing case set which the wrk testing tool will run (to adhere to workload.Tool interface).
-func (t *TestingTool) GetTestingCaseSet() []workload.TestingCase {
-	return t.TestingCaseSet
-}
-
-// findTemplate returns the true testing tool template and arguments for different testing cases.
-func (t *TestingTool) findTemplate(name string) (string, string) {
-	if t.CurrentTesting.Name == benchmarkPodIPDiffNode {
-		return wrkPodAntiAffinity, t.CurrentTesting.TestingToolArgs
-	}
-	if t.CurrentTesting.Name == benchmarkPodIPSameNode {
-		return wrkPodAffinity, t.CurrentTesting.TestingToolArgs
-	}
-	return "", ""
-}
-
-func getQPS(data []byte) (float64, error) {
-	scanner := bufio.NewScanner(bytes.NewBuffer(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.Contains(line, "Requests/sec") {
-			qps, err := strconv.ParseFloat(strings.Fields(line)[1], 64)
-			if err != nil {
-				return 0, errors.WithStack(err)
-			}
-			return qps, nil
-		}
-	}
-	return 0, errors.Errorf("results not contain Requests/sec")
+// Collect sends the metric (to adhere to prometheus.Collector interface).
+func (c constCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- c.metric
 }
@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WrkStats are the thread stats of wrk: the average, the standard deviation, the max
+// and the percentage of the samples within one standard deviation.
+type WrkStats struct {
+	Avg           float64
+	Stdev         float64
+	Max           float64
+	WithinStdevPc float64
+}
+
+// WrkSocketErrors are the socket errors counted by wrk.
+type WrkSocketErrors struct {
+	Connect int64
+	Read    int64
+	Write   int64
+	Timeout int64
+}
+
+// Total returns the number of all socket errors.
+func (e WrkSocketErrors) Total() int64 {
+	return e.Connect + e.Read + e.Write + e.Timeout
+}
+
+// WrkResult is the result printed by wrk.
+type WrkResult struct {
+	Threads     int
+	Connections int
+	// Latency is the latency of the requests in seconds.
+	Latency WrkStats
+	// RequestsPerThread is the requests per second of a thread.
+	RequestsPerThread WrkStats
+	// LatencyDistribution maps the quantiles of the latency to seconds, it is only printed
+	// by wrk with --latency.
+	LatencyDistribution map[float64]float64
+	Requests            int64
+	Duration            time.Duration
+	// BytesRead is the bytes read in the duration.
+	BytesRead    float64
+	SocketErrors WrkSocketErrors
+	// Non2xx is the number of the responses whose status is neither 2xx nor 3xx.
+	Non2xx         int64
+	RequestsPerSec float64
+	// TransferPerSec is the bytes read per second.
+	TransferPerSec float64
+}
+
+var (
+	latencyUnits = map[string]float64{"us": 1e-6, "ms": 1e-3, "s": 1, "m": 60, "h": 3600}
+	countUnits   = map[string]float64{"": 1, "k": 1e3, "M": 1e6, "G": 1e9}
+	byteUnits    = map[string]float64{"B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30, "TB": 1 << 40}
+)
+
+// ParseWrk parses the output of wrk, it fails if the output has no Requests/sec.
+func ParseWrk(data []byte) (*WrkResult, error) {
+	r := &WrkResult{}
+	foundQPS := false
+	inDistribution := false
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch {
+		case inDistribution && len(fields) == 2 && strings.HasSuffix(fields[0], "%"):
+			var pc, latency float64
+			if pc, err = strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64); err == nil {
+				if latency, err = parseUnit(fields[1], latencyUnits); err == nil {
+					r.LatencyDistribution[pc/100] = latency
+				}
+			}
+		case strings.HasPrefix(line, "Latency Distribution"):
+			inDistribution = true
+			r.LatencyDistribution = map[float64]float64{}
+			continue
+		case strings.Contains(line, "threads and") && strings.HasSuffix(line, "connections"):
+			// 10 threads and 100 connections
+			if r.Threads, err = strconv.Atoi(fields[0]); err == nil {
+				r.Connections, err = strconv.Atoi(fields[3])
+			}
+		case fields[0] == "Latency" && len(fields) == 5:
+			r.Latency, err = parseStats(fields[1:], latencyUnits)
+		case fields[0] == "Req/Sec" && len(fields) == 5:
+			r.RequestsPerThread, err = parseStats(fields[1:], countUnits)
+		case len(fields) >= 6 && fields[1] == "requests" && fields[2] == "in":
+			// 22464657 requests in 30.00s, 17.76GB read
+			if r.Requests, err = strconv.ParseInt(fields[0], 10, 64); err == nil {
+				var seconds float64
+				if seconds, err = parseUnit(strings.TrimSuffix(fields[3], ","), latencyUnits); err == nil {
+					r.Duration = time.Duration(seconds * float64(time.Second))
+					r.BytesRead, err = parseUnit(fields[4], byteUnits)
+				}
+			}
+		case strings.HasPrefix(line, "Socket errors:"):
+			// Socket errors: connect 0, read 0, write 0, timeout 0
+			r.SocketErrors, err = parseSocketErrors(strings.TrimPrefix(line, "Socket errors:"))
+		case strings.HasPrefix(line, "Non-2xx or 3xx responses:"):
+			r.Non2xx, err = strconv.ParseInt(fields[len(fields)-1], 10, 64)
+		case fields[0] == "Requests/sec:" && len(fields) == 2:
+			r.RequestsPerSec, err = strconv.ParseFloat(fields[1], 64)
+			foundQPS = err == nil
+		case fields[0] == "Transfer/sec:" && len(fields) == 2:
+			r.TransferPerSec, err = parseUnit(fields[1], byteUnits)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid line %q", line)
+		}
+		inDistribution = inDistribution && strings.HasSuffix(fields[0], "%")
+	}
+
+	if !foundQPS {
+		return nil, errors.Errorf("results not contain Requests/sec")
+	}
+	return r, nil
+}
+
+// parseStats parses the avg, stdev, max and +/- stdev columns of the thread stats.
+func parseStats(fields []string, units map[string]float64) (WrkStats, error) {
+	var stats WrkStats
+	var err error
+	if stats.Avg, err = parseUnit(fields[0], units); err != nil {
+		return stats, err
+	}
+	if stats.Stdev, err = parseUnit(fields[1], units); err != nil {
+		return stats, err
+	}
+	if stats.Max, err = parseUnit(fields[2], units); err != nil {
+		return stats, err
+	}
+	stats.WithinStdevPc, err = strconv.ParseFloat(strings.TrimSuffix(fields[3], "%"), 64)
+	return stats, errors.WithStack(err)
+}
+
+func parseSocketErrors(s string) (WrkSocketErrors, error) {
+	var socketErrors WrkSocketErrors
+	for _, item := range strings.Split(s, ",") {
+		fields := strings.Fields(item)
+		if len(fields) != 2 {
+			return socketErrors, errors.Errorf("invalid socket errors %q", item)
+		}
+		n, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return socketErrors, errors.WithStack(err)
+		}
+		switch fields[0] {
+		case "connect":
+			socketErrors.Connect = n
+		case "read":
+			socketErrors.Read = n
+		case "write":
+			socketErrors.Write = n
+		case "timeout":
+			socketErrors.Timeout = n
+		}
+	}
+	return socketErrors, nil
+}
+
+// parseUnit parses a number with one of the units as its suffix, e.g. 1.23ms, and
+// returns it in the base unit.
+func parseUnit(s string, units map[string]float64) (float64, error) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	multiplier, found := units[s[i:]]
+	if !found {
+		return 0, errors.Errorf("unknown unit of %q", s)
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return value * multiplier, nil
+}
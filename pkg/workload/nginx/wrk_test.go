@@ -0,0 +1,144 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nginx
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestParseWrk(t *testing.T) {
+	tests := []struct {
+		file string
+		want WrkResult
+	}{
+		{
+			file: "wrk-latency.txt",
+			want: WrkResult{
+				Threads:           10,
+				Connections:       100,
+				Latency:           WrkStats{Avg: 812.47e-6, Stdev: 1.23e-3, Max: 52.31e-3, WithinStdevPc: 94.12},
+				RequestsPerThread: WrkStats{Avg: 12.34e3, Stdev: 1.05e3, Max: 16.79e3, WithinStdevPc: 71.33},
+				LatencyDistribution: map[float64]float64{
+					0.5: 612e-6, 0.75: 803e-6, 0.9: 1.12e-3, 0.99: 6.54e-3,
+				},
+				Requests:       3686754,
+				Duration:       30100 * time.Millisecond,
+				BytesRead:      2.92 * (1 << 30),
+				RequestsPerSec: 122483.52,
+				TransferPerSec: 99.38 * (1 << 20),
+			},
+		},
+		{
+			file: "wrk-socket-errors.txt",
+			want: WrkResult{
+				Threads:           12,
+				Connections:       400,
+				Latency:           WrkStats{Avg: 1.21, Stdev: 350.12e-3, Max: 2, WithinStdevPc: 68.75},
+				RequestsPerThread: WrkStats{Avg: 25.13, Stdev: 15.2, Max: 90, WithinStdevPc: 70},
+				Requests:          16004,
+				Duration:          time.Minute,
+				BytesRead:         12.94 * (1 << 20),
+				SocketErrors:      WrkSocketErrors{Connect: 157, Read: 12, Write: 0, Timeout: 1043},
+				Non2xx:            25,
+				RequestsPerSec:    266.59,
+				TransferPerSec:    220.71 * (1 << 10),
+			},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseWrk(readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		want := test.want
+		if got.Threads != want.Threads || got.Connections != want.Connections || got.Requests != want.Requests ||
+			got.Duration != want.Duration || got.SocketErrors != want.SocketErrors || got.Non2xx != want.Non2xx {
+			t.Errorf("%s: got %+v, want %+v", test.file, got, want)
+		}
+		for _, stats := range [][2]WrkStats{{got.Latency, want.Latency}, {got.RequestsPerThread, want.RequestsPerThread}} {
+			if !near(stats[0].Avg, stats[1].Avg) || !near(stats[0].Stdev, stats[1].Stdev) ||
+				!near(stats[0].Max, stats[1].Max) || !near(stats[0].WithinStdevPc, stats[1].WithinStdevPc) {
+				t.Errorf("%s: thread stats %+v, want %+v", test.file, stats[0], stats[1])
+			}
+		}
+		if !near(got.BytesRead, want.BytesRead) || !near(got.RequestsPerSec, want.RequestsPerSec) || !near(got.TransferPerSec, want.TransferPerSec) {
+			t.Errorf("%s: read %v, qps %v, transfer %v, want %v, %v, %v", test.file,
+				got.BytesRead, got.RequestsPerSec, got.TransferPerSec, want.BytesRead, want.RequestsPerSec, want.TransferPerSec)
+		}
+		if len(got.LatencyDistribution) != len(want.LatencyDistribution) {
+			t.Errorf("%s: latency distribution %v, want %v", test.file, got.LatencyDistribution, want.LatencyDistribution)
+		}
+		for q, latency := range want.LatencyDistribution {
+			if !near(got.LatencyDistribution[q], latency) {
+				t.Errorf("%s: latency of quantile %v is %v, want %v", test.file, q, got.LatencyDistribution[q], latency)
+			}
+		}
+	}
+
+	if _, err := ParseWrk([]byte("unable to connect to 10.244.1.5:80 Connection refused\n")); err == nil {
+		t.Errorf("parsing the output without Requests/sec succeeded")
+	}
+}
+
+func TestParseResultsSocketErrors(t *testing.T) {
+	tests := []struct {
+		file    string
+		options map[string]string
+		fail    bool
+	}{
+		{"wrk-latency.txt", map[string]string{optionMaxSocketErrors: "0"}, false},
+		{"wrk-socket-errors.txt", nil, false},
+		{"wrk-socket-errors.txt", map[string]string{optionMaxSocketErrors: "1212"}, false},
+		{"wrk-socket-errors.txt", map[string]string{optionMaxSocketErrors: "1211"}, true},
+	}
+	for _, test := range tests {
+		testingCase := workload.TestingCase{Name: "benchmarkPodIPDiffNode", Options: test.options}
+		metrics, err := (&Driver{}).ParseResults(testingCase, readTestdata(t, test.file))
+		if test.fail {
+			if err == nil {
+				t.Errorf("%s with options %v: socket errors did not fail the testing case", test.file, test.options)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s with options %v: %v", test.file, test.options, err)
+			continue
+		}
+		if metrics[0].Name != "qps" {
+			t.Errorf("%s: the headline metric is %s, want qps", test.file, metrics[0].Name)
+		}
+	}
+}
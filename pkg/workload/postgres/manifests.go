@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+const (
+	postgresPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Name }}
+  annotations:
+    capstan-workload: postgres
+    capstan-testingcase: {{ .TestingName }}
+  labels:
+    component: capstan
+    testing: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  containers:
+  - name: workload-postgres
+    image: {{ .Image }}
+    imagePullPolicy: Always
+    env:
+    - name: POSTGRES_HOST_AUTH_METHOD
+      value: trust
+    ports:
+    - containerPort: 5432
+    readinessProbe:
+      exec:
+        command: ["pg_isready", "-U", "postgres", "-h", "127.0.0.1"]
+      periodSeconds: 2
+  restartPolicy: Never
+  tolerations:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/master
+    operator: Exists
+  - key: CriticalAddonsOnly
+    operator: Exists
+`
+	pgbenchPodAntiAffinity = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Name }}
+  annotations:
+    capstan-testing: pgbench
+    capstan-testingcase: {{ .TestingName }}
+  labels:
+    component: capstan
+    testing: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  affinity:
+    podAntiAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+      - labelSelector:
+          matchExpressions:
+          - key: testing
+            operator: In
+            values:
+            -  {{ .WorkloadName }}
+        topologyKey: "kubernetes.io/hostname"
+  containers:
+  - name: testing-pgbench
+    image: {{ .Image }}
+    imagePullPolicy: Always
+    args: [{{ .Args }}]
+    env:
+    - name: ENDPOINT
+      value: {{ .PodIP }}
+    - name: SCALE
+      value: "{{ .Scale }}"
+  restartPolicy: Never
+  tolerations:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/master
+    operator: Exists
+  - key: CriticalAddonsOnly
+    operator: Exists
+`
+	pgbenchPodAffinity = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: {{ .Name }}
+  annotations:
+    capstan-testing: pgbench
+    capstan-testingcase: {{ .TestingName }}
+  labels:
+    component: capstan
+    testing: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+  affinity:
+    podAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+      - labelSelector:
+          matchExpressions:
+          - key: testing
+            operator: In
+            values:
+            -  {{ .WorkloadName }}
+        topologyKey: "kubernetes.io/hostname"
+  containers:
+  - name: testing-pgbench
+    image: {{ .Image }}
+    imagePullPolicy: Always
+    args: [{{ .Args }}]
+    env:
+    - name: ENDPOINT
+      value: {{ .PodIP }}
+    - name: SCALE
+      value: "{{ .Scale }}"
+  restartPolicy: Never
+  tolerations:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/master
+    operator: Exists
+  - key: CriticalAddonsOnly
+    operator: Exists
+`
+)
@@ -0,0 +1,160 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Statement is the average latency of a statement of the pgbench script.
+type Statement struct {
+	Statement string
+	// Latency is the average latency of the statement in milliseconds.
+	Latency float64
+	// Failures is the number of the failures of the statement, it is only printed by pgbench 15 and later.
+	Failures int64
+}
+
+// Result is the result printed by pgbench.
+type Result struct {
+	TransactionType string
+	Scale           int
+	Clients         int
+	Threads         int
+	Transactions    int64
+	// FailedTransactions is only printed by pgbench 15 and later.
+	FailedTransactions int64
+	// LatencyAverage and LatencyStddev are in milliseconds, the stddev is only printed by
+	// pgbench with -P, -R or --latency-limit, HasLatencyStddev is true then.
+	LatencyAverage   float64
+	LatencyStddev    float64
+	HasLatencyStddev bool
+	// TPS excludes the initial connection time if pgbench prints it.
+	TPS float64
+	// Statements are only printed by pgbench with -r.
+	Statements []Statement
+}
+
+// ParsePgbench parses the output of pgbench, it fails if the output has no tps.
+func ParsePgbench(data []byte) (*Result, error) {
+	r := &Result{}
+	foundTPS := false
+	inStatements := false
+	withFailures := false
+
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value := splitLine(line)
+
+		var err error
+		switch {
+		case strings.HasPrefix(line, "statement latencies in milliseconds"):
+			inStatements = true
+			withFailures = strings.Contains(line, "failures")
+			continue
+		case inStatements:
+			inStatements, err = r.addStatement(line, withFailures)
+		case key == "transaction type":
+			r.TransactionType = value
+		case key == "scaling factor":
+			r.Scale, err = strconv.Atoi(value)
+		case key == "number of clients":
+			r.Clients, err = strconv.Atoi(value)
+		case key == "number of threads":
+			r.Threads, err = strconv.Atoi(value)
+		case key == "number of transactions actually processed":
+			// 123456 or 123456/200000 with -t.
+			r.Transactions, err = strconv.ParseInt(strings.SplitN(value, "/", 2)[0], 10, 64)
+		case key == "number of failed transactions":
+			// 0 (0.000%)
+			r.FailedTransactions, err = strconv.ParseInt(strings.Fields(value)[0], 10, 64)
+		case key == "latency average":
+			r.LatencyAverage, err = parseMs(value)
+		case key == "latency stddev":
+			r.LatencyStddev, err = parseMs(value)
+			r.HasLatencyStddev = err == nil
+		case key == "tps":
+			// tps = 2057.123456 (without initial connection time), older versions print the
+			// tps including and excluding connections establishing.
+			if foundTPS && strings.Contains(value, "including") {
+				break
+			}
+			r.TPS, err = strconv.ParseFloat(strings.Fields(value)[0], 64)
+			foundTPS = err == nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid line %q", line)
+		}
+	}
+
+	if !foundTPS {
+		return nil, errors.Errorf("results not contain tps")
+	}
+	return r, nil
+}
+
+// addStatement adds a line of the statement latencies, it returns false if the line
+// is not a statement latency, i.e. the statement latencies have ended.
+func (r *Result) addStatement(line string, withFailures bool) (bool, error) {
+	fields := strings.Fields(line)
+	minFields := 2
+	if withFailures {
+		minFields = 3
+	}
+	if len(fields) < minFields {
+		return false, nil
+	}
+	latency, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return false, nil
+	}
+
+	s := Statement{Latency: latency}
+	statement := strings.TrimSpace(line[len(fields[0]):])
+	if withFailures {
+		if s.Failures, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+			return false, errors.WithStack(err)
+		}
+		statement = strings.TrimSpace(statement[len(fields[1]):])
+	}
+	s.Statement = statement
+	r.Statements = append(r.Statements, s)
+	return true, nil
+}
+
+// splitLine splits a line of pgbench into its key and value, e.g. "scaling factor: 10"
+// and "latency average = 4.861 ms".
+func splitLine(line string) (string, string) {
+	for _, sep := range []string{" = ", ": "} {
+		if i := strings.Index(line, sep); i > 0 {
+			return line[:i], strings.TrimSpace(line[i+len(sep):])
+		}
+	}
+	return "", ""
+}
+
+// parseMs parses a duration in milliseconds, e.g. "4.861 ms".
+func parseMs(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "ms")), 64)
+	return v, errors.WithStack(err)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParsePgbench(t *testing.T) {
+	tests := []struct {
+		file       string
+		want       Result
+		statements int
+		last       Statement
+	}{
+		{
+			file: "pgbench16.txt",
+			want: Result{
+				TransactionType: "<builtin: TPC-B (sort of)>",
+				Scale:           10,
+				Clients:         8,
+				Threads:         2,
+				Transactions:    61712,
+				LatencyAverage:  3.888,
+				TPS:             2057.632081,
+			},
+			statements: 11,
+			last:       Statement{Statement: "END;", Latency: 0.522},
+		},
+		{
+			// pgbench before 14 prints the tps including and excluding connections establishing.
+			file: "pgbench12.txt",
+			want: Result{
+				TransactionType:  "<builtin: select only>",
+				Scale:            10,
+				Clients:          8,
+				Threads:          2,
+				Transactions:     612594,
+				LatencyAverage:   0.392,
+				LatencyStddev:    0.118,
+				HasLatencyStddev: true,
+				TPS:              20419.876543,
+			},
+			statements: 2,
+			last:       Statement{Statement: "SELECT abalance FROM pgbench_accounts WHERE aid = :aid;", Latency: 0.387},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParsePgbench(readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		statements := got.Statements
+		got.Statements = nil
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.file, *got, test.want)
+		}
+		if len(statements) != test.statements {
+			t.Errorf("%s: %d statements, want %d", test.file, len(statements), test.statements)
+			continue
+		}
+		if statements[len(statements)-1] != test.last {
+			t.Errorf("%s: last statement %+v, want %+v", test.file, statements[len(statements)-1], test.last)
+		}
+	}
+
+	if _, err := ParsePgbench([]byte("pgbench: error: connection to server at \"10.244.1.9\", port 5432 failed: Connection refused\n")); err == nil {
+		t.Errorf("parsing the output without tps succeeded")
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	metrics, err := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "pgbench16.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics[0].Name != "tps" || metrics[0].Value != 2057.632081 {
+		t.Errorf("headline metric %+v, want tps 2057.632081", metrics[0])
+	}
+	// 4 metrics of the run and the latency of each of the 11 statements, pgbench has not
+	// printed the latency stddev without -P.
+	if len(metrics) != 4+11 || metrics[len(metrics)-1].Name != "statement_10_latency" {
+		t.Errorf("metrics %+v, want 4 metrics and 11 statement latencies", metrics)
+	}
+	for _, m := range metrics {
+		if m.Name == "latency_stddev" {
+			t.Errorf("latency_stddev published without being printed by pgbench")
+		}
+	}
+
+	metrics, err = (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "pgbench12.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, m := range metrics {
+		if m.Name == "latency_stddev" {
+			found = m.Value == 0.118
+		}
+	}
+	if !found {
+		t.Errorf("metrics %+v, want latency_stddev 0.118", metrics)
+	}
+}
+
+func TestTestingPodArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"-c 8 -T 30", []string{"-c", "8", "-T", "30", "-b", "tpcb-like", "-r", "-P", "10"}},
+		{"-c 8 -T 30 -S -P 5", []string{"-c", "8", "-T", "30", "-S", "-P", "5", "-r"}},
+		{"--progress=1 --report-per-command -f /bench.sql", []string{"--progress=1", "--report-per-command", "-f", "/bench.sql"}},
+	}
+	for _, test := range tests {
+		pod, err := (&Driver{}).TestingPod(workload.PodArgs{
+			Name:         "capstan-postgres-pgbench-benchmarkreadwritesamenode-testing",
+			TestingName:  benchmarkReadWriteSameNode,
+			Image:        "wadelee/pgbench",
+			Namespace:    "capstan",
+			WorkloadName: "capstan-postgres-benchmarkreadwritesamenode-workload",
+			PodIP:        "10.244.1.9",
+			TestingCase:  workload.TestingCase{Name: benchmarkReadWriteSameNode, TestingToolArgs: test.args},
+		})
+		if err != nil {
+			t.Errorf("%q: %v", test.args, err)
+			continue
+		}
+		if got := pod.Spec.Containers[0].Args; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: args %q, want %q", test.args, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// ToolName is the name of the testing tool of the postgres workload.
+	ToolName                      = "pgbench"
+	benchmarkReadWriteSameNode    = "benchmarkReadWriteSameNode"
+	benchmarkReadWriteDiffNode    = "benchmarkReadWriteDiffNode"
+	benchmarkReadOnlySameNode     = "benchmarkReadOnlySameNode"
+	benchmarkReadOnlyDiffNode     = "benchmarkReadOnlyDiffNode"
+	benchmarkSimpleUpdateSameNode = "benchmarkSimpleUpdateSameNode"
+	benchmarkSimpleUpdateDiffNode = "benchmarkSimpleUpdateDiffNode"
+
+	// optionScale is the option of a testing case setting the scale factor which pgbench
+	// initializes the database with, it defaults to 1.
+	optionScale = "scale"
+	// optionStorageSize is the option of a testing case backing the data of postgres by a
+	// persistent volume claim of the size, e.g. 10Gi, the data is in an emptyDir if it is not set.
+	optionStorageSize = "storageSize"
+	// optionStorageClass is the option of a testing case setting the storage class of the
+	// persistent volume claim, it defaults to the default storage class.
+	optionStorageClass = "storageClass"
+
+	// StatementsFile is the average latency of every statement of pgbench as CSV in the
+	// results directory of a testing case.
+	StatementsFile = "statements.csv"
+
+	// defaultProgress is the interval in seconds of the progress report of pgbench unless the
+	// testing case sets -P, pgbench only prints the latency stddev with it.
+	defaultProgress = "10"
+
+	dataVolume = "data"
+	dataPath   = "/var/lib/postgresql/data"
+)
+
+// TestingCaseSet is the list of postgres defined testing cases.
+var TestingCaseSet = []string{
+	"benchmarkReadWriteSameNode",
+	"benchmarkReadWriteDiffNode",
+	"benchmarkReadOnlySameNode",
+	"benchmarkReadOnlyDiffNode",
+	"benchmarkSimpleUpdateSameNode",
+	"benchmarkSimpleUpdateDiffNode",
+}
+
+// builtinScripts are the builtin scripts of pgbench run by the testing cases.
+var builtinScripts = map[string]string{
+	benchmarkReadWriteSameNode:    "tpcb-like",
+	benchmarkReadWriteDiffNode:    "tpcb-like",
+	benchmarkReadOnlySameNode:     "select-only",
+	benchmarkReadOnlyDiffNode:     "select-only",
+	benchmarkSimpleUpdateSameNode: "simple-update",
+	benchmarkSimpleUpdateDiffNode: "simple-update",
+}
+
+// Driver supplies the manifests and the result parser of the pgbench testing tool.
+type Driver struct{}
+
+// Ensure pgbench Driver implements workload.Driver, workload.WorkloadConditioner,
+// workload.VolumeClaimer and workload.ResultsWriter interfaces.
+var (
+	_ workload.Driver              = &Driver{}
+	_ workload.WorkloadConditioner = &Driver{}
+	_ workload.VolumeClaimer       = &Driver{}
+	_ workload.ResultsWriter       = &Driver{}
+)
+
+// WorkloadPod returns the postgres pod of a testing case, whose data is in the persistent
+// volume claim of the testing case if it has one (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	pod, err := workload.PodFromTemplate(postgresPod, args)
+	if err != nil {
+		return nil, err
+	}
+	if args.TestingCase.Options[optionStorageSize] == "" {
+		return pod, nil
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: dataVolume,
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: args.Name},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: dataVolume, MountPath: dataPath})
+	// the root of a volume may have a lost+found directory, which initdb refuses.
+	container.Env = append(container.Env, v1.EnvVar{Name: "PGDATA", Value: dataPath + "/pgdata"})
+	return pod, nil
+}
+
+// WorkloadCondition returns the condition of the postgres pod, which is ready once it
+// accepts connections (to adhere to workload.WorkloadConditioner interface).
+func (d *Driver) WorkloadCondition() workload.PodCondition {
+	return workload.PodReady
+}
+
+// VolumeClaim returns the persistent volume claim of the postgres pod of a testing case
+// with the storageSize option (to adhere to workload.VolumeClaimer interface).
+func (d *Driver) VolumeClaim(args workload.PodArgs) (*v1.PersistentVolumeClaim, error) {
+	size := args.TestingCase.Options[optionStorageSize]
+	if size == "" {
+		return nil, nil
+	}
+	return workload.NewPersistentVolumeClaim(args.TestingCase.Options[optionStorageClass], size)
+}
+
+// TestingPod returns the pgbench pod of a testing case, which runs on the node of the
+// workload pod or on another node. pgbench runs the builtin script of the testing case
+// unless the testingToolArgs select a script, always reports the latency of every statement,
+// and reports its progress every 10 seconds unless -P is given (to adhere to workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	script, found := builtinScripts[args.TestingName]
+	if !found {
+		return nil, errors.Errorf("unknown testing case %s of pgbench", args.TestingName)
+	}
+	scale, err := getScale(args.TestingCase)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := strings.Fields(args.TestingCase.TestingToolArgs)
+	if !hasFlag(flags, "-b", "--builtin", "-f", "--file", "-S", "--select-only", "-N", "--skip-some-updates") {
+		flags = append(flags, "-b", script)
+	}
+	if !hasFlag(flags, "-r", "--report-latencies", "--report-per-command") {
+		flags = append(flags, "-r")
+	}
+	if !hasFlag(flags, "-P", "--progress") {
+		flags = append(flags, "-P", defaultProgress)
+	}
+	args.Args = workload.FomatArgs(strings.Join(flags, " "))
+
+	template := pgbenchPodAffinity
+	if strings.HasSuffix(args.TestingName, "DiffNode") {
+		template = pgbenchPodAntiAffinity
+	}
+	return workload.PodFromTemplate(template, struct {
+		workload.PodArgs
+		Scale int
+	}{args, scale})
+}
+
+// ParseResults parses the results of pgbench into metrics, the tps is the headline metric
+// (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	r, err := ParsePgbench(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get tps")
+	}
+
+	metrics := []results.Metric{
+		{Name: "tps", Unit: "transactions/sec", Value: r.TPS},
+		{Name: "latency_avg", Unit: "ms", Value: r.LatencyAverage, LowerIsBetter: true},
+		{Name: "transactions", Unit: "transactions", Value: float64(r.Transactions)},
+		{Name: "failed_transactions", Unit: "transactions", Value: float64(r.FailedTransactions), LowerIsBetter: true},
+	}
+	if r.HasLatencyStddev {
+		metrics = append(metrics, results.Metric{Name: "latency_stddev", Unit: "ms", Value: r.LatencyStddev, LowerIsBetter: true})
+	}
+	for i, s := range r.Statements {
+		metrics = append(metrics, results.Metric{Name: "statement_" + strconv.Itoa(i) + "_latency", Unit: "ms", Value: s.Latency, LowerIsBetter: true})
+	}
+	return metrics, nil
+}
+
+// WriteResults writes the latency of every statement of pgbench as CSV to the results
+// directory of the testing case, the index of a statement is the one in its metric name
+// (to adhere to workload.ResultsWriter interface).
+func (d *Driver) WriteResults(testingCase workload.TestingCase, log []byte, dir string) error {
+	r, err := ParsePgbench(log)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get tps")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"index", "latency_ms", "failures", "statement"})
+	for i, s := range r.Statements {
+		w.Write([]string{strconv.Itoa(i), strconv.FormatFloat(s.Latency, 'f', -1, 64), strconv.FormatInt(s.Failures, 10), s.Statement})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(ioutil.WriteFile(path.Join(dir, StatementsFile), buf.Bytes(), 0644))
+}
+
+// Validate checks the options of the testing cases of a postgres workload.
+func Validate(fldPath *field.Path, wl workload.Workload) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, testingCase := range wl.TestingTool.TestingCaseSet {
+		optionsPath := fldPath.Child("testingTool", "testingCaseSet").Index(i).Child("options")
+		if _, err := getScale(testingCase); err != nil {
+			allErrs = append(allErrs, field.Invalid(optionsPath.Child(optionScale), testingCase.Options[optionScale], "must be a positive integer"))
+		}
+		if size := testingCase.Options[optionStorageSize]; size != "" {
+			if _, err := workload.NewPersistentVolumeClaim("", size); err != nil {
+				allErrs = append(allErrs, field.Invalid(optionsPath.Child(optionStorageSize), size, "must be a quantity, e.g. 10Gi"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// getScale returns the scale option of a testing case.
+func getScale(testingCase workload.TestingCase) (int, error) {
+	value, found := testingCase.Options[optionScale]
+	if !found {
+		return 1, nil
+	}
+	scale, err := strconv.Atoi(value)
+	if err != nil || scale < 1 {
+		return 0, errors.Errorf("invalid %s option %q of testing case %s", optionScale, value, testingCase.Name)
+	}
+	return scale, nil
+}
+
+// hasFlag returns whether the flags have any of the names.
+func hasFlag(flags []string, names ...string) bool {
+	for _, f := range flags {
+		for _, name := range names {
+			if f == name || strings.HasPrefix(f, name+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
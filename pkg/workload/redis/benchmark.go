@@ -0,0 +1,106 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Test is a test of redis-benchmark, i.e. a row of its CSV results. The latencies are in
+// milliseconds and only printed by redis-benchmark 6.0 and later.
+type Test struct {
+	Name           string
+	RequestsPerSec float64
+	HasLatency     bool
+	AvgLatency     float64
+	MinLatency     float64
+	P50Latency     float64
+	P95Latency     float64
+	P99Latency     float64
+	MaxLatency     float64
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// MetricName returns the name of the test in metric names, e.g. lpush for
+// "LPUSH (needed to benchmark LPOP)".
+func (t Test) MetricName() string {
+	name := t.Name
+	if i := strings.Index(name, " ("); i > 0 {
+		name = name[:i]
+	}
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// ParseBenchmark parses the CSV results of redis-benchmark --csv, it fails if the log has no tests.
+// The rows which are not tests, e.g. the header and the TestingDoneMark, are skipped.
+func ParseBenchmark(data []byte) ([]Test, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var tests []Test
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid CSV results of redis-benchmark")
+		}
+		if len(record) < 2 {
+			continue
+		}
+		values, err := parseFloats(record[1:])
+		if err != nil {
+			// the header or other output.
+			continue
+		}
+
+		t := Test{Name: record[0], RequestsPerSec: values[0]}
+		if len(values) >= 7 {
+			t.HasLatency = true
+			t.AvgLatency, t.MinLatency, t.P50Latency, t.P95Latency, t.P99Latency, t.MaxLatency =
+				values[1], values[2], values[3], values[4], values[5], values[6]
+		}
+		tests = append(tests, t)
+	}
+
+	if len(tests) == 0 {
+		return nil, errors.Errorf("results not contain the tests of redis-benchmark")
+	}
+	return tests, nil
+}
+
+func parseFloats(ss []string) ([]float64, error) {
+	var values []float64
+	for _, s := range ss {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
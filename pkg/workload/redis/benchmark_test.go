@@ -0,0 +1,111 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseBenchmark(t *testing.T) {
+	tests := []struct {
+		file  string
+		count int
+		first Test
+		last  Test
+		names []string
+	}{
+		{
+			file:  "redis7.csv",
+			count: 8,
+			first: Test{Name: "PING_INLINE", RequestsPerSec: 181818.19, HasLatency: true, AvgLatency: 0.15, MinLatency: 0.048, P50Latency: 0.143, P95Latency: 0.231, P99Latency: 0.311, MaxLatency: 1.327},
+			last:  Test{Name: "MSET (10 keys)", RequestsPerSec: 147058.83, HasLatency: true, AvgLatency: 0.279, MinLatency: 0.088, P50Latency: 0.271, P95Latency: 0.399, P99Latency: 0.495, MaxLatency: 1.431},
+			names: []string{"ping_inline", "ping_mbulk", "set", "get", "lpush", "lpush", "lrange_100", "mset"},
+		},
+		{
+			// redis-benchmark before 6.0 prints no header and no latencies.
+			file:  "redis5.csv",
+			count: 6,
+			first: Test{Name: "PING_INLINE", RequestsPerSec: 87719.3},
+			last:  Test{Name: "MSET (10 keys)", RequestsPerSec: 64935.07},
+			names: []string{"ping_inline", "ping_bulk", "set", "get", "lrange_100", "mset"},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseBenchmark(readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if len(got) != test.count {
+			t.Errorf("%s: %d tests, want %d", test.file, len(got), test.count)
+			continue
+		}
+		if got[0] != test.first || got[len(got)-1] != test.last {
+			t.Errorf("%s: first and last tests %+v, %+v, want %+v, %+v", test.file, got[0], got[len(got)-1], test.first, test.last)
+		}
+		for i, name := range test.names {
+			if got[i].MetricName() != name {
+				t.Errorf("%s: metric name of %q is %q, want %q", test.file, got[i].Name, got[i].MetricName(), name)
+			}
+		}
+	}
+
+	if _, err := ParseBenchmark([]byte("Could not connect to Redis at 10.244.1.5:6379: Connection refused\n")); err == nil {
+		t.Errorf("parsing the output without tests succeeded")
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	metrics, err := (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "redis7.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics[0].Name != "rps" || metrics[0].Value != 181818.19 {
+		t.Errorf("headline metric %+v, want rps of PING_INLINE", metrics[0])
+	}
+	// the headline and 7 metrics of each of the 8 tests, but the second LPUSH.
+	if len(metrics) != 1+7*7 {
+		t.Errorf("%d metrics, want %d", len(metrics), 1+7*7)
+	}
+	names := map[string]bool{}
+	for _, metric := range metrics {
+		if names[metric.Name] {
+			t.Errorf("duplicate metric %s", metric.Name)
+		}
+		names[metric.Name] = true
+	}
+
+	metrics, err = (&Driver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "redis5.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metrics) != 1+6 || metrics[6].Name != "mset_rps" {
+		t.Errorf("metrics %+v, want the headline and the rps of 6 tests", metrics)
+	}
+}
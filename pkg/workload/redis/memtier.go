@@ -0,0 +1,148 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MemtierStats is the stats of a type of requests of memtier_benchmark, i.e. a row of its
+// ALL STATS table, e.g. Sets, Gets or Totals. The latencies are in milliseconds.
+type MemtierStats struct {
+	Type       string
+	OpsPerSec  float64
+	HasLatency bool
+	AvgLatency float64
+	// Percentiles are the latency percentiles of --print-percentiles (p50, p99 and p99.9 by
+	// default) in the order of the table, they are only printed by memtier_benchmark 1.3 and later.
+	Percentiles []MemtierPercentile
+}
+
+// MemtierPercentile is a latency percentile of memtier_benchmark, e.g. 99.9 for the
+// "p99.9 Latency" column.
+type MemtierPercentile struct {
+	Percentile float64
+	Latency    float64
+}
+
+// MetricName returns the name of the percentile in metric names, e.g. p99_9 for 99.9.
+func (p MemtierPercentile) MetricName() string {
+	return "p" + strings.Replace(strconv.FormatFloat(p.Percentile, 'f', -1, 64), ".", "_", -1)
+}
+
+// MetricName returns the name of the type of requests in metric names, e.g. sets.
+func (s MemtierStats) MetricName() string {
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s.Type), "_"), "_")
+}
+
+// ParseMemtier parses the stats of memtier_benchmark from its log, it fails if the log has no
+// stats table. With --run-count memtier_benchmark prints a table for the best, worst and
+// aggregated average runs, the last table is parsed then. The rows without requests, e.g. the
+// Waits or the Sets of a read-only ratio, are skipped.
+func ParseMemtier(data []byte) ([]MemtierStats, error) {
+	var (
+		columns []string
+		stats   []MemtierStats
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "Type" && len(fields) > 1 && fields[1] == "Ops/sec" {
+			// a new table, the former ones are dropped.
+			columns = memtierColumns(fields)
+			stats = nil
+			continue
+		}
+		if columns == nil || len(fields) != len(columns) {
+			continue
+		}
+
+		s, ok, err := parseMemtierRow(columns, fields)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			stats = append(stats, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "unable to read the log of memtier_benchmark")
+	}
+
+	if len(stats) == 0 {
+		return nil, errors.Errorf("results not contain the stats of memtier_benchmark")
+	}
+	return stats, nil
+}
+
+// memtierColumns returns the columns of the header of a stats table, the columns of the
+// latencies are made of two words, e.g. "Avg. Latency" or "p99.9 Latency".
+func memtierColumns(header []string) []string {
+	var columns []string
+	for _, field := range header {
+		if field == "Latency" && len(columns) > 0 {
+			last := columns[len(columns)-1]
+			if last == "Avg." || strings.HasPrefix(last, "p") {
+				columns[len(columns)-1] = last + " " + field
+				continue
+			}
+		}
+		columns = append(columns, field)
+	}
+	return columns
+}
+
+// parseMemtierRow parses a row of a stats table, it returns false if the row is not one of the
+// table or has no requests.
+func parseMemtierRow(columns, fields []string) (MemtierStats, bool, error) {
+	s := MemtierStats{Type: fields[0]}
+	hasOps := false
+	for i, column := range columns[1:] {
+		value := fields[i+1]
+		if value == "---" {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			// not a row of the table.
+			return MemtierStats{}, false, nil
+		}
+
+		switch {
+		case column == "Ops/sec":
+			s.OpsPerSec, hasOps = v, true
+		case column == "Avg. Latency" || column == "Latency":
+			s.HasLatency = true
+			s.AvgLatency = v
+		case strings.HasPrefix(column, "p") && strings.HasSuffix(column, " Latency"):
+			percentile, err := strconv.ParseFloat(strings.TrimSuffix(column[1:], " Latency"), 64)
+			if err != nil {
+				return MemtierStats{}, false, errors.Wrapf(err, "invalid latency percentile %q of memtier_benchmark", column)
+			}
+			s.Percentiles = append(s.Percentiles, MemtierPercentile{Percentile: percentile, Latency: v})
+		}
+	}
+	return s, hasOps && s.OpsPerSec > 0, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+)
+
+func TestParseMemtier(t *testing.T) {
+	tests := []struct {
+		file string
+		want []MemtierStats
+	}{
+		{
+			file: "memtier2.txt",
+			want: []MemtierStats{
+				{Type: "Sets", OpsPerSec: 17506.48, HasLatency: true, AvgLatency: 1.04106, Percentiles: []MemtierPercentile{{50, 0.983}, {99, 2.159}, {99.9, 4.191}}},
+				{Type: "Gets", OpsPerSec: 175023.12, HasLatency: true, AvgLatency: 1.03824, Percentiles: []MemtierPercentile{{50, 0.975}, {99, 2.143}, {99.9, 4.159}}},
+				{Type: "Totals", OpsPerSec: 192529.6, HasLatency: true, AvgLatency: 1.0385, Percentiles: []MemtierPercentile{{50, 0.975}, {99, 2.143}, {99.9, 4.159}}},
+			},
+		},
+		{
+			// memtier_benchmark before 1.3 prints the average latency only.
+			file: "memtier1.txt",
+			want: []MemtierStats{
+				{Type: "Sets", OpsPerSec: 13628.5, HasLatency: true, AvgLatency: 0.733},
+				{Type: "Gets", OpsPerSec: 136272.79, HasLatency: true, AvgLatency: 0.731},
+				{Type: "Totals", OpsPerSec: 149901.29, HasLatency: true, AvgLatency: 0.731},
+			},
+		},
+		{
+			// the aggregated average results of --run-count=2.
+			file: "memtier-runcount.txt",
+			want: []MemtierStats{
+				{Type: "Sets", OpsPerSec: 16765.28, HasLatency: true, AvgLatency: 1.08405, Percentiles: []MemtierPercentile{{50, 1.015}, {99, 2.271}, {99.9, 4.575}}},
+				{Type: "Gets", OpsPerSec: 167652.8, HasLatency: true, AvgLatency: 1.08377, Percentiles: []MemtierPercentile{{50, 1.015}, {99, 2.255}, {99.9, 4.543}}},
+				{Type: "Totals", OpsPerSec: 184418.08, HasLatency: true, AvgLatency: 1.0838, Percentiles: []MemtierPercentile{{50, 1.015}, {99, 2.255}, {99.9, 4.543}}},
+			},
+		},
+	}
+	for _, test := range tests {
+		got, err := ParseMemtier(readTestdata(t, test.file))
+		if err != nil {
+			t.Errorf("%s: %v", test.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.file, got, test.want)
+		}
+	}
+
+	if _, err := ParseMemtier([]byte("error: failed to connect to 10.244.1.5:6379\n")); err == nil {
+		t.Errorf("parsing the output without stats succeeded")
+	}
+}
+
+func TestMemtierParseResults(t *testing.T) {
+	metrics, err := (&MemtierDriver{}).ParseResults(workload.TestingCase{}, readTestdata(t, "memtier2.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, metric := range metrics {
+		names = append(names, metric.Name)
+	}
+	want := []string{
+		"ops",
+		"sets_ops", "sets_latency_avg", "sets_latency_p50", "sets_latency_p99", "sets_latency_p99_9",
+		"gets_ops", "gets_latency_avg", "gets_latency_p50", "gets_latency_p99", "gets_latency_p99_9",
+		"totals_ops", "totals_latency_avg", "totals_latency_p50", "totals_latency_p99", "totals_latency_p99_9",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("metrics %v, want %v", names, want)
+	}
+	if metrics[0].Value != 192529.6 || metrics[0].LowerIsBetter {
+		t.Errorf("headline metric %+v, want ops/sec of the Totals", metrics[0])
+	}
+	if m := metrics[len(metrics)-1]; m.Value != 4.159 || m.Unit != "ms" || !m.LowerIsBetter {
+		t.Errorf("metric %+v, want the p99.9 latency of the Totals", m)
+	}
+}
+
+func TestMemtierTestingPodArgs(t *testing.T) {
+	tests := []struct {
+		testingName string
+		args        string
+		want        []string
+	}{
+		{memtierSameNode, "-s $(ENDPOINT) --test-time=30", []string{"-s", "$(ENDPOINT)", "--test-time=30"}},
+		{memtierPipelineDiffNode, "-s $(ENDPOINT)", []string{"-s", "$(ENDPOINT)", "--pipeline=16"}},
+		{memtierPipelineDiffNode, "-s $(ENDPOINT) --pipeline=4", []string{"-s", "$(ENDPOINT)", "--pipeline=4"}},
+		{memtierDataSizeSameNode, "-s $(ENDPOINT)", []string{"-s", "$(ENDPOINT)", "--data-size=1024"}},
+		{memtierDataSizeSameNode, "-s $(ENDPOINT) -d 32", []string{"-s", "$(ENDPOINT)", "-d", "32"}},
+	}
+	for _, test := range tests {
+		pod, err := (&MemtierDriver{}).TestingPod(workload.PodArgs{
+			Name:         "capstan-redis-" + MemtierToolName + "-" + test.testingName + "-testing",
+			TestingName:  test.testingName,
+			Image:        "wadelee/memtier",
+			Namespace:    "capstan",
+			WorkloadName: "capstan-redis-" + test.testingName + "-workload",
+			PodIP:        "10.244.1.5",
+			TestingCase:  workload.TestingCase{Name: test.testingName, TestingToolArgs: test.args},
+		})
+		if err != nil {
+			t.Errorf("%s %q: %v", test.testingName, test.args, err)
+			continue
+		}
+		if got := pod.Spec.Containers[0].Args; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: args %q, want %q", test.testingName, test.args, got, test.want)
+		}
+		if pod.Annotations["capstan-testing"] != MemtierToolName {
+			t.Errorf("%s: annotations %v, want the memtier_benchmark testing tool", test.testingName, pod.Annotations)
+		}
+	}
+
+	if _, err := (&MemtierDriver{}).TestingPod(workload.PodArgs{TestingName: benchmarkSameNode}); err == nil {
+		t.Errorf("the testing pod of a testing case of redis-benchmark was built")
+	}
+}
+
+func TestToolTestingCaseSet(t *testing.T) {
+	factory, err := workload.Lookup(WorkloadName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range []string{ToolName, MemtierToolName} {
+		testingCaseSet, err := factory.ToolTestingCaseSet(tool)
+		if err != nil || len(testingCaseSet) != 6 {
+			t.Errorf("testing cases of %s: %v, %v", tool, testingCaseSet, err)
+		}
+	}
+	if _, err := factory.ToolTestingCaseSet("memtier_benchmark"); err == nil {
+		t.Errorf("an unknown testing tool of the redis workload is allowed")
+	}
+}
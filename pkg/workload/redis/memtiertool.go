@@ -0,0 +1,130 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// MemtierToolName is the name of the memtier_benchmark testing tool of the redis workload,
+	// it is part of the names of the testing pods so it has no underscore.
+	MemtierToolName         = "memtier"
+	memtierSameNode         = "memtierSameNode"
+	memtierDiffNode         = "memtierDiffNode"
+	memtierPipelineSameNode = "memtierPipelineSameNode"
+	memtierPipelineDiffNode = "memtierPipelineDiffNode"
+	memtierDataSizeSameNode = "memtierDataSizeSameNode"
+	memtierDataSizeDiffNode = "memtierDataSizeDiffNode"
+)
+
+// MemtierTestingCaseSet is the list of memtier_benchmark defined testing cases.
+var MemtierTestingCaseSet = []string{
+	"memtierSameNode",
+	"memtierDiffNode",
+	"memtierPipelineSameNode",
+	"memtierPipelineDiffNode",
+	"memtierDataSizeSameNode",
+	"memtierDataSizeDiffNode",
+}
+
+// MemtierDriver supplies the manifests and the result parser of the memtier_benchmark testing tool.
+type MemtierDriver struct{}
+
+// Ensure memtier_benchmark Driver implements workload.Driver interface.
+var _ workload.Driver = &MemtierDriver{}
+
+// WorkloadPod returns the redis pod of a testing case (to adhere to workload.Driver interface).
+func (d *MemtierDriver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return workload.PodFromTemplate(redisPod, args)
+}
+
+// TestingPod returns the memtier_benchmark pod of a testing case, which runs on the node of the
+// workload pod or on another node. The pipeline and data size testing cases have the same default
+// pipeline and data size as the ones of redis-benchmark (to adhere to workload.Driver interface).
+func (d *MemtierDriver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	flags := strings.Fields(args.TestingCase.TestingToolArgs)
+	switch args.TestingName {
+	case memtierPipelineSameNode, memtierPipelineDiffNode:
+		if !hasMemtierFlag(flags, "--pipeline") {
+			flags = append(flags, "--pipeline="+defaultPipeline)
+		}
+	case memtierDataSizeSameNode, memtierDataSizeDiffNode:
+		if !hasMemtierFlag(flags, "-d", "--data-size") {
+			flags = append(flags, "--data-size="+defaultDataSize)
+		}
+	}
+	args.Args = workload.FomatArgs(strings.Join(flags, " "))
+
+	switch args.TestingName {
+	case memtierDiffNode, memtierPipelineDiffNode, memtierDataSizeDiffNode:
+		return workload.PodFromTemplate(memtierPodAntiAffinity, args)
+	case memtierSameNode, memtierPipelineSameNode, memtierDataSizeSameNode:
+		return workload.PodFromTemplate(memtierPodAffinity, args)
+	}
+	return nil, errors.Errorf("unknown testing case %s of memtier_benchmark", args.TestingName)
+}
+
+// ParseResults parses the stats of memtier_benchmark into metrics, the ops/sec of the Totals
+// is the headline metric (to adhere to workload.Driver interface).
+func (d *MemtierDriver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	stats, err := ParseMemtier(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get ops per second")
+	}
+
+	var metrics []results.Metric
+	for _, s := range stats {
+		if s.Type == "Totals" {
+			metrics = append(metrics, results.Metric{Name: "ops", Unit: "ops/sec", Value: s.OpsPerSec})
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, errors.Errorf("results not contain the Totals of memtier_benchmark")
+	}
+
+	for _, s := range stats {
+		name := s.MetricName()
+		metrics = append(metrics, results.Metric{Name: name + "_ops", Unit: "ops/sec", Value: s.OpsPerSec})
+		if !s.HasLatency {
+			continue
+		}
+		metrics = append(metrics, results.Metric{Name: name + "_latency_avg", Unit: "ms", Value: s.AvgLatency, LowerIsBetter: true})
+		for _, p := range s.Percentiles {
+			metrics = append(metrics, results.Metric{Name: name + "_latency_" + p.MetricName(), Unit: "ms", Value: p.Latency, LowerIsBetter: true})
+		}
+	}
+	return metrics, nil
+}
+
+// hasMemtierFlag returns whether the flags have one of the flags, whose value may be given
+// after "=", e.g. --pipeline=16.
+func hasMemtierFlag(flags []string, names ...string) bool {
+	for _, f := range flags {
+		for _, name := range names {
+			if f == name || strings.HasPrefix(f, name+"=") {
+				return true
+			}
+		}
+	}
+	return false
+}
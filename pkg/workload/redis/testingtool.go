@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"strings"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/ZJU-SEL/capstan/pkg/workload"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ToolName is the name of the testing tool of the redis workload.
+	ToolName                  = "redis-benchmark"
+	benchmarkSameNode         = "benchmarkSameNode"
+	benchmarkDiffNode         = "benchmarkDiffNode"
+	benchmarkPipelineSameNode = "benchmarkPipelineSameNode"
+	benchmarkPipelineDiffNode = "benchmarkPipelineDiffNode"
+	benchmarkDataSizeSameNode = "benchmarkDataSizeSameNode"
+	benchmarkDataSizeDiffNode = "benchmarkDataSizeDiffNode"
+
+	// defaultPipeline is the number of pipelined requests of the pipeline testing cases
+	// whose testingToolArgs have no -P.
+	defaultPipeline = "16"
+	// defaultDataSize is the data size in bytes of the values of the data size testing
+	// cases whose testingToolArgs have no -d.
+	defaultDataSize = "1024"
+)
+
+// TestingCaseSet is the list of redis defined testing cases.
+var TestingCaseSet = []string{
+	"benchmarkSameNode",
+	"benchmarkDiffNode",
+	"benchmarkPipelineSameNode",
+	"benchmarkPipelineDiffNode",
+	"benchmarkDataSizeSameNode",
+	"benchmarkDataSizeDiffNode",
+}
+
+// Driver supplies the manifests and the result parser of the redis-benchmark testing tool.
+type Driver struct{}
+
+// Ensure redis-benchmark Driver implements workload.Driver interface.
+var _ workload.Driver = &Driver{}
+
+// WorkloadPod returns the redis pod of a testing case (to adhere to workload.Driver interface).
+func (d *Driver) WorkloadPod(args workload.PodArgs) (*v1.Pod, error) {
+	return workload.PodFromTemplate(redisPod, args)
+}
+
+// TestingPod returns the redis-benchmark pod of a testing case, which runs on the node of the
+// workload pod or on another node. redis-benchmark always prints its results as CSV, and the
+// pipeline and data size testing cases have a default pipeline and data size (to adhere to
+// workload.Driver interface).
+func (d *Driver) TestingPod(args workload.PodArgs) (*v1.Pod, error) {
+	flags := strings.Fields(args.TestingCase.TestingToolArgs)
+	switch args.TestingName {
+	case benchmarkPipelineSameNode, benchmarkPipelineDiffNode:
+		flags = withDefault(flags, "-P", defaultPipeline)
+	case benchmarkDataSizeSameNode, benchmarkDataSizeDiffNode:
+		flags = withDefault(flags, "-d", defaultDataSize)
+	}
+	if !hasFlag(flags, "--csv") {
+		flags = append(flags, "--csv")
+	}
+	args.Args = workload.FomatArgs(strings.Join(flags, " "))
+
+	switch args.TestingName {
+	case benchmarkDiffNode, benchmarkPipelineDiffNode, benchmarkDataSizeDiffNode:
+		return workload.PodFromTemplate(redisBenchmarkPodAntiAffinity, args)
+	case benchmarkSameNode, benchmarkPipelineSameNode, benchmarkDataSizeSameNode:
+		return workload.PodFromTemplate(redisBenchmarkPodAffinity, args)
+	}
+	return nil, errors.Errorf("unknown testing case %s of redis-benchmark", args.TestingName)
+}
+
+// ParseResults parses the CSV results of redis-benchmark into metrics, the requests per second
+// of the first test is the headline metric (to adhere to workload.Driver interface).
+func (d *Driver) ParseResults(testingCase workload.TestingCase, log []byte) ([]results.Metric, error) {
+	tests, err := ParseBenchmark(log)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get requests per second")
+	}
+
+	metrics := []results.Metric{{Name: "rps", Unit: "requests/sec", Value: tests[0].RequestsPerSec}}
+	seen := map[string]bool{}
+	for _, t := range tests {
+		// e.g. "LPUSH (needed to benchmark LPOP)" has the metric name of LPUSH, the first one is kept.
+		name := t.MetricName()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		metrics = append(metrics, results.Metric{Name: name + "_rps", Unit: "requests/sec", Value: t.RequestsPerSec})
+		if !t.HasLatency {
+			continue
+		}
+		metrics = append(metrics,
+			results.Metric{Name: name + "_latency_avg", Unit: "ms", Value: t.AvgLatency, LowerIsBetter: true},
+			results.Metric{Name: name + "_latency_min", Unit: "ms", Value: t.MinLatency, LowerIsBetter: true},
+			results.Metric{Name: name + "_latency_p50", Unit: "ms", Value: t.P50Latency, LowerIsBetter: true},
+			results.Metric{Name: name + "_latency_p95", Unit: "ms", Value: t.P95Latency, LowerIsBetter: true},
+			results.Metric{Name: name + "_latency_p99", Unit: "ms", Value: t.P99Latency, LowerIsBetter: true},
+			results.Metric{Name: name + "_latency_max", Unit: "ms", Value: t.MaxLatency, LowerIsBetter: true},
+		)
+	}
+	return metrics, nil
+}
+
+// withDefault appends the flag with the value unless the flags have it.
+func withDefault(flags []string, flag, value string) []string {
+	if hasFlag(flags, flag) {
+		return flags
+	}
+	return append(flags, flag, value)
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Factory creates the workloads of a kind, it is registered with the name of the workload.
+type Factory struct {
+	// ToolName is the name of the testing tool of the workload, any testing tool
+	// is allowed if it is empty.
+	ToolName string
+	// TestingCaseSet is the list of the defined testing cases of the testing tool,
+	// any testing case is allowed if it is empty.
+	TestingCaseSet []string
+	// OtherTools are the defined testing cases of the other testing tools of the workload by
+	// the name of the testing tool, a workload runs the testing tool named in its config.
+	OtherTools map[string][]string
+	// New creates a workload from its definition in the capstan config, whose testing
+	// cases run with the options.
+	New func(wl Workload, opts RunOptions) Interface
+	// NoWorkloadPod is true if the testing cases of the workload only run a testing pod,
+	// the image of the workload is not required then.
+	NoWorkloadPod bool
+	// Validate checks the definition of a workload in the capstan config, it is optional.
+	Validate func(fldPath *field.Path, wl Workload) field.ErrorList
+}
+
+// ToolTestingCaseSet returns the defined testing cases of a testing tool of the workload, any
+// testing case is allowed if it is empty. It fails if the workload does not have the testing tool.
+func (f Factory) ToolTestingCaseSet(toolName string) ([]string, error) {
+	if f.ToolName == "" || toolName == f.ToolName {
+		return f.TestingCaseSet, nil
+	}
+	if testingCaseSet, found := f.OtherTools[toolName]; found {
+		return testingCaseSet, nil
+	}
+	return nil, errors.Errorf("Wrong parameter(%q), the testing tool name must be in %v", toolName, f.ToolNames())
+}
+
+// ToolNames returns the names of the testing tools of the workload, the first one is ToolName
+// and the others are sorted. It is empty if any testing tool is allowed.
+func (f Factory) ToolNames() []string {
+	if f.ToolName == "" {
+		return nil
+	}
+	var others []string
+	for name := range f.OtherTools {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+	return append([]string{f.ToolName}, others...)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a workload available in capstan by its name, it is called from the init
+// function of the package of the workload, so a workload is added to a capstan binary by
+// importing its package. It panics if the name is registered twice or the factory is invalid.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || factory.New == nil {
+		panic(fmt.Sprintf("workload: invalid registration of workload %q", name))
+	}
+	if _, found := registry[name]; found {
+		panic(fmt.Sprintf("workload: Register called twice for workload %q", name))
+	}
+	registry[name] = factory
+}
+
+// Lookup returns the factory of a registered workload.
+func Lookup(name string) (Factory, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, found := registry[name]
+	if !found {
+		return Factory{}, errors.Errorf("The testing workload %q has not defined in capstan", name)
+	}
+	return factory, nil
+}
+
+// Workloads returns the sorted names of the registered workloads.
+func Workloads() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tools returns the sorted names of the testing tools of the registered workloads.
+func Tools() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var names []string
+	seen := map[string]bool{}
+	for _, factory := range registry {
+		for _, name := range factory.ToolNames() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
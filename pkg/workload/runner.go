@@ -0,0 +1,503 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kuberuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Driver supplies what is specific to a testing tool to the shared Runner: the manifests
+// of the workload pod and the testing pod of a testing case, and the parser of the results.
+type Driver interface {
+	// WorkloadPod returns the workload pod of a testing case, or nil if the testing case
+	// only runs a testing pod.
+	WorkloadPod(args PodArgs) (*v1.Pod, error)
+	// TestingPod returns the testing pod of a testing case, args has the IP of the workload pod.
+	TestingPod(args PodArgs) (*v1.Pod, error)
+	// ParseResults parses the log of the testing pod of a testing case into metrics, the
+	// first one is the headline metric of the testing case. The testing case fails if the
+	// results are not acceptable to it.
+	ParseResults(testingCase TestingCase, log []byte) ([]results.Metric, error)
+}
+
+// CollectorDriver is implemented by the drivers publishing more than a gauge per metric,
+// e.g. a latency distribution as a summary.
+type CollectorDriver interface {
+	Collectors(testingCase TestingCase, log []byte) ([]prometheus.Collector, error)
+}
+
+// WorkloadConditioner is implemented by the drivers whose workload pod must meet another
+// condition than PodRunning before the testing pod is started.
+type WorkloadConditioner interface {
+	WorkloadCondition() PodCondition
+}
+
+// VolumeClaimer is implemented by the drivers whose workload pod or testing pod may mount a
+// persistent volume claim, which is created before and deleted after the pods. The runner
+// adds the provisioning and the attach/mount latencies of the volume to the metrics.
+type VolumeClaimer interface {
+	// VolumeClaim returns the claim of a testing case or nil if it has none, the claim is
+	// named after the workload pod, i.e. args.Name of the workload pod and args.WorkloadName
+	// of the testing pod.
+	VolumeClaim(args PodArgs) (*v1.PersistentVolumeClaim, error)
+}
+
+// ResultsWriter is implemented by the drivers writing their parsed results to the results
+// directory of a testing case besides the log of the testing pod, e.g. a time series as CSV.
+type ResultsWriter interface {
+	WriteResults(testingCase TestingCase, log []byte, dir string) error
+}
+
+// PodArgs are the arguments of the manifests of a testing case.
+type PodArgs struct {
+	// Name is the name of the pod.
+	Name string
+	// TestingName is the name of the testing case.
+	TestingName string
+	// Image is the image of the workload for the workload pod, and of the testing tool for the testing pod.
+	Image     string
+	Namespace string
+	// WorkloadName is the name of the workload pod, it is only set for the testing pod.
+	WorkloadName string
+	// Args is the testingToolArgs of the testing case formatted by FomatArgs, it is only set for the testing pod.
+	Args string
+	// PodIP is the IP of the workload pod, it is only set for the testing pod.
+	PodIP       string
+	TestingCase TestingCase
+}
+
+// Publisher publishes the metrics of a testing case, e.g. pushes them to Pushgateway,
+// the job is the name of the testing tool.
+type Publisher func(job string, labels map[string]string, collectors ...prometheus.Collector) error
+
+// PodFromTemplate parses the YAML template of a pod with the args.
+func PodFromTemplate(strtmpl string, args interface{}) (*v1.Pod, error) {
+	podBytes, err := ParseTemplate(strtmpl, args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %v using %v", strtmpl, args)
+	}
+	pod := &v1.Pod{}
+	if err := kuberuntime.DecodeInto(scheme.Codecs.UniversalDecoder(), podBytes, pod); err != nil {
+		return nil, errors.Wrap(err, "unable to decode pod")
+	}
+	return pod, nil
+}
+
+// Runner is a workload whose testing cases start a workload pod, wait for it, start a testing
+// pod and parse its log once it prints the TestingDoneMark. The testing tool only supplies a
+// Driver, the runner handles the repeats, waiting, log archiving, metric publishing and cleanup.
+type Runner struct {
+	workload  Workload
+	Name      string
+	Image     string
+	Frequency int
+	Options   RunOptions
+	Driver    Driver
+}
+
+// Ensure Runner implements Interface
+var _ Interface = &Runner{}
+
+// NewRunner creates a workload from the given workload definition, whose testing cases
+// run with the options and the driver.
+func NewRunner(wl Workload, opts RunOptions, driver Driver) *Runner {
+	return &Runner{
+		workload:  wl,
+		Name:      wl.Name,
+		Image:     wl.Image,
+		Frequency: wl.Frequency,
+		Options:   opts,
+		Driver:    driver,
+	}
+}
+
+// Run runs all repeats of all testing cases of the workload (to adhere to workload.Interface).
+func (r *Runner) Run(ctx context.Context, kubeClient kubernetes.Interface) error {
+	// initialize a new testing tool for this workload.
+	testingTool, err := r.TestingTool()
+	if err != nil {
+		return err
+	}
+	return RunTestingCases(ctx, kubeClient, r, testingTool, r.Frequency, nil, r.Options)
+}
+
+// TestingTool initializes a new testing tool for this workload (to adhere to workload.Interface).
+func (r *Runner) TestingTool() (Tool, error) {
+	if err := TestingCaseSetHasDefined(r.workload.GetType(), r.workload.TestingTool.Name, r.workload.TestingTool.TestingCaseSet); err != nil {
+		return nil, err
+	}
+
+	return &runnerTool{
+		runner:         r,
+		name:           r.workload.TestingTool.Name,
+		image:          r.workload.TestingTool.Image,
+		steps:          time.Duration(r.workload.TestingTool.Steps) * time.Second,
+		testingCaseSet: r.workload.TestingTool.TestingCaseSet,
+	}, nil
+}
+
+// GetName returns the name of this workload (to adhere to workload.Interface).
+func (r *Runner) GetName() string {
+	return r.Name
+}
+
+// GetImage returns the image name of this workload (to adhere to workload.Interface).
+func (r *Runner) GetImage() string {
+	return r.Image
+}
+
+// GetNamespace returns the namespace which this workload runs in (to adhere to workload.Interface).
+func (r *Runner) GetNamespace() string {
+	return r.Options.Namespace
+}
+
+// RunTestingCases runs all repeats of the testing cases of the workload with the testing tool,
+// or of all its testing cases if testingCases is empty. The testing tool sleeps its steps
+// after every testing case.
+func RunTestingCases(ctx context.Context, kubeClient kubernetes.Interface, w Interface, testingTool Tool, frequency int, testingCases []string, opts RunOptions) error {
+	for i := 1; i <= frequency; i++ {
+		for _, testingCase := range testingTool.GetTestingCaseSet() {
+			if len(testingCases) != 0 && !contains(testingCases, testingCase.Name) {
+				continue
+			}
+			if err := RunTestingCase(ctx, kubeClient, w, testingTool, testingCase, i, opts); err != nil {
+				return err
+			}
+
+			// sleep some seconds between testing cases.
+			glog.V(4).Infof("Repeat %d: Sleeping %v and starting next testing case.", i, testingTool.GetSteps())
+			if err := Sleep(ctx, testingTool.GetSteps()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runnerTool is the testing tool of a Runner.
+type runnerTool struct {
+	runner         *Runner
+	name           string
+	image          string
+	steps          time.Duration
+	testingCaseSet []TestingCase
+
+	nodes          []string
+	startTime      time.Time
+	workloadNode   string
+	claimName      string
+	claimBound     <-chan time.Duration
+	claimPod       string
+	currentTesting TestingCase
+}
+
+// Ensure runnerTool implements Tool
+var _ Tool = &runnerTool{}
+
+// Run starts the workload pod of the testing case, waits for it, and starts the testing pod (to adhere to workload.Tool interface).
+func (t *runnerTool) Run(ctx context.Context, kubeClient kubernetes.Interface, testingCase TestingCase) error {
+	t.currentTesting = testingCase
+	t.startTime = time.Now()
+	t.workloadNode = ""
+	t.claimName = ""
+	t.claimBound = nil
+	t.claimPod = ""
+	namespace := t.runner.GetNamespace()
+	workloadPodName := t.workloadPodName()
+	workloadArgs := PodArgs{
+		Name:        workloadPodName,
+		TestingName: testingCase.Name,
+		Image:       t.runner.GetImage(),
+		Namespace:   namespace,
+		TestingCase: testingCase,
+	}
+
+	// 1. create the persistent volume claim of the workload if it has one.
+	if c, ok := t.runner.Driver.(VolumeClaimer); ok {
+		claim, err := c.VolumeClaim(workloadArgs)
+		if err != nil {
+			return errors.Wrapf(err, "unable to build the volume claim of the %s workload for testing case %s", t.runner.GetName(), testingCase.Name)
+		}
+		if claim != nil {
+			t.setMetadata(&claim.ObjectMeta, workloadPodName, "capstan-workload", t.runner.GetName())
+			glog.V(4).Infof("Creating persistent volume claim %q of testing case %s", workloadPodName, testingCase.Name)
+			if err := CreatePersistentVolumeClaim(kubeClient, namespace, claim); err != nil {
+				return errors.Wrapf(err, "unable to create the volume claim of the %s workload for testing case %s", t.runner.GetName(), testingCase.Name)
+			}
+			t.claimName = workloadPodName
+			t.claimBound = WaitForClaimBound(ctx, kubeClient, namespace, workloadPodName, testingCase.GetStartTimeout())
+		}
+	}
+
+	// 2. start a workload for the testing case.
+	workloadPod, err := t.runner.Driver.WorkloadPod(workloadArgs)
+	if err != nil {
+		return errors.Wrapf(err, "unable to build the %s workload for testing case %s", t.runner.GetName(), testingCase.Name)
+	}
+	podIP := ""
+	if workloadPod != nil {
+		t.setMetadata(&workloadPod.ObjectMeta, workloadPodName, "capstan-workload", t.runner.GetName())
+		if mountsClaim(workloadPod, t.claimName) {
+			t.claimPod = workloadPodName
+		}
+
+		glog.V(4).Infof("Creating workload %q of testing case %s", workloadPodName, testingCase.Name)
+		if err := CreatePodObject(kubeClient, namespace, t.nodes, workloadPod); err != nil {
+			return errors.Wrapf(err, "unable to create the %s workload for testing case %s", t.runner.GetName(), testingCase.Name)
+		}
+
+		// 3. get the podIP and hostIP of the workload until workload is running.
+		condition := PodRunning
+		if c, ok := t.runner.Driver.(WorkloadConditioner); ok {
+			condition = c.WorkloadCondition()
+		}
+		glog.V(4).Infof("Geting the podIP and hostIP of workload %s", workloadPodName)
+		pod, err := WaitForPod(ctx, kubeClient, namespace, workloadPodName, testingCase.GetStartTimeout(), condition)
+		if err != nil {
+			return errors.Wrapf(err, "unable to get podIP and hostIP of pod %s created by the %s workload for testing case %s", workloadPodName, t.runner.GetName(), testingCase.Name)
+		}
+		t.workloadNode = pod.Status.HostIP
+		podIP = pod.Status.PodIP
+	}
+
+	// 4. start a testing pod for testing the workload.
+	testingPodName := t.testingPodName()
+	testingPod, err := t.runner.Driver.TestingPod(PodArgs{
+		Name:         testingPodName,
+		TestingName:  testingCase.Name,
+		Image:        t.GetImage(),
+		Namespace:    namespace,
+		WorkloadName: workloadPodName,
+		Args:         FomatArgs(testingCase.TestingToolArgs),
+		PodIP:        podIP,
+		TestingCase:  testingCase,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to build the testing pod for testing case %s", testingCase.Name)
+	}
+	t.setMetadata(&testingPod.ObjectMeta, testingPodName, "capstan-testing", t.GetName())
+	if mountsClaim(testingPod, t.claimName) {
+		t.claimPod = testingPodName
+	}
+
+	glog.V(4).Infof("Creating testing pod %q of testing case %s", testingPodName, testingCase.Name)
+	if err := CreatePodObject(kubeClient, namespace, t.nodes, testingPod); err != nil {
+		return errors.Wrapf(err, "unable to create the testing pod for testing case %s", testingCase.Name)
+	}
+
+	return nil
+}
+
+// GetTestingResults waits for the testing pod to finish, archives its log, and parses and
+// publishes its metrics (to adhere to workload.Tool interface).
+func (t *runnerTool) GetTestingResults(ctx context.Context, kubeClient kubernetes.Interface) error {
+	body, pod, err := WaitForTestingDone(ctx, kubeClient, t.runner.GetNamespace(), t.testingPodName(), t.currentTesting.GetStartTimeout(), t.currentTesting.GetTimeout())
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("Testing case %s has done", t.currentTesting.Name)
+
+	// export to capstan result directory.
+	if t.runner.Options.ResultsDir != "" {
+		outdir := path.Join(t.runner.Options.ResultsDir, caseDir(t.runner, t, t.currentTesting))
+		if err = os.MkdirAll(outdir, 0755); err != nil {
+			return errors.WithStack(err)
+		}
+
+		outfile := path.Join(outdir, t.GetName()) + ".log"
+		if err = ioutil.WriteFile(outfile, body, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if w, ok := t.runner.Driver.(ResultsWriter); ok {
+			if err := w.WriteResults(t.currentTesting, body, outdir); err != nil {
+				return errors.Wrapf(err, "Failed to write the results of testing case %s", t.currentTesting.Name)
+			}
+		}
+	}
+
+	metrics, err := t.runner.Driver.ParseResults(t.currentTesting, body)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse the results of testing case %s", t.currentTesting.Name)
+	}
+	if len(metrics) == 0 {
+		return errors.Errorf("No metrics in the results of testing case %s", t.currentTesting.Name)
+	}
+	metrics = append(metrics, t.volumeMetrics(kubeClient)...)
+	results.DefaultStore.SetNodes(t.runner.GetName(), t.currentTesting.Name, t.workloadNode, pod.Status.HostIP)
+	results.DefaultStore.SetMetrics(t.runner.GetName(), t.currentTesting.Name, metrics)
+
+	// export to prometheus pushGateway.
+	if t.runner.Options.Publish == nil {
+		return nil
+	}
+	var collectors []prometheus.Collector
+	for _, metric := range metrics {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: invalidMetricChars.ReplaceAllString(fmt.Sprintf("capstan_%s_%s", t.GetName(), metric.Name), "_"),
+			Help: fmt.Sprintf("The %s of %s testing case", metric.Name, t.GetName()),
+		})
+		gauge.Set(metric.Value)
+		collectors = append(collectors, gauge)
+	}
+	if c, ok := t.runner.Driver.(CollectorDriver); ok {
+		extra, err := c.Collectors(t.currentTesting, body)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse the results of testing case %s", t.currentTesting.Name)
+		}
+		collectors = append(collectors, extra...)
+	}
+	if err := t.runner.Options.Publish(
+		t.GetName(),
+		map[string]string{
+			"startTime":    t.startTime.Format("2006-01-02 15:04:05"),
+			"endTime":      time.Now().Format("2006-01-02 15:04:05"),
+			"workloadNode": t.workloadNode,
+			"testingNode":  pod.Status.HostIP,
+			"workloadName": t.runner.GetName(),
+			"testingName":  t.GetName(),
+			"testingCase":  t.currentTesting.Name,
+		},
+		collectors...,
+	); err != nil {
+		return errors.Wrapf(err, "Could not push metrics to Pushgateway")
+	}
+
+	return nil
+}
+
+// Cleanup deletes the testing pod, the workload pod and its volume claim of the testing case (to adhere to workload.Tool interface).
+func (t *runnerTool) Cleanup(ctx context.Context, kubeClient kubernetes.Interface) error {
+	if err := DeletePod(ctx, kubeClient, t.runner.GetNamespace(), t.testingPodName()); err != nil {
+		return err
+	}
+	if err := DeletePod(ctx, kubeClient, t.runner.GetNamespace(), t.workloadPodName()); err != nil {
+		return err
+	}
+	if t.claimName != "" {
+		if err := DeletePersistentVolumeClaim(ctx, kubeClient, t.runner.GetNamespace(), t.claimName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetName returns the name of the testing tool (to adhere to workload.Tool interface).
+func (t *runnerTool) GetName() string {
+	return t.name
+}
+
+// GetImage returns the image name of the testing tool (to adhere to workload.Tool interface).
+func (t *runnerTool) GetImage() string {
+	return t.image
+}
+
+// GetSteps returns the steps between each testing case (to adhere to workload.Tool interface).
+func (t *runnerTool) GetSteps() time.Duration {
+	return t.steps
+}
+
+// GetTestingCaseSet returns the testing case set which the testing tool will run (to adhere to workload.Tool interface).
+func (t *runnerTool) GetTestingCaseSet() []TestingCase {
+	return t.testingCaseSet
+}
+
+// SetNodes restricts the pods of the testing cases to the nodes (to adhere to workload.Tool interface).
+func (t *runnerTool) SetNodes(nodes []string) {
+	t.nodes = nodes
+}
+
+// volumeMetrics returns the provisioning latency of the volume claim of the testing case, from
+// its creation until it is bound, and the attach/mount latency of the volume, from the pod
+// mounting it being scheduled until the kubelet starts pulling its images.
+func (t *runnerTool) volumeMetrics(kubeClient kubernetes.Interface) []results.Metric {
+	if t.claimName == "" {
+		return nil
+	}
+
+	var metrics []results.Metric
+	select {
+	case d, ok := <-t.claimBound:
+		if ok {
+			metrics = append(metrics, results.Metric{Name: "volume_provisioning", Unit: "seconds", Value: d.Seconds(), LowerIsBetter: true})
+		}
+	default:
+	}
+
+	if t.claimPod != "" {
+		latency, err := MountLatency(kubeClient, t.runner.GetNamespace(), t.claimPod)
+		if err != nil {
+			glog.Warningf("Failed to get the attach/mount latency of volume %s: %v", t.claimName, err)
+		} else {
+			metrics = append(metrics, results.Metric{Name: "volume_attach_mount", Unit: "seconds", Value: latency, LowerIsBetter: true})
+		}
+	}
+	return metrics
+}
+
+func (t *runnerTool) workloadPodName() string {
+	return BuildWorkloadPodName(t.runner.GetName(), t.currentTesting.Name)
+}
+
+func (t *runnerTool) testingPodName() string {
+	return BuildTestingPodName(t.runner.GetName(), t.GetName(), t.currentTesting.Name)
+}
+
+// setMetadata sets the name, labels and annotations which capstan relies on to find
+// the objects of the current testing case, and the role annotation unless the manifest has it.
+func (t *runnerTool) setMetadata(meta *apismetav1.ObjectMeta, name, roleAnnotation, role string) {
+	meta.Name = name
+	if meta.Labels == nil {
+		meta.Labels = map[string]string{}
+	}
+	meta.Labels["component"] = "capstan"
+	meta.Labels["testing"] = name
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[testingCaseAnnotation] = t.currentTesting.Name
+	if _, found := meta.Annotations[roleAnnotation]; !found {
+		meta.Annotations[roleAnnotation] = role
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
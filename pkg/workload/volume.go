@@ -0,0 +1,180 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NewPersistentVolumeClaim returns a ReadWriteOnce persistent volume claim of the size, e.g. 10Gi,
+// from the storage class, or from the default storage class if storageClass is empty.
+func NewPersistentVolumeClaim(storageClass, size string) (*v1.PersistentVolumeClaim, error) {
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid size %q of persistent volume claim", size)
+	}
+
+	claim := &v1.PersistentVolumeClaim{
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: quantity},
+			},
+		},
+	}
+	if storageClass != "" {
+		claim.Spec.StorageClassName = &storageClass
+	}
+	return claim, nil
+}
+
+// CreatePersistentVolumeClaim creates the persistent volume claim in the namespace.
+func CreatePersistentVolumeClaim(kubeClient kubernetes.Interface, namespace string, claim *v1.PersistentVolumeClaim) error {
+	claim.Namespace = namespace
+	if _, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Create(claim); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// DeletePersistentVolumeClaim deletes the persistent volume claim and waits until it is gone.
+func DeletePersistentVolumeClaim(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	if err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(name, &apismetav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to delete persistent volume claim %v", name)
+	}
+
+	err := poll(ctx, 500*time.Millisecond, 60*time.Second, func() (bool, error) {
+		_, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(name, apismetav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+
+			return false, err
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// WaitForClaimBound waits in the background until the persistent volume claim is bound, the
+// returned channel receives the time from the call until then, or is closed without it if
+// the claim is not bound in the timeout or the ctx is cancelled. The claim of a storage class
+// with WaitForFirstConsumer binding is only bound once a pod mounting it is scheduled.
+func WaitForClaimBound(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, timeout time.Duration) <-chan time.Duration {
+	bound := make(chan time.Duration, 1)
+	start := time.Now()
+	go func() {
+		defer close(bound)
+		err := poll(ctx, 100*time.Millisecond, timeout, func() (bool, error) {
+			claim, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(name, apismetav1.GetOptions{})
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					return false, nil
+				}
+				return false, err
+			}
+			return claim.Status.Phase == v1.ClaimBound, nil
+		})
+		if err != nil {
+			glog.V(4).Infof("Persistent volume claim %s is not bound: %v", name, err)
+			return
+		}
+		bound <- time.Since(start)
+	}()
+	return bound
+}
+
+// MountLatency returns the seconds from the pod being scheduled until the kubelet starts
+// pulling its images, which it only does once the volumes of the pod are attached and mounted.
+// Only the events of this pod are used, not the ones of an earlier pod of the same name, e.g.
+// from the former repeat of the testing case. Both the PodScheduled condition and the events
+// have a resolution of a second, so the latency is in whole seconds and a mount quicker than
+// a second may be measured as 0 or 1.
+func MountLatency(kubeClient kubernetes.Interface, namespace, name string) (float64, error) {
+	pod, err := kubeClient.CoreV1().Pods(namespace).Get(name, apismetav1.GetOptions{})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	var scheduled time.Time
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodScheduled && cond.Status == v1.ConditionTrue {
+			scheduled = cond.LastTransitionTime.Time
+		}
+	}
+	if scheduled.IsZero() {
+		return 0, errors.Errorf("pod %s has not been scheduled", name)
+	}
+
+	events, err := kubeClient.CoreV1().Events(namespace).List(apismetav1.ListOptions{FieldSelector: podEventsSelector(pod)})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	var pulling time.Time
+	for _, event := range events.Items {
+		if event.Reason != "Pulling" && event.Reason != "Pulled" {
+			continue
+		}
+		if pulling.IsZero() || event.FirstTimestamp.Time.Before(pulling) {
+			pulling = event.FirstTimestamp.Time
+		}
+	}
+	if pulling.IsZero() {
+		return 0, errors.Errorf("the images of pod %s have not been pulled", name)
+	}
+	return sinceCreated(scheduled, pulling), nil
+}
+
+// podEventsSelector returns the field selector of the events of the pod, which are not the
+// ones of an earlier pod of the same name.
+func podEventsSelector(pod *v1.Pod) string {
+	return fields.SelectorFromSet(fields.Set{
+		"involvedObject.name": pod.Name,
+		"involvedObject.uid":  string(pod.UID),
+	}).String()
+}
+
+// mountsClaim returns whether the pod mounts the persistent volume claim.
+func mountsClaim(pod *v1.Pod, claimName string) bool {
+	if claimName == "" {
+		return false
+	}
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestMountLatency(t *testing.T) {
+	created := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	pod := v1.Pod{
+		ObjectMeta: apismetav1.ObjectMeta{Name: "capstan-fio-fio-benchmarkrandread-testing", UID: types.UID("uid-2")},
+		Status: v1.PodStatus{Conditions: []v1.PodCondition{
+			{Type: v1.PodScheduled, Status: v1.ConditionTrue, LastTransitionTime: apismetav1.NewTime(created.Add(time.Second))},
+		}},
+	}
+	event := func(uid, reason string, at time.Time) v1.Event {
+		return v1.Event{
+			InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: pod.Name, UID: types.UID(uid)},
+			Reason:         reason,
+			FirstTimestamp: apismetav1.NewTime(at),
+			LastTimestamp:  apismetav1.NewTime(at),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		events []v1.Event
+		want   float64
+		err    bool
+	}{
+		{
+			name:   "pulling",
+			events: []v1.Event{event("uid-2", "Scheduled", created.Add(time.Second)), event("uid-2", "Pulling", created.Add(5*time.Second)), event("uid-2", "Pulled", created.Add(7*time.Second))},
+			want:   4,
+		},
+		{
+			// the image is present on the node.
+			name:   "pulled",
+			events: []v1.Event{event("uid-2", "Pulled", created.Add(3*time.Second))},
+			want:   2,
+		},
+		{
+			// the events of the pod of the former repeat of the testing case, which have the
+			// same pod name, are ignored.
+			name: "stale events",
+			events: []v1.Event{
+				event("uid-1", "Pulling", created.Add(-time.Minute)),
+				event("uid-1", "Pulled", created.Add(-time.Minute+time.Second)),
+				event("uid-2", "Pulling", created.Add(9*time.Second)),
+			},
+			want: 8,
+		},
+		{
+			name:   "only stale events",
+			events: []v1.Event{event("uid-1", "Pulling", created.Add(-time.Minute))},
+			err:    true,
+		},
+	}
+	for _, test := range tests {
+		kubeClient := &fakeClient{pods: []v1.Pod{pod}, events: test.events}
+		got, err := MountLatency(kubeClient, "capstan", pod.Name)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: latency %v, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: latency %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	unscheduled := pod
+	unscheduled.Status.Conditions = nil
+	if _, err := MountLatency(&fakeClient{pods: []v1.Pod{unscheduled}}, "capstan", pod.Name); err == nil {
+		t.Errorf("the mount latency of an unscheduled pod is measured")
+	}
+}
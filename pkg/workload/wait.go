@@ -0,0 +1,230 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// DefaultStartTimeout is the default max time waiting for a pod to run.
+	DefaultStartTimeout = 5 * time.Minute
+	// DefaultTimeout is the default max time waiting for a testing case to finish.
+	DefaultTimeout = 30 * time.Minute
+	// CleanupTimeout is the max time cleaning up the resources of a testing case.
+	CleanupTimeout = 2 * time.Minute
+	// NamespaceDeleteTimeout is the max time waiting for a namespace to be deleted.
+	NamespaceDeleteTimeout = 5 * time.Minute
+	// TestingDoneMark is the log line printed by a testing pod when the testing case has finished.
+	TestingDoneMark = "Capstan Testing Done"
+)
+
+// PodCondition returns true if the pod has reached the condition, or an error
+// if the pod will never reach it.
+type PodCondition func(pod *v1.Pod) (bool, error)
+
+// PodRunning is met when the pod is running and has an IP.
+func PodRunning(pod *v1.Pod) (bool, error) {
+	if isFailing, err := IsPodFailing(pod); isFailing {
+		return false, err
+	}
+	switch pod.Status.Phase {
+	case v1.PodFailed, v1.PodSucceeded:
+		return false, errors.Errorf("pod %s has exited with phase %s", pod.Name, pod.Status.Phase)
+	case v1.PodRunning:
+		return pod.Status.PodIP != "", nil
+	}
+	return false, nil
+}
+
+// PodReady is met when the pod is running and ready, i.e. its readiness probes have passed.
+func PodReady(pod *v1.Pod) (bool, error) {
+	if running, err := PodRunning(pod); !running {
+		return false, err
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// PodStarted is met when the containers of the pod have started, whether or not they have exited.
+func PodStarted(pod *v1.Pod) (bool, error) {
+	if isFailing, err := IsPodFailing(pod); isFailing {
+		return false, err
+	}
+	switch pod.Status.Phase {
+	case v1.PodRunning, v1.PodSucceeded, v1.PodFailed:
+		return true, nil
+	}
+	return false, nil
+}
+
+// WaitForPod watches the pod until the condition is met, and returns the pod.
+// It returns an error if the condition fails, the pod is deleted, the timeout
+// expires or the ctx is cancelled.
+func WaitForPod(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, timeout time.Duration, condition PodCondition) (*v1.Pod, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	for {
+		// List first, so that the watch starts from the current state of the pod.
+		list, err := kubeClient.CoreV1().Pods(namespace).List(apismetav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(list.Items) != 0 {
+			pod := &list.Items[0]
+			if done, err := condition(pod); err != nil || done {
+				return pod, err
+			}
+		}
+
+		watcher, err := kubeClient.CoreV1().Pods(namespace).Watch(apismetav1.ListOptions{
+			FieldSelector:   selector,
+			ResourceVersion: list.ResourceVersion,
+		})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pod, closed, err := watchPod(ctx, watcher, timer.C, condition)
+		watcher.Stop()
+		if closed {
+			// The watch may be closed by the apiserver at any time, start over.
+			glog.V(5).Infof("Watch of pod %s closed, rewatching", name)
+			continue
+		}
+		if err == wait.ErrWaitTimeout {
+			return nil, errors.Errorf("timed out after %v waiting for pod %s", timeout, name)
+		}
+		return pod, err
+	}
+}
+
+// watchPod reads the events of the watcher until the condition is met. It returns
+// closed if the watch is closed before the condition is met.
+func watchPod(ctx context.Context, watcher watch.Interface, timeout <-chan time.Time, condition PodCondition) (*v1.Pod, bool, error) {
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil, true, nil
+			}
+			switch event.Type {
+			case watch.Deleted:
+				return nil, false, errors.New("pod has been deleted")
+			case watch.Error:
+				return nil, false, errors.WithStack(apierrors.FromObject(event.Object))
+			}
+			pod, ok := event.Object.(*v1.Pod)
+			if !ok {
+				continue
+			}
+			if done, err := condition(pod); err != nil || done {
+				return pod, false, err
+			}
+		case <-timeout:
+			return nil, false, wait.ErrWaitTimeout
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+// WaitForTestingDone waits for the testing pod to start within startTimeout, then follows
+// its log until the TestingDoneMark is printed within timeout. It returns the log and
+// the testing pod.
+func WaitForTestingDone(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, startTimeout, timeout time.Duration) ([]byte, *v1.Pod, error) {
+	if _, err := WaitForPod(ctx, kubeClient, namespace, name, startTimeout, PodStarted); err != nil {
+		return nil, nil, err
+	}
+
+	stream, err := kubeClient.CoreV1().Pods(namespace).GetLogs(name, &v1.PodLogOptions{Follow: true}).Stream()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer stream.Close()
+
+	// Closing the stream stops the blocked read below when the timeout expires
+	// or the ctx is cancelled.
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	var waitErr error
+	go func() {
+		defer close(finished)
+		select {
+		case <-timer.C:
+			waitErr = errors.Errorf("timed out after %v waiting for the testing pod %s", timeout, name)
+		case <-ctx.Done():
+			waitErr = ctx.Err()
+		case <-stop:
+			return
+		}
+		stream.Close()
+	}()
+
+	var body bytes.Buffer
+	done, err := scanForDoneMark(io.TeeReader(stream, &body))
+	close(stop)
+	<-finished
+	if !done {
+		if waitErr != nil {
+			return nil, nil, waitErr
+		}
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed to read the log of the testing pod %s", name)
+		}
+		return nil, nil, errors.Errorf("the testing pod %s exited before the testing case is done", name)
+	}
+
+	pod, err := kubeClient.CoreV1().Pods(namespace).Get(name, apismetav1.GetOptions{})
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return body.Bytes(), pod, nil
+}
+
+// scanForDoneMark reads the log line by line until the TestingDoneMark.
+func scanForDoneMark(r io.Reader) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == TestingDoneMark {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
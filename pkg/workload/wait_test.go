@@ -0,0 +1,246 @@
+/*
+Copyright (c) 2018 The ZJU-SEL Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apismetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newTestPod(name string, phase v1.PodPhase, ip string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: apismetav1.ObjectMeta{Name: name},
+		Status:     v1.PodStatus{Phase: phase, PodIP: ip},
+	}
+}
+
+// watchers returns a watch function of fakeClient which returns the watchers in turn,
+// and counts the watches.
+func watchers(t *testing.T, count *int, ws ...*watch.FakeWatcher) func(opts apismetav1.ListOptions) (watch.Interface, error) {
+	return func(opts apismetav1.ListOptions) (watch.Interface, error) {
+		if opts.FieldSelector != "metadata.name=capstan-pod" {
+			t.Errorf("watching pods with field selector %q", opts.FieldSelector)
+		}
+		*count++
+		if *count > len(ws) {
+			t.Fatalf("watching pods %d times, want %d", *count, len(ws))
+		}
+		return ws[*count-1], nil
+	}
+}
+
+func TestWaitForPod(t *testing.T) {
+	pending := newTestPod("capstan-pod", v1.PodPending, "")
+	running := newTestPod("capstan-pod", v1.PodRunning, "10.0.0.1")
+	failed := newTestPod("capstan-pod", v1.PodFailed, "")
+
+	// events returns a watcher with the events, which is closed after them if closed is set.
+	events := func(closed bool, events ...watch.Event) *watch.FakeWatcher {
+		w := watch.NewFakeWithChanSize(len(events), false)
+		for _, event := range events {
+			w.Action(event.Type, event.Object)
+		}
+		if closed {
+			w.Stop()
+		}
+		return w
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		pods     []v1.Pod
+		watchers []*watch.FakeWatcher
+		pod      *v1.Pod
+		err      string
+	}{
+		{
+			name: "met when listed",
+			pods: []v1.Pod{running},
+			pod:  &running,
+		},
+		{
+			name:     "met when watched",
+			pods:     []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{events(false, watch.Event{Type: watch.Modified, Object: &pending}, watch.Event{Type: watch.Modified, Object: &running})},
+			pod:      &running,
+		},
+		{
+			name:     "created when watched",
+			watchers: []*watch.FakeWatcher{events(false, watch.Event{Type: watch.Added, Object: &running})},
+			pod:      &running,
+		},
+		{
+			// The watch is restarted from a new list when it is closed by the apiserver.
+			name: "rewatched when closed",
+			pods: []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{
+				events(true, watch.Event{Type: watch.Modified, Object: &pending}),
+				events(true),
+				events(false, watch.Event{Type: watch.Modified, Object: &running}),
+			},
+			pod: &running,
+		},
+		{
+			name: "failed when listed",
+			pods: []v1.Pod{failed},
+			err:  "exited with phase Failed",
+		},
+		{
+			name:     "failed when watched",
+			pods:     []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{events(false, watch.Event{Type: watch.Modified, Object: &failed})},
+			err:      "exited with phase Failed",
+		},
+		{
+			name:     "deleted",
+			pods:     []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{events(false, watch.Event{Type: watch.Deleted, Object: &pending})},
+			err:      "deleted",
+		},
+		{
+			name:     "timeout",
+			pods:     []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{events(false, watch.Event{Type: watch.Modified, Object: &pending})},
+			err:      "timed out after 50ms waiting for pod capstan-pod",
+		},
+		{
+			name:     "ctx cancelled",
+			ctx:      cancelled,
+			pods:     []v1.Pod{pending},
+			watchers: []*watch.FakeWatcher{events(false)},
+			err:      context.Canceled.Error(),
+		},
+	}
+	for _, test := range tests {
+		ctx := test.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		watches := 0
+		client := &fakeClient{pods: test.pods, watch: watchers(t, &watches, test.watchers...)}
+		pod, err := WaitForPod(ctx, client, "capstan", "capstan-pod", 50*time.Millisecond, PodRunning)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want an error containing %q", test.name, err, test.err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		} else if pod.Status.Phase != test.pod.Status.Phase || pod.Status.PodIP != test.pod.Status.PodIP {
+			t.Errorf("%s: pod status %+v, want %+v", test.name, pod.Status, test.pod.Status)
+		}
+		if watches != len(test.watchers) {
+			t.Errorf("%s: watched %d times, want %d", test.name, watches, len(test.watchers))
+		}
+	}
+}
+
+func TestWaitForTestingDone(t *testing.T) {
+	const log = "Requests/sec: 100\n" + TestingDoneMark + "\n"
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// cancel cancels the ctx after the delay if it is set.
+		cancel time.Duration
+		// written is written to the followed log, which is closed after it if closed is set.
+		written string
+		closed  bool
+		log     string
+		err     string
+	}{
+		{name: "done", written: log, closed: true, log: log},
+		// The log is followed until the mark, the stream stays open.
+		{name: "done while following", written: log, log: log},
+		{name: "exited", written: "Requests/sec: 100\n", closed: true, err: "exited before the testing case is done"},
+		{name: "mark in a line", written: "echo " + TestingDoneMark + "\n", closed: true, err: "exited before"},
+		{name: "timeout", written: "Requests/sec: 100\n", timeout: 50 * time.Millisecond, err: "timed out after 50ms waiting for the testing pod capstan-pod"},
+		{name: "ctx cancelled", written: "Requests/sec: 100\n", cancel: 20 * time.Millisecond, err: context.Canceled.Error()},
+	}
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		if test.cancel != 0 {
+			time.AfterFunc(test.cancel, cancel)
+		}
+		timeout := test.timeout
+		if timeout == 0 {
+			timeout = time.Minute
+		}
+
+		r, w := io.Pipe()
+		go func(written string, closed bool) {
+			io.WriteString(w, written)
+			if closed {
+				w.Close()
+			}
+		}(test.written, test.closed)
+		client := &fakeClient{
+			pods: []v1.Pod{newTestPod("capstan-pod", v1.PodRunning, "10.0.0.1")},
+			logs: func(name string) io.ReadCloser {
+				if name != "capstan-pod" {
+					t.Errorf("%s: following the log of pod %s", test.name, name)
+				}
+				return r
+			},
+		}
+
+		data, pod, err := WaitForTestingDone(ctx, client, "capstan", "capstan-pod", time.Minute, timeout)
+		cancel()
+		w.Close()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error %v, want an error containing %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if string(data) != test.log {
+			t.Errorf("%s: log %q, want %q", test.name, data, test.log)
+		}
+		if pod == nil || pod.Name != "capstan-pod" {
+			t.Errorf("%s: pod %v, want capstan-pod", test.name, pod)
+		}
+	}
+}
+
+func TestWaitForTestingDoneNotStarted(t *testing.T) {
+	watches := 0
+	client := &fakeClient{
+		pods:  []v1.Pod{newTestPod("capstan-pod", v1.PodPending, "")},
+		watch: watchers(t, &watches, watch.NewFake()),
+		logs: func(name string) io.ReadCloser {
+			t.Errorf("following the log of pod %s which has not started", name)
+			return ioutil.NopCloser(strings.NewReader(""))
+		},
+	}
+	_, _, err := WaitForTestingDone(context.Background(), client, "capstan", "capstan-pod", 50*time.Millisecond, time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("error %v, want a timeout waiting for the testing pod to start", err)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package workload
 
 import (
+	"context"
 	"time"
 
 	"github.com/pkg/errors"
@@ -26,28 +27,38 @@ import (
 const (
 	// DefaultNamespace is the default namespace for capstan.
 	DefaultNamespace = "capstan"
+	// LabelHostname is the label of the hostname of a node.
+	LabelHostname = "kubernetes.io/hostname"
+
+	// PlacementSameNode places the testing pod on the node of the workload pod.
+	PlacementSameNode = "same-node"
+	// PlacementDifferentNode places the testing pod on another node than the workload pod.
+	PlacementDifferentNode = "different-node"
 )
 
 // Interface should be implemented by a specific workload.
 type Interface interface {
-	// Run runs a testing workload.
-	Run(kubeClient kubernetes.Interface) error
+	// Run runs a testing workload until it finishes or the ctx is cancelled.
+	Run(ctx context.Context, kubeClient kubernetes.Interface) error
 	// TestingTool returns the workload‘s Tool interface.
 	TestingTool() (Tool, error)
 	// GetName returns the name of this workload.
 	GetName() string
 	// GetImage returns the image name of this workload.
 	GetImage() string
+	// GetNamespace returns the namespace which this workload runs in.
+	GetNamespace() string
 }
 
 // Tool should be implemented by a testing tool.
 type Tool interface {
 	// Run runs the defined testing case set.
-	Run(kubeClient kubernetes.Interface, testingCase TestingCase) error
+	Run(ctx context.Context, kubeClient kubernetes.Interface, testingCase TestingCase) error
 	// GetTestingResults gets the testing results of a testing case.
-	GetTestingResults(kubeClient kubernetes.Interface) error
-	// Cleanup cleans up all resources created by a testing case.
-	Cleanup(kubeClient kubernetes.Interface) error
+	GetTestingResults(ctx context.Context, kubeClient kubernetes.Interface) error
+	// Cleanup cleans up all resources created by a testing case, it must not
+	// fail if some of the resources have not been created.
+	Cleanup(ctx context.Context, kubeClient kubernetes.Interface) error
 	// GetName returns the name of this testing tool.
 	GetName() string
 	// GetImage returns the image name of this testing tool.
@@ -56,14 +67,29 @@ type Tool interface {
 	GetSteps() time.Duration
 	// GetTestingCaseSet returns the testing case set which the testing tool will run.
 	GetTestingCaseSet() []TestingCase
+	// SetNodes restricts the pods of the testing cases to the nodes (by hostname),
+	// no restriction if nodes is empty.
+	SetNodes(nodes []string)
 }
 
 // Workload is the internal representation of a testing workload.
 type Workload struct {
-	Name        string `json:"name"`
+	Name string `json:"name"`
+	// Type is the registered workload this workload is created by, it defaults to the name.
+	Type        string `json:"type,omitempty"`
 	Image       string `json:"image"`
 	Frequency   int    `json:"frequency"`
 	TestingTool TestingTool
+	// Generic is the definition of a workload of the generic type.
+	Generic *GenericWorkload `json:"generic,omitempty"`
+}
+
+// GetType returns the registered workload this workload is created by.
+func (wl Workload) GetType() string {
+	if wl.Type != "" {
+		return wl.Type
+	}
+	return wl.Name
 }
 
 // TestingTool is the internal representation of a testing tool.
@@ -79,24 +105,49 @@ type TestingCase struct {
 	Name            string `json:"name"`
 	WorkloadArgs    string `json:"workloadArgs"`
 	TestingToolArgs string `json:"testingToolArgs"`
+	// StartTimeout is the max seconds waiting for a pod of the testing case to run.
+	StartTimeout int `json:"startTimeout,omitempty"`
+	// Timeout is the max seconds waiting for the testing pod to finish.
+	Timeout int `json:"timeout,omitempty"`
+	// Placement is where the testing pod runs relative to the workload pod, PlacementSameNode
+	// or PlacementDifferentNode. It is only used by the workloads without fixed placements.
+	Placement string `json:"placement,omitempty"`
+	// Options are the options of the testing tool for the testing case, they are
+	// documented and validated by each workload.
+	Options map[string]string `json:"options,omitempty"`
 }
 
-// DefWorkloads is the defined workloads.
-var DefWorkloads = []string{
-	"nginx",
-	"iperf3",
-	"mysql",
+// GetStartTimeout returns the max time waiting for a pod of the testing case to run.
+func (c TestingCase) GetStartTimeout() time.Duration {
+	if c.StartTimeout > 0 {
+		return time.Duration(c.StartTimeout) * time.Second
+	}
+	return DefaultStartTimeout
 }
 
-// DefTools is list of the defined testing tools.
-var DefTools = []string{
-	"wrk",
-	"iperf3",
-	"tpcc-mysql",
+// GetTimeout returns the max time waiting for the testing pod to finish.
+func (c TestingCase) GetTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return DefaultTimeout
 }
 
-// TestingCaseSetHasDefined finds whether all the string in slice a have defined in slice b or not.
-func TestingCaseSetHasDefined(testingCaseSet []TestingCase, defs []string) error {
+// TestingCaseSetHasDefined finds whether all the testing cases have been registered
+// for the testing tool of the workload or not.
+func TestingCaseSetHasDefined(name, toolName string, testingCaseSet []TestingCase) error {
+	factory, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	defs, err := factory.ToolTestingCaseSet(toolName)
+	if err != nil {
+		return err
+	}
+	if len(defs) == 0 {
+		// any testing case is allowed.
+		return nil
+	}
 	for _, testingCase := range testingCaseSet {
 		found := false
 		for _, def := range defs {
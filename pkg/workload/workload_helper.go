@@ -19,11 +19,15 @@ package workload
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
+	"path"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/ZJU-SEL/capstan/pkg/results"
+	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -48,14 +52,24 @@ func ParseTemplate(strtmpl string, obj interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// CreatePod creates a pod using podBytes.
-func CreatePod(kubeClient kubernetes.Interface, podBytes []byte) error {
+// CreatePod creates a pod using podBytes in the namespace, the pod is restricted
+// to the nodes if nodes is not empty.
+func CreatePod(kubeClient kubernetes.Interface, namespace string, nodes []string, podBytes []byte) error {
 	pod := &v1.Pod{}
 	if err := kuberuntime.DecodeInto(scheme.Codecs.UniversalDecoder(), podBytes, pod); err != nil {
 		return errors.Wrap(err, "unable to decode pod")
 	}
+	return CreatePodObject(kubeClient, namespace, nodes, pod)
+}
 
-	_, err := kubeClient.CoreV1().Pods(DefaultNamespace).Create(pod)
+// CreatePodObject creates the pod in the namespace, the pod is restricted to the nodes
+// if nodes is not empty.
+func CreatePodObject(kubeClient kubernetes.Interface, namespace string, nodes []string, pod *v1.Pod) error {
+	pod.Namespace = namespace
+	if len(nodes) != 0 {
+		restrictToNodes(pod, nodes)
+	}
+	_, err := kubeClient.CoreV1().Pods(namespace).Create(pod)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -63,14 +77,48 @@ func CreatePod(kubeClient kubernetes.Interface, podBytes []byte) error {
 	return nil
 }
 
-// DeletePod deletes a pod with the name.
-func DeletePod(kubeClient kubernetes.Interface, name string) error {
-	if err := kubeClient.CoreV1().Pods(DefaultNamespace).Delete(name, apismetav1.NewDeleteOptions(0)); err != nil {
+// restrictToNodes adds a required node affinity to the pod, which only allows
+// the pod to be scheduled to the nodes.
+func restrictToNodes(pod *v1.Pod, nodes []string) {
+	requirement := v1.NodeSelectorRequirement{
+		Key:      LabelHostname,
+		Operator: v1.NodeSelectorOpIn,
+		Values:   nodes,
+	}
+
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+	// The terms are ORed, so the requirement is added to every term.
+	for i := range selector.NodeSelectorTerms {
+		term := &selector.NodeSelectorTerms[i]
+		term.MatchExpressions = append(term.MatchExpressions, requirement)
+	}
+}
+
+// DeletePod deletes a pod with the name in the namespace, and waits until it is gone.
+// It is not an error if the pod doesn't exist.
+func DeletePod(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) error {
+	if err := kubeClient.CoreV1().Pods(namespace).Delete(name, apismetav1.NewDeleteOptions(0)); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
 		return errors.Wrapf(err, "failed to delete pod %v", name)
 	}
 
-	err := wait.Poll(500*time.Millisecond, 60*time.Second, func() (bool, error) {
-		_, err := kubeClient.CoreV1().Pods(DefaultNamespace).Get(name, apismetav1.GetOptions{})
+	err := poll(ctx, 500*time.Millisecond, 60*time.Second, func() (bool, error) {
+		_, err := kubeClient.CoreV1().Pods(namespace).Get(name, apismetav1.GetOptions{})
 		if err != nil {
 			if apierrors.IsNotFound(err) {
 				return true, nil
@@ -88,6 +136,153 @@ func DeletePod(kubeClient kubernetes.Interface, name string) error {
 	return nil
 }
 
+// poll tries the condition every interval until it is true, the timeout expires or the ctx is cancelled.
+func poll(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return wait.PollUntil(interval, condition, ctx.Done())
+}
+
+// Sleep pauses for the duration, it returns the error of the ctx if the ctx is cancelled.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOptions is the options of running the testing cases of a workload.
+type RunOptions struct {
+	// Namespace is the namespace which the pods of the testing cases run in.
+	Namespace string
+	// FailurePolicy is what to do when a testing case fails.
+	FailurePolicy FailurePolicy
+	// ResultsDir is the results directory of the run, the failures of testing cases are recorded under it.
+	ResultsDir string
+	// Publish publishes the metrics of every testing case, they are not published if it is nil.
+	Publish Publisher
+}
+
+// RunTestingCase runs a repeat of a testing case of the workload with the testing tool,
+// gets its testing results and cleans up all the resources created by it. The resources
+// are cleaned up even if the testing case fails or the ctx is cancelled, in which case
+// the testing case is recorded as aborted.
+//
+// A failed testing case is retried and skipped according to the failure policy, every failed
+// attempt is recorded under the results directory. An error is returned only if the run
+// must stop: the failure policy is abort, or the ctx is cancelled.
+func RunTestingCase(ctx context.Context, kubeClient kubernetes.Interface, w Interface, testingTool Tool, testingCase TestingCase, repeat int, opts RunOptions) error {
+	var err error
+	for attempt := 1; attempt <= opts.FailurePolicy.Retries+1; attempt++ {
+		if attempt > 1 {
+			glog.Warningf("Repeat %d: Retrying the testing case %q of %s, attempt %d", repeat, testingCase.Name, w.GetName(), attempt)
+		}
+		err = runAttempt(ctx, kubeClient, w, testingTool, testingCase, repeat, attempt, opts)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		results.DefaultStore.AbortCase(w.GetName(), testingCase.Name, repeat)
+		return errors.Wrapf(ctx.Err(), "Testing case %s of %s aborted", testingCase.Name, w.GetName())
+	}
+	results.DefaultStore.FinishCase(w.GetName(), testingCase.Name, repeat, err)
+	if err != nil && opts.FailurePolicy.Action != FailurePolicyAbort {
+		glog.Warningf("Repeat %d: Skipping the failed testing case %q of %s: %v", repeat, testingCase.Name, w.GetName(), err)
+		return nil
+	}
+	return err
+}
+
+// runAttempt runs an attempt of a repeat of a testing case, and cleans it up.
+func runAttempt(ctx context.Context, kubeClient kubernetes.Interface, w Interface, testingTool Tool, testingCase TestingCase, repeat, attempt int, opts RunOptions) error {
+	// running a testing case.
+	glog.V(1).Infof("Repeat %d: Running the testing case %q of %s", repeat, testingCase.Name, w.GetName())
+	results.DefaultStore.StartCase(w.GetName(), testingCase.Name, repeat)
+	err := testingTool.Run(ctx, kubeClient, testingCase)
+	if err != nil {
+		err = errors.Wrapf(err, "Failed to create the resouces belong to testing case %q of %s", testingCase.Name, w.GetName())
+	} else {
+		// get the testing results of the testing case.
+		glog.V(4).Infof("Repeat %d: Starting fetch the testing results of the testing case %q", repeat, testingCase.Name)
+		err = testingTool.GetTestingResults(ctx, kubeClient)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to gets the testing results of the testing case %s", testingCase.Name)
+		}
+	}
+
+	// capture the pods and events of the testing case and record the failure before
+	// its pods are cleaned up.
+	captureTestingCase(kubeClient, w, testingTool, testingCase, repeat, opts)
+	if err != nil && ctx.Err() == nil {
+		recordFailure(kubeClient, w, testingTool, testingCase, repeat, attempt, opts, err)
+	}
+
+	// clean up all the resouces created by the testing case, with a new context
+	// so that an interrupted testing case is cleaned up as well.
+	glog.V(4).Infof("Repeat %d: Cleaning up all the resouces created by the testing case %q", repeat, testingCase.Name)
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), CleanupTimeout)
+	defer cancel()
+	if cleanupErr := testingTool.Cleanup(cleanupCtx, kubeClient); cleanupErr != nil {
+		if err == nil {
+			err = errors.Wrapf(cleanupErr, "Failed to cleanup the resouces created by the testing case %s", testingCase.Name)
+			recordFailure(kubeClient, w, testingTool, testingCase, repeat, attempt, opts, err)
+		} else {
+			glog.Warningf("Failed to cleanup the resouces created by the testing case %s: %v", testingCase.Name, cleanupErr)
+		}
+	}
+	return err
+}
+
+// caseDir returns the directory of the results of a testing case, relative to the results directory of the run.
+func caseDir(w Interface, testingTool Tool, testingCase TestingCase) string {
+	return path.Join("workloads", w.GetName(), testingTool.GetName(), testingCase.Name)
+}
+
+// captureTestingCase saves the pods and events of a repeat of a testing case, and records
+// the times of its pods as the metadata of the repeat.
+func captureTestingCase(kubeClient kubernetes.Interface, w Interface, testingTool Tool, testingCase TestingCase, repeat int, opts RunOptions) {
+	if opts.ResultsDir == "" {
+		return
+	}
+	dir := path.Join(opts.ResultsDir, caseDir(w, testingTool, testingCase), fmt.Sprintf("repeat-%d", repeat))
+	pods, err := CaptureTestingCase(kubeClient, opts.Namespace, testingCase.Name, testingCasePodNames(w, testingTool, testingCase), dir)
+	if err != nil {
+		glog.Warningf("Failed to capture the pods of testing case %s: %v", testingCase.Name, err)
+		return
+	}
+	results.DefaultStore.SetPods(w.GetName(), testingCase.Name, pods)
+}
+
+// testingCasePodNames returns the names of the workload pod and the testing pod of a testing case.
+func testingCasePodNames(w Interface, testingTool Tool, testingCase TestingCase) []string {
+	return []string{BuildWorkloadPodName(w.GetName(), testingCase.Name), BuildTestingPodName(w.GetName(), testingTool.GetName(), testingCase.Name)}
+}
+
+// recordFailure adds a failed attempt of a testing case to the failure ledger of the run.
+func recordFailure(kubeClient kubernetes.Interface, w Interface, testingTool Tool, testingCase TestingCase, repeat, attempt int, opts RunOptions, failure error) {
+	dir := path.Join(caseDir(w, testingTool, testingCase), "failures", fmt.Sprintf("repeat-%d-attempt-%d", repeat, attempt))
+	if opts.ResultsDir != "" {
+		if err := RecordFailure(kubeClient, opts.Namespace, testingCase.Name, testingCasePodNames(w, testingTool, testingCase), path.Join(opts.ResultsDir, dir), failure); err != nil {
+			glog.Warningf("Failed to record the failure of testing case %s: %v", testingCase.Name, err)
+		}
+	}
+	results.DefaultStore.AddFailure(results.Failure{
+		Workload:    w.GetName(),
+		TestingCase: testingCase.Name,
+		Repeat:      repeat,
+		Attempt:     attempt,
+		Time:        time.Now(),
+		Error:       failure.Error(),
+		Dir:         dir,
+	})
+}
+
 // IsPodFailing returns whether a testing case pod is failing and isn't likely to succeed.
 // TODO(mozhuli): this may require more revisions as we get more experience with
 // various types of failures that can occur.
@@ -118,45 +313,23 @@ func IsPodFailing(pod *v1.Pod) (bool, error) {
 	return false, nil
 }
 
-// GetIPs gets podIP and hostIP of a running pod created by a workload, If no pod is found,
-// or if pod's status is not running, returns an error.
-func GetIPs(kubeClient kubernetes.Interface, name string) (string, string, error) {
-	n := 0
-	for {
-		// Sleep between each poll, which should give the workload enough time to create a Pod
-		// TODO(mozhuli): Use a watcher instead of polling.
-		time.Sleep(10 * time.Second)
-
-		// Make sure there's a pod.
-		pod, err := kubeClient.CoreV1().Pods(DefaultNamespace).Get(name, apismetav1.GetOptions{})
-		if err != nil {
-			return "", "", errors.WithStack(err)
-		}
-
-		// Make sure the pod isn't failing.
-		if isFailing, err := IsPodFailing(pod); isFailing {
-			return "", "", err
-		}
-
-		if pod.Status.Phase == v1.PodRunning {
-			return pod.Status.PodIP, pod.Status.HostIP, nil
-		}
-
-		// return an error, if has not get the pod ip for 60 seconds.
-		if n > 5 {
-			return "", "", errors.Errorf("long time to get pod %s ip", name)
-		}
-		n++
+// GetIPs waits for a pod created by a workload to run within the timeout, and gets its
+// podIP and hostIP. If no pod is found, or if the pod is failing, returns an error.
+func GetIPs(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, timeout time.Duration) (string, string, error) {
+	pod, err := WaitForPod(ctx, kubeClient, namespace, name, timeout, PodRunning)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to get the ip of pod %s", name)
 	}
+	return pod.Status.PodIP, pod.Status.HostIP, nil
 }
 
 // HasTestingDone checks the testing case has finished
-// or not(use the finish mark "Capstan Testing Done").
+// or not(use the finish mark TestingDoneMark).
 func HasTestingDone(data []byte) bool {
 	scanner := bufio.NewScanner(bytes.NewBuffer(data))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if line == "Capstan Testing Done" {
+		if line == TestingDoneMark {
 			return true
 		}
 	}
@@ -177,14 +350,17 @@ func FomatArgs(agrs string) string {
 	return str
 }
 
-// BuildWorkloadPodName builds the name of workload pod.
+// BuildWorkloadPodName builds the name of workload pod, it has a "-workload" suffix so it
+// never collides with the testing pod of a testing tool with the same name as the workload.
 func BuildWorkloadPodName(name, testingName string) string {
-	return strings.ToLower("capstan-" + name + "-" + testingName)
+	return strings.ToLower("capstan-" + name + "-" + testingName + "-workload")
 }
 
-// BuildTestingPodName builds the name of testing pod.
-func BuildTestingPodName(name, testingName string) string {
-	return strings.ToLower("capstan-" + name + "-" + testingName)
+// BuildTestingPodName builds the name of testing pod of a testing tool of a workload, it has
+// the name of the workload so the testing pods of two workloads with the same testing tool
+// never collide in a parallel run.
+func BuildTestingPodName(workloadName, toolName, testingName string) string {
+	return strings.ToLower("capstan-" + workloadName + "-" + toolName + "-" + testingName + "-testing")
 }
 
 // CreateNamespace creates a namespace.
@@ -198,11 +374,27 @@ func CreateNamespace(kubeClient kubernetes.Interface, namespace string) error {
 	return nil
 }
 
-// DeleteNamespace deletes a namespace.
-func DeleteNamespace(kubeClient kubernetes.Interface, namespace string) error {
+// DeleteNamespace deletes a namespace, and waits until all of its resources are deleted.
+func DeleteNamespace(ctx context.Context, kubeClient kubernetes.Interface, namespace string) error {
 	if err := kubeClient.CoreV1().Namespaces().Delete(namespace, apismetav1.NewDeleteOptions(0)); err != nil {
 		return errors.Wrapf(err, "failed to delete namespace %v", namespace)
 	}
 
+	err := poll(ctx, time.Second, NamespaceDeleteTimeout, func() (bool, error) {
+		_, err := kubeClient.CoreV1().Namespaces().Get(namespace, apismetav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+
+			return false, err
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to wait for namespace %v to be deleted", namespace)
+	}
+
 	return nil
 }